@@ -4,6 +4,7 @@ const packets := preload("res://packets.gd")
 
 var _action_on_ok_received: Callable
 
+@onready var _server_name_label: Label = $UI/VBoxContainer/ServerName
 @onready var _username_field: LineEdit = $UI/VBoxContainer/Username
 @onready var _password_field: LineEdit = $UI/VBoxContainer/Password
 @onready var _login_button: Button = $UI/VBoxContainer/HBoxContainer/LoginButton
@@ -24,6 +25,15 @@ func _on_ws_packet_received(packet: packets.Packet) -> void:
 		_log.error(deny_response_msg.get_reason())
 	elif packet.has_ok_response():
 		_action_on_ok_received.call()
+	elif packet.has_id():
+		# The server gives us our account's ID once we log in
+		GameManager.client_id = packet.get_id().get_id()
+	elif packet.has_server_info():
+		var server_info_msg := packet.get_server_info()
+		_server_name_label.text = server_info_msg.get_name()
+		
+		if not server_info_msg.get_motd().is_empty():
+			_log.info(server_info_msg.get_motd())
 
 func _on_ws_connection_closed() -> void:
 	_log.warning("Connection closed")
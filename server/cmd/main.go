@@ -1,38 +1,314 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
 	"server/internal/server"
 	"server/internal/server/clients"
+	"server/internal/server/objects"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 var (
 	port = flag.Int("port", 8080, "Port to listen on")
+	selfTest = flag.Bool("selftest", false, "Check the game's math and exit, with a non-zero status if anything is wrong")
+	metrics = flag.Bool("metrics", false, "Serve Prometheus metrics at /metrics")
+	configFile = flag.String("config", "", "File of settings to start with, one name=value per line using these flags' names. Flags given on the command line take precedence")
+	logFormat = flag.String("log-format", "text", "Format of the logs: text or json")
+	logLevel slog.Level
 )
 
 func main() {
+	flag.TextVar(&logLevel, "log-level", slog.LevelInfo, "Least severe logs to show: debug, info, warn or error. Debug includes the noisy per-message logs")
+
+	config := server.NewServerConfig()
+	flag.StringVar(&config.ServerName, "name", config.ServerName, "Server name shown to players")
+	flag.StringVar(&config.Motd, "motd", config.Motd, "Message of the day shown to players")
+	flag.StringVar(&config.GameMode, "mode", config.GameMode, "Name of the game mode sent to players as they join")
+	flag.Float64Var(&config.DriftStrength, "drift", config.DriftStrength, "Speed at which players drift toward the world center (0 disables)")
+	flag.Float64Var(&config.ZoneStartRadius, "zone-radius", config.ZoneStartRadius, "Starting radius of the shrinking safe zone (0 disables)")
+	flag.Float64Var(&config.ZoneMinRadius, "zone-min-radius", config.ZoneMinRadius, "Smallest radius the safe zone shrinks to")
+	flag.Float64Var(&config.ZoneShrinkRate, "zone-shrink-rate", config.ZoneShrinkRate, "Units per second the safe zone shrinks by")
+	flag.Float64Var(&config.ZoneDamage, "zone-damage", config.ZoneDamage, "Fraction of mass lost each damage interval outside the safe zone")
+	flag.DurationVar(&config.ZoneDamageInterval, "zone-damage-interval", config.ZoneDamageInterval, "How often players outside the safe zone take damage")
+	flag.DurationVar(&config.AfkThreshold, "afk-threshold", config.AfkThreshold, "Time without changing direction before a player starts losing mass (0 disables)")
+	flag.Float64Var(&config.AfkDecay, "afk-decay", config.AfkDecay, "Fraction of mass lost each second by AFK players")
+	flag.Float64Var(&config.MassDecay, "mass-decay", config.MassDecay, "Fraction of mass big players lose every decay interval (0 disables)")
+	flag.DurationVar(&config.MassDecayInterval, "mass-decay-interval", config.MassDecayInterval, "How often big players lose mass")
+	flag.Float64Var(&config.MassDecayMinRadius, "mass-decay-min-radius", config.MassDecayMinRadius, "Radius players don't decay below")
+	flag.Float64Var(&config.WorldBound, "world-bound", config.WorldBound, "Half the width of the square world")
+	flag.IntVar(&config.MaxSpores, "max-spores", config.MaxSpores, "How many spores the world is kept topped up to")
+	flag.DurationVar(&config.SporeReplenishInterval, "spore-replenish-interval", config.SporeReplenishInterval, "How often missing spores are put back")
+	flag.Float64Var(&config.SporeRadiusMean, "spore-radius-mean", config.SporeRadiusMean, "Average radius of new spores")
+	flag.Float64Var(&config.SporeRadiusDeviation, "spore-radius-deviation", config.SporeRadiusDeviation, "Standard deviation of new spores' radii")
+	flag.Float64Var(&config.MinSporeRadius, "min-spore-radius", config.MinSporeRadius, "Smallest radius of new spores")
+	flag.IntVar(&config.MaxHazards, "max-hazards", config.MaxHazards, "How many hazards the world is kept topped up to (0 disables)")
+	flag.Float64Var(&config.HazardRadius, "hazard-radius", config.HazardRadius, "Radius of hazards")
+	flag.Float64Var(&config.HazardMinPlayerRadius, "hazard-min-player-radius", config.HazardMinPlayerRadius, "Radius players must be bigger than for hazards to hurt them")
+	flag.Float64Var(&config.HazardPenalty, "hazard-penalty", config.HazardPenalty, "Fraction of their mass players lose popping a hazard")
+	flag.Float64Var(&config.SporeTypeWeights[objects.SporeTypePlain], "plain-spore-weight", config.SporeTypeWeights[objects.SporeTypePlain], "How often new spores are plain, relative to the other types")
+	flag.Float64Var(&config.SporeTypeWeights[objects.SporeTypeRich], "rich-spore-weight", config.SporeTypeWeights[objects.SporeTypeRich], "How often new spores are rich, worth double the mass, relative to the other types")
+	flag.Float64Var(&config.SporeTypeWeights[objects.SporeTypeGolden], "golden-spore-weight", config.SporeTypeWeights[objects.SporeTypeGolden], "How often new spores are golden, worth five times the mass, relative to the other types")
+	flag.Float64Var(&config.PlayerStartRadius, "player-start-radius", config.PlayerStartRadius, "Radius players start out with")
+	flag.Float64Var(&config.PlayerSpeed, "player-speed", config.PlayerSpeed, "Speed of players at their starting size")
+	flag.Float64Var(&config.MinPlayerSpeed, "min-player-speed", config.MinPlayerSpeed, "Speed big players never slow down below")
+	flag.IntVar(&config.MaxSporesPerCell, "max-spores-per-cell", config.MaxSporesPerCell, "Most spores placed in a single world grid cell (0 disables)")
+	flag.Float64Var(&config.SporeCellSize, "spore-cell-size", config.SporeCellSize, "Size of the world grid cells used to spread out spores")
+	flag.BoolVar(&config.BufferInputs, "buffer-inputs", config.BufferInputs, "Follow every direction change between ticks for part of the next tick, rather than only the last one")
+	flag.Float64Var(&config.SporeSyncRadius, "spore-sync-radius", config.SporeSyncRadius, "How close spores need to be to a player to be sent to their client, with the rest sent as they get near (0 sends every spore)")
+	flag.Float64Var(&config.PlayerViewRange, "player-view-range", config.PlayerViewRange, "How close other players need to be to a player for their client to be sent their movements (0 sends every player)")
+	flag.Float64Var(&config.ConsumptionViewRange, "consumption-view-range", config.ConsumptionViewRange, "How close a consumption needs to happen to a player for their client to be told about it, besides the players involved (0 tells everyone)")
+	flag.BoolVar(&config.RetryFailedMarshal, "retry-failed-marshal", config.RetryFailedMarshal, "Retry marshalling an outgoing packet once before dropping it")
+	flag.DurationVar(&config.MaxConnectionLifetime, "max-connection-lifetime", config.MaxConnectionLifetime, "How long a connection stays open before the client is asked to reconnect (0 disables)")
+	flag.Float64Var(&config.ConnectionLifetimeJitter, "connection-lifetime-jitter", config.ConnectionLifetimeJitter, "Fraction to randomly vary each connection's maximum lifetime by")
+	flag.Float64Var(&config.ConsumeRatio, "consume-ratio", config.ConsumeRatio, "How many times more massive a player must be than another to consume them")
+	flag.StringVar(&config.ConsumeMode, "consume-mode", config.ConsumeMode, "How close players must be to consume each other: contact or engulf")
+	flag.Float64Var(&config.EngulfFraction, "engulf-fraction", config.EngulfFraction, "In engulf mode, how far inside the consumer's edge the victim's center must be, as a fraction of the consumer's radius")
+	flag.BoolVar(&config.SharedTick, "shared-tick", config.SharedTick, "Move all of a room's players together in one simulation loop, rather than each on their own goroutine")
+	flag.DurationVar(&config.PlayerKeepaliveInterval, "player-keepalive-interval", config.PlayerKeepaliveInterval, "How often players who haven't changed are sent anyway (0 sends every player every tick)")
+	flag.IntVar(&config.WorkerPoolSize, "workers", config.WorkerPoolSize, "Number of goroutines running background tasks")
+	flag.IntVar(&config.WorkerQueueSize, "worker-queue", config.WorkerQueueSize, "Number of background tasks that can wait for a worker")
+	flag.DurationVar(&config.PlayerConsumeCooldown, "player-consume-cooldown", config.PlayerConsumeCooldown, "Time a player must wait between consuming other players (0 disables)")
+	flag.DurationVar(&config.SpawnConsumeGrace, "spawn-consume-grace", config.SpawnConsumeGrace, "How long a player has to wait after spawning before they can consume other players (0 disables)")
+	flag.IntVar(&config.MaxWorldObjects, "max-world-objects", config.MaxWorldObjects, "Most spores and players in the world together, after which new spores aren't placed (0 disables)")
+	flag.IntVar(&config.SporeReplenishBatch, "spore-replenish-batch", config.SporeReplenishBatch, "Most spores put back into the world each time the spore count is topped up")
+	flag.IntVar(&config.FairStartPlayers, "fair-start-players", config.FairStartPlayers, "Until this many players are in the game, growing from spores stops at the fair start radius (0 disables)")
+	flag.Float64Var(&config.FairStartMaxRadius, "fair-start-max-radius", config.FairStartMaxRadius, "Radius players can grow to from spores before enough players have joined")
+	flag.Float64Var(&config.KillSpeedBoost, "kill-speed-boost", config.KillSpeedBoost, "Fraction of extra speed a player gets right after consuming another player (0 disables)")
+	flag.DurationVar(&config.KillSpeedBoostDuration, "kill-speed-boost-duration", config.KillSpeedBoostDuration, "How long the speed boost from consuming another player takes to wear off")
+	flag.Float64Var(&config.MinRespawnMass, "min-respawn-mass", config.MinRespawnMass, "Fraction of the starting mass players respawn with after being consumed by a much larger player (1 disables)")
+	flag.IntVar(&config.MaxPlayersPerIp, "max-players-per-ip", config.MaxPlayersPerIp, "Most players in the game at once from a single IP address (0 disables)")
+	flag.BoolVar(&config.TrustForwardedFor, "trust-forwarded-for", config.TrustForwardedFor, "Take client IP addresses from the X-Forwarded-For header set by a proxy")
+	flag.IntVar(&config.ConnectionsPerWindow, "connections-per-window", config.ConnectionsPerWindow, "Most connections accepted from one IP address per connection window (0 disables)")
+	flag.DurationVar(&config.ConnectionWindow, "connection-window", config.ConnectionWindow, "Length of the window connections per IP address are limited over")
+	flag.BoolVar(&config.SmartReplenish, "smart-replenish", config.SmartReplenish, "Place new spores ahead of players rather than anywhere in the world")
+	flag.DurationVar(&config.SporeLifetime, "spore-lifetime", config.SporeLifetime, "How long a spore lasts before it's replaced somewhere else (0 disables)")
+	flag.Float64Var(&config.SporeMergeRadius, "spore-merge-radius", config.SporeMergeRadius, "How close spores need to be to each other to be merged into one bigger spore (0 disables)")
+	flag.IntVar(&config.MaxConcurrentRegistrations, "max-concurrent-registrations", config.MaxConcurrentRegistrations, "Most registrations using the database at once (0 disables)")
+	flag.DurationVar(&config.ServerStatsInterval, "server-stats-interval", config.ServerStatsInterval, "How often the player count and biggest player are sent to everyone in the game (0 disables)")
+	flag.IntVar(&config.LeaderboardSize, "leaderboard-size", config.LeaderboardSize, "How many of the biggest players are sent to everyone in the game every second (0 disables)")
+	flag.IntVar(&config.MaxClients, "max-clients", config.MaxClients, "Most clients connected at once (0 disables)")
+	flag.IntVar(&config.ClientQueueSize, "client-queue-size", config.ClientQueueSize, "How many clients can wait for a place when the server is full, with any more turned away (0 turns everyone away)")
+	flag.IntVar(&config.MaxRooms, "max-rooms", config.MaxRooms, "Most rooms which can be played in at once, including the default room (0 disables)")
+	flag.DurationVar(&config.ResumeWindow, "resume-window", config.ResumeWindow, "How long players who drop out can resume their session for (0 disables)")
+	flag.DurationVar(&config.IdleTimeout, "idle-timeout", config.IdleTimeout, "How long a player in the game can go without sending anything before they're disconnected (0 disables)")
+	flag.DurationVar(&config.PongWait, "pong-wait", config.PongWait, "How long to wait for a client to answer a ping before dropping the connection (0 disables pings)")
+	flag.DurationVar(&config.PingInterval, "ping-interval", config.PingInterval, "How often to ping clients, which has to be shorter than the pong wait")
+	flag.IntVar(&config.CompressionThreshold, "compression-threshold", config.CompressionThreshold, "Size in bytes from which packets are compressed for clients which support it (0 disables)")
+	flag.DurationVar(&config.RoomIdleTimeout, "room-idle-timeout", config.RoomIdleTimeout, "How long a room is kept around after its last player leaves")
+	flag.BoolVar(&config.ChatDisabled, "no-chat", config.ChatDisabled, "Keep players from chatting with each other")
+	flag.IntVar(&config.ChatRateLimit, "chat-rate-limit", config.ChatRateLimit, "How many chat messages each player can send per chat rate period (0 disables)")
+	flag.DurationVar(&config.ChatRatePeriod, "chat-rate-period", config.ChatRatePeriod, "Period the chat rate limit applies over")
+	flag.DurationVar(&config.SlowHandlerThreshold, "slow-handler-threshold", config.SlowHandlerThreshold, "How long handling a single message can take before a warning is logged (0 disables)")
+	flag.BoolVar(&config.CountTraffic, "count-traffic", config.CountTraffic, "Count the bytes sent to each client, for the admin traffic report")
+	flag.IntVar(&config.EventLogSize, "event-log-size", config.EventLogSize, "Number of recent events kept per client for debugging (0 disables)")
+	flag.IntVar(&config.MaxCredentialLength, "max-credential-length", config.MaxCredentialLength, "Most bytes in a username or password sent to log in or register (0 disables)")
+	flag.BoolVar(&config.Maintenance, "maintenance", config.Maintenance, "Start in maintenance mode, keeping players from joining the game")
+
 	flag.Parse()
 
+	if *configFile != "" {
+		if err := loadConfigFile(*configFile); err != nil {
+			log.Fatalf("Error loading config file %s: %v", *configFile, err)
+		}
+	}
+
+	if err := setupLogging(*logFormat, logLevel); err != nil {
+		log.Fatalf("Error setting up logging: %v", err)
+	}
+
+	if *selfTest {
+		if err := objects.SelfTest(); err != nil {
+			log.Fatalf("Self-test failed:\n%v", err)
+		}
+
+		log.Println("Self-test passed")
+		return
+	}
+
+	// The admin token is a secret, so it comes from the environment rather than the command line
+	godotenv.Load()
+	config.AdminToken = os.Getenv("ADMIN_TOKEN")
+
+	if config.ConsumeMode != server.ConsumeModeContact && config.ConsumeMode != server.ConsumeModeEngulf {
+		log.Fatalf("Invalid consume mode: %s", config.ConsumeMode)
+	}
+
+	if config.MinRespawnMass <= 0 || config.MinRespawnMass > 1 {
+		log.Fatalf("Invalid minimum respawn mass: %f", config.MinRespawnMass)
+	}
+
+	if config.WorldBound <= 0 || config.PlayerStartRadius <= 0 || config.PlayerStartRadius >= config.WorldBound {
+		log.Fatalf("Invalid world bound %f or player start radius %f", config.WorldBound, config.PlayerStartRadius)
+	}
+
+	if config.MinPlayerSpeed <= 0 || config.MinPlayerSpeed > config.PlayerSpeed {
+		log.Fatalf("Invalid player speeds: minimum %f, starting %f", config.MinPlayerSpeed, config.PlayerSpeed)
+	}
+
+	if config.MaxSpores < 0 || config.SporeReplenishInterval <= 0 || config.MinSporeRadius <= 0 {
+		log.Fatalf("Invalid spore settings: max %d, replenish interval %v, min radius %f", config.MaxSpores, config.SporeReplenishInterval, config.MinSporeRadius)
+	}
+
+	if weights := config.SporeTypeWeights; slices.Min(weights[:]) < 0 || slices.Max(weights[:]) <= 0 {
+		log.Fatalf("Invalid spore type weights: %v", weights)
+	}
+
+	if config.MaxHazards < 0 || config.HazardRadius <= 0 || config.HazardPenalty < 0 || config.HazardPenalty >= 1 {
+		log.Fatalf("Invalid hazard settings: max %d, radius %f, penalty %f", config.MaxHazards, config.HazardRadius, config.HazardPenalty)
+	}
+
+	if config.MaxClients < 0 || config.ClientQueueSize < 0 {
+		log.Fatalf("Invalid client limits: max %d, queue size %d", config.MaxClients, config.ClientQueueSize)
+	}
+
+	if config.PlayerKeepaliveInterval < 0 {
+		log.Fatalf("Invalid player keepalive interval: %v", config.PlayerKeepaliveInterval)
+	}
+
+	if config.PongWait > 0 && (config.PingInterval <= 0 || config.PingInterval >= config.PongWait) {
+		log.Fatalf("Invalid ping interval %v, which has to be shorter than the pong wait %v", config.PingInterval, config.PongWait)
+	}
+
 	// Game hub
-	hub := server.NewHub()
+	hub := server.NewHub(config)
 
 	// Handler for websocket connections
 	http.HandleFunc("/ws", func(writer http.ResponseWriter, request *http.Request) {
 		hub.Serve(clients.NewWebsocketClient, writer, request)
 	})
 
+	// Handlers for server administration
+	http.HandleFunc("/admin/maintenance", hub.AdminOnly(hub.HandleMaintenance))
+	http.HandleFunc("/admin/pause", hub.AdminOnly(hub.HandlePause))
+	http.HandleFunc("/admin/events", hub.AdminOnly(hub.HandleEvents))
+	http.HandleFunc("/admin/config", hub.AdminOnly(hub.HandleConfig))
+	http.HandleFunc("/admin/traffic", hub.AdminOnly(hub.HandleTraffic))
+	http.HandleFunc("/admin/clients", hub.AdminOnly(hub.HandleClients))
+	http.HandleFunc("/admin/kick", hub.AdminOnly(hub.HandleKick))
+	http.HandleFunc("/admin/bans", hub.AdminOnly(hub.HandleBans))
+
+	// Handler for the server's population, open to anyone
+	http.HandleFunc("/stats", hub.HandleStats)
+
+	if *metrics {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(hub.MetricsCollector(), collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+		http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	}
+
 	go hub.Run()
 
 	addr := fmt.Sprintf(":%d", *port)
+	httpServer := &http.Server{Addr: addr}
+
+	// Stop taking connections and wind down the game loops on Ctrl+C or when asked to stop
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	shutdownDone := make(chan struct{})
+
+	go func() {
+		defer close(shutdownDone)
+		<-signalCtx.Done()
+		log.Println("Shutting down...")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10 * time.Second)
+		defer cancel()
+
+		// Stop taking new connections first, so nobody joins while the game is being wound down
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down HTTP server: %v", err)
+		}
+
+		if err := hub.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down hub: %v", err)
+		}
+	}()
 
 	log.Printf("Starting server on %s", addr)
 
-	err := http.ListenAndServe(addr, nil)
+	err := httpServer.ListenAndServe()
 
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("Failed to start server: %v", err)
 	}
+
+	<-shutdownDone
+	log.Println("Server stopped")
+}
+
+// Send all logs, including those from the log package, through a structured handler of the given format
+func setupLogging(format string, level slog.Level) error {
+	options := &slog.HandlerOptions{Level: level}
+
+	switch format {
+		case "text":
+			slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, options)))
+		case "json":
+			slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, options)))
+		default:
+			return fmt.Errorf("unknown log format %s", format)
+	}
+
+	return nil
+}
+
+// Apply the settings in a config file which weren't also given on the command line. Each line is a flag's name and its
+// value separated by =, and blank lines and lines starting with # are skipped
+func loadConfigFile(path string) error {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	givenFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		givenFlags[f.Name] = true
+	})
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, found := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+
+		if !found {
+			return fmt.Errorf("line %d: expected name=value", i + 1)
+		}
+
+		if flag.Lookup(name) == nil || name == "config" {
+			return fmt.Errorf("line %d: unknown setting %s", i + 1, name)
+		}
+
+		if givenFlags[name] {
+			continue
+		}
+
+		if err := flag.Set(name, strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("line %d: %w", i + 1, err)
+		}
+	}
+
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,332 @@
+package server
+
+import (
+	"cmp"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"server/internal/server/db"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Wrap an admin handler so it only serves requests carrying the admin token as a bearer token.
+// If no admin token is set, every admin request is refused
+func (hub *Hub) AdminOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		adminToken := hub.Config().AdminToken
+		token, found := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+
+		if adminToken == "" || !found || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			http.Error(writer, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		handler(writer, request)
+	}
+}
+
+// Report whether maintenance mode is on with a GET, or turn it on or off with a POST to ?enabled=true or false
+func (hub *Hub) HandleMaintenance(writer http.ResponseWriter, request *http.Request) {
+	hub.handleToggle(writer, request, "maintenance", func(config *ServerConfig) *bool { return &config.Maintenance })
+}
+
+// Report whether the game is paused with a GET, or pause or resume it with a POST to ?enabled=true or false
+func (hub *Hub) HandlePause(writer http.ResponseWriter, request *http.Request) {
+	hub.handleToggle(writer, request, "paused", func(config *ServerConfig) *bool { return &config.Paused })
+}
+
+// Dump the event log of the client with the ID given as ?client=
+func (hub *Hub) HandleEvents(writer http.ResponseWriter, request *http.Request) {
+	clientId, err := strconv.ParseUint(request.URL.Query().Get("client"), 10, 64)
+
+	if err != nil {
+		http.Error(writer, "Expected a client ID", http.StatusBadRequest)
+		return
+	}
+
+	client, exists := hub.Clients.Get(clientId)
+
+	if !exists {
+		http.Error(writer, "No such client", http.StatusNotFound)
+		return
+	}
+
+	writeJson(writer, client.EventLog().Events())
+}
+
+// Report the bytes sent to clients, in total and for each client by ID
+func (hub *Hub) HandleTraffic(writer http.ResponseWriter, request *http.Request) {
+	clientTraffic := make(map[uint64]TrafficSnapshot)
+
+	hub.Clients.ForEach(func(clientId uint64, client ClientInterfacer) {
+		clientTraffic[clientId] = client.SentTraffic().Snapshot()
+	})
+
+	writeJson(writer, map[string]any{
+		"total": hub.SentTraffic.Snapshot(),
+		"clients": clientTraffic,
+	})
+}
+
+// A connected client as listed to admins. Player is the name the client is playing as, if they're in the game
+type clientInfo struct {
+	Id uint64 `json:"id"`
+	Ip string `json:"ip"`
+	Room string `json:"room"`
+	Player string `json:"player,omitempty"`
+}
+
+// List the connected clients, ordered by ID. The list is put together by the hub's run loop, so it doesn't race with
+// clients coming and going
+func (hub *Hub) HandleClients(writer http.ResponseWriter, request *http.Request) {
+	reply := make(chan []clientInfo, 1)
+
+	select {
+		case hub.clientListChan <- reply:
+		case <-hub.ctx.Done():
+			http.Error(writer, "Server is shutting down", http.StatusServiceUnavailable)
+			return
+		case <-request.Context().Done():
+			return
+	}
+
+	writeJson(writer, <-reply)
+}
+
+// The connected clients as listed to admins, ordered by ID. Only called by the run loop
+func (hub *Hub) listClients() []clientInfo {
+	clients := make([]clientInfo, 0, hub.Clients.Len())
+
+	hub.Clients.ForEach(func(clientId uint64, client ClientInterfacer) {
+		info := clientInfo{Id: clientId, Ip: client.Ip(), Room: client.Room().Name}
+
+		if player, playing := client.Room().SharedGameObjects.Players.Get(clientId); playing {
+			info.Player = player.Name
+		}
+
+		clients = append(clients, info)
+	})
+
+	slices.SortFunc(clients, func(a, b clientInfo) int { return cmp.Compare(a.Id, b.Id) })
+
+	return clients
+}
+
+// Disconnect the client with the ID given as ?client= with a POST, optionally telling them why with ?reason=
+func (hub *Hub) HandleKick(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(writer, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	clientId, err := strconv.ParseUint(request.URL.Query().Get("client"), 10, 64)
+
+	if err != nil {
+		http.Error(writer, "Expected a client ID", http.StatusBadRequest)
+		return
+	}
+
+	if !hub.Kick(clientId, request.URL.Query().Get("reason")) {
+		http.Error(writer, "No such client", http.StatusNotFound)
+		return
+	}
+
+	writeJson(writer, map[string]uint64{"kicked": clientId})
+}
+
+// List the banned users with a GET, ban the user given as ?username= with a POST, optionally with a ?reason=, or lift
+// their ban with a DELETE. Banning a user also kicks them out of the game
+func (hub *Hub) HandleBans(writer http.ResponseWriter, request *http.Request) {
+	queries := hub.NewDbTransaction().Queries
+	ctx := request.Context()
+
+	if request.Method == http.MethodGet {
+		bans, err := queries.ListBans(ctx)
+
+		if err != nil {
+			slog.Error("Error listing bans", "err", err)
+			http.Error(writer, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJson(writer, bans)
+		return
+	}
+
+	username := strings.ToLower(strings.TrimSpace(request.URL.Query().Get("username")))
+
+	if username == "" {
+		http.Error(writer, "Expected a username", http.StatusBadRequest)
+		return
+	}
+
+	switch request.Method {
+		case http.MethodPost:
+			reason := request.URL.Query().Get("reason")
+
+			if err := queries.BanUser(ctx, db.BanUserParams{Username: username, Reason: reason}); err != nil {
+				slog.Error("Error banning user", "username", username, "err", err)
+				http.Error(writer, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			kicked := hub.KickUser(username, reason)
+			slog.Info("Admin banned user", "username", username, "reason", reason, "kicked", kicked)
+
+			writeJson(writer, map[string]any{"banned": username, "kicked": kicked})
+		case http.MethodDelete:
+			lifted, err := queries.UnbanUser(ctx, username)
+
+			if err != nil {
+				slog.Error("Error unbanning user", "username", username, "err", err)
+				http.Error(writer, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			if lifted == 0 {
+				http.Error(writer, "No such ban", http.StatusNotFound)
+				return
+			}
+
+			slog.Info("Admin unbanned user", "username", username)
+
+			writeJson(writer, map[string]string{"unbanned": username})
+		default:
+			http.Error(writer, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// The settings which are safe to change while the game is running. Settings left out of a PATCH are unchanged
+type tunables struct {
+	ConsumeRatio *float64 `json:"consume_ratio,omitempty"`
+	EngulfFraction *float64 `json:"engulf_fraction,omitempty"`
+	DriftStrength *float64 `json:"drift_strength,omitempty"`
+	AfkDecay *float64 `json:"afk_decay,omitempty"`
+	ZoneShrinkRate *float64 `json:"zone_shrink_rate,omitempty"`
+	ZoneDamage *float64 `json:"zone_damage,omitempty"`
+	MinRespawnMass *float64 `json:"min_respawn_mass,omitempty"`
+	SporeReplenishBatch *int `json:"spore_replenish_batch,omitempty"`
+	SmartReplenish *bool `json:"smart_replenish,omitempty"`
+}
+
+func tunablesOf(config *ServerConfig) *tunables {
+	return &tunables{
+		ConsumeRatio: &config.ConsumeRatio,
+		EngulfFraction: &config.EngulfFraction,
+		DriftStrength: &config.DriftStrength,
+		AfkDecay: &config.AfkDecay,
+		ZoneShrinkRate: &config.ZoneShrinkRate,
+		ZoneDamage: &config.ZoneDamage,
+		MinRespawnMass: &config.MinRespawnMass,
+		SporeReplenishBatch: &config.SporeReplenishBatch,
+		SmartReplenish: &config.SmartReplenish,
+	}
+}
+
+func (t *tunables) validate() error {
+	switch {
+		case t.ConsumeRatio != nil && *t.ConsumeRatio < 1:
+			return errors.New("consume_ratio must be at least 1")
+		case t.EngulfFraction != nil && (*t.EngulfFraction < 0 || *t.EngulfFraction >= 1):
+			return errors.New("engulf_fraction must be at least 0 and less than 1")
+		case t.AfkDecay != nil && (*t.AfkDecay < 0 || *t.AfkDecay > 1):
+			return errors.New("afk_decay must be between 0 and 1")
+		case t.ZoneShrinkRate != nil && *t.ZoneShrinkRate < 0:
+			return errors.New("zone_shrink_rate can't be negative")
+		case t.ZoneDamage != nil && (*t.ZoneDamage < 0 || *t.ZoneDamage > 1):
+			return errors.New("zone_damage must be between 0 and 1")
+		case t.MinRespawnMass != nil && (*t.MinRespawnMass <= 0 || *t.MinRespawnMass > 1):
+			return errors.New("min_respawn_mass must be more than 0 and at most 1")
+		case t.SporeReplenishBatch != nil && *t.SporeReplenishBatch < 0:
+			return errors.New("spore_replenish_batch can't be negative")
+	}
+
+	return nil
+}
+
+func (t *tunables) applyTo(config *ServerConfig) {
+	setIfGiven(&config.ConsumeRatio, t.ConsumeRatio)
+	setIfGiven(&config.EngulfFraction, t.EngulfFraction)
+	setIfGiven(&config.DriftStrength, t.DriftStrength)
+	setIfGiven(&config.AfkDecay, t.AfkDecay)
+	setIfGiven(&config.ZoneShrinkRate, t.ZoneShrinkRate)
+	setIfGiven(&config.ZoneDamage, t.ZoneDamage)
+	setIfGiven(&config.MinRespawnMass, t.MinRespawnMass)
+	setIfGiven(&config.SporeReplenishBatch, t.SporeReplenishBatch)
+	setIfGiven(&config.SmartReplenish, t.SmartReplenish)
+}
+
+func setIfGiven[T any](setting *T, value *T) {
+	if value != nil {
+		*setting = *value
+	}
+}
+
+// Report the tunable settings with a GET, or change some of them with a PATCH of a JSON object holding the new values.
+// A PATCH is applied all at once or not at all
+func (hub *Hub) HandleConfig(writer http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+		case http.MethodGet:
+		case http.MethodPatch:
+			changes := &tunables{}
+			decoder := json.NewDecoder(request.Body)
+			decoder.DisallowUnknownFields()
+
+			if err := decoder.Decode(changes); err != nil {
+				http.Error(writer, "Invalid settings: " + err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if err := changes.validate(); err != nil {
+				http.Error(writer, "Invalid settings: " + err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			hub.UpdateConfig(changes.applyTo)
+
+			changed, _ := json.Marshal(changes)
+			slog.Info("Admin changed settings", "changes", string(changed))
+		default:
+			http.Error(writer, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+	}
+
+	writeJson(writer, tunablesOf(hub.Config()))
+}
+
+// Serve an on/off setting, picked out of the config by the given function
+func (hub *Hub) handleToggle(writer http.ResponseWriter, request *http.Request, name string, setting func(config *ServerConfig) *bool) {
+	switch request.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			enabled, err := strconv.ParseBool(request.URL.Query().Get("enabled"))
+
+			if err != nil {
+				http.Error(writer, "Expected enabled=true or enabled=false", http.StatusBadRequest)
+				return
+			}
+
+			hub.UpdateConfig(func(config *ServerConfig) {
+				*setting(config) = enabled
+			})
+
+			slog.Info("Admin changed setting", "setting", name, "enabled", enabled)
+		default:
+			http.Error(writer, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+	}
+
+	writeJson(writer, map[string]bool{name: *setting(hub.Config())})
+}
+
+func writeJson(writer http.ResponseWriter, value any) {
+	writer.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(writer).Encode(value); err != nil {
+		slog.Error("Error writing JSON response", "err", err)
+	}
+}
\ No newline at end of file
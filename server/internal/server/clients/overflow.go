@@ -0,0 +1,132 @@
+package clients
+
+import (
+	"server/pkg/packets"
+	"sync"
+)
+
+// What happens to a packet which doesn't fit in a client's full send channel
+type sendPriority int
+
+const (
+	// Dropped, since it's either stale soon anyway or can be asked for again
+	priorityLow sendPriority = iota
+
+	// Replaces the pending packet about the same player, or the pending batch of players, since only the latest one matters
+	priorityCoalesce
+
+	// Never dropped, since the client can't recover without it
+	priorityCritical
+)
+
+func priorityOf(message packets.Msg) sendPriority {
+	switch message.(type) {
+		case *packets.Packet_Player, *packets.Packet_PlayersBatch:
+			return priorityCoalesce
+		case *packets.Packet_PlayerConsumed, *packets.Packet_PlayerLeft, *packets.Packet_Id, *packets.Packet_OkResponse, *packets.Packet_DenyResponse:
+			return priorityCritical
+		// Each of these is only sent once, when the client joins or logs in
+		case *packets.Packet_SessionToken, *packets.Packet_ServerFull, *packets.Packet_GameMode:
+			return priorityCritical
+		// Spores and hazards are only sent once, so missing one would leave the client out of sync for good
+		case *packets.Packet_Spore, *packets.Packet_SporesBatch, *packets.Packet_SporeConsumed, *packets.Packet_SporeRemoved:
+			return priorityCritical
+		case *packets.Packet_Hazard, *packets.Packet_HazardRemoved:
+			return priorityCritical
+		default:
+			return priorityLow
+	}
+}
+
+// Holds the packets which didn't fit in the send channel until the write pump gets round to them
+type sendOverflow struct {
+	mux sync.Mutex
+	critical []*packets.Packet
+	players map[uint64]*packets.Packet
+	playersBatch *packets.Packet
+
+	// Signalled when there's something to write
+	ready chan struct{}
+}
+
+func newSendOverflow() *sendOverflow {
+	return &sendOverflow{
+		players: make(map[uint64]*packets.Packet),
+		ready: make(chan struct{}, 1),
+	}
+}
+
+// Hold on to a packet, returning false if it's low priority and so dropped instead
+func (overflow *sendOverflow) add(packet *packets.Packet) bool {
+	overflow.mux.Lock()
+
+	switch priorityOf(packet.Msg) {
+		case priorityCritical:
+			overflow.critical = append(overflow.critical, packet)
+
+			// An update held for a player who has since left would bring them back once written after this
+			if left := packet.GetPlayerLeft(); left != nil {
+				delete(overflow.players, left.PlayerId)
+				overflow.playersBatch = withoutPlayer(overflow.playersBatch, left.PlayerId)
+			}
+		case priorityCoalesce:
+			if packet.GetPlayersBatch() != nil {
+				overflow.playersBatch = packet
+			} else {
+				overflow.players[packet.GetPlayer().GetId()] = packet
+			}
+		default:
+			overflow.mux.Unlock()
+			return false
+	}
+
+	overflow.mux.Unlock()
+
+	select {
+		case overflow.ready <- struct{}{}:
+		default:
+	}
+
+	return true
+}
+
+// Take all the held packets to write, critical ones first
+func (overflow *sendOverflow) take() []*packets.Packet {
+	overflow.mux.Lock()
+	defer overflow.mux.Unlock()
+
+	taken := make([]*packets.Packet, 0, len(overflow.critical) + len(overflow.players))
+	taken = append(taken, overflow.critical...)
+
+	for _, packet := range overflow.players {
+		taken = append(taken, packet)
+	}
+
+	if overflow.playersBatch != nil {
+		taken = append(taken, overflow.playersBatch)
+	}
+
+	overflow.critical = nil
+	clear(overflow.players)
+	overflow.playersBatch = nil
+
+	return taken
+}
+
+// A copy of a held batch of players without the given player. The batch is shared with every other client it was sent
+// to, so it's rebuilt rather than changed
+func withoutPlayer(batchPacket *packets.Packet, playerId uint64) *packets.Packet {
+	if batchPacket == nil {
+		return nil
+	}
+
+	playerMessages := make([]*packets.PlayerMessage, 0, len(batchPacket.GetPlayersBatch().GetPlayers()))
+
+	for _, playerMessage := range batchPacket.GetPlayersBatch().GetPlayers() {
+		if playerMessage.GetId() != playerId {
+			playerMessages = append(playerMessages, playerMessage)
+		}
+	}
+
+	return &packets.Packet{SenderId: batchPacket.SenderId, Msg: packets.NewPlayersBatchOf(playerMessages)}
+}
\ No newline at end of file
@@ -0,0 +1,98 @@
+package clients
+
+import (
+	"server/internal/server/objects"
+	"server/pkg/packets"
+	"slices"
+	"testing"
+)
+
+// With the write pump stalled and the send channel full, critical packets must all be kept in the order they were sent,
+// player updates and batches must be cut down to the latest for each player and the latest batch, and low priority
+// packets dropped. A player who then left mustn't be kept in either, or writing them would bring them back
+func TestSlowWriterKeepsCriticalPackets(t *testing.T) {
+	// The client's pumps aren't running, so nothing is taken off the send channel
+	client := newUnregisteredClient(t)
+
+	for len(client.sendChan) < cap(client.sendChan) {
+		client.SocketSendAs(packets.NewChat("filler"), 0)
+	}
+
+	for x := range 10 {
+		client.SocketSendAs(packets.NewPlayer(5, &objects.Player{Name: "moving", X: float64(x), Radius: 20}), 5)
+	}
+
+	client.SocketSendAs(packets.NewPlayer(6, &objects.Player{Name: "leaving", Radius: 20}), 6)
+
+	for x := range 3 {
+		client.SocketSendAs(packets.NewPlayersBatch(map[uint64]*objects.Player{
+			5: {Name: "moving", X: float64(x), Radius: 20},
+			6: {Name: "leaving", Radius: 20},
+		}), 0)
+	}
+
+	client.SocketSendAs(packets.NewChat("dropped"), 7)
+	client.SocketSendAs(&packets.Packet_PlayerConsumed{PlayerConsumed: &packets.PlayerConsumedMessage{PlayerId: 8, NewRadius: 30}}, 9)
+	client.SocketSendAs(packets.NewId(42), 0)
+	client.SocketSendAs(packets.NewSessionToken("token"), 0)
+	client.SocketSendAs(packets.NewServerFull(1), 0)
+	client.SocketSendAs(packets.NewGameMode("classic", false, 1.5, "contact"), 0)
+	client.SocketSendAs(packets.NewSporeRemoved(3), 0)
+	client.SocketSendAs(packets.NewPlayerLeft(6), 6)
+
+	held := client.overflow.take()
+	names := make([]string, 0, len(held))
+
+	for _, packet := range held {
+		names = append(names, packets.MsgName(packet))
+	}
+
+	if expected := []string{"player_consumed", "id", "session_token", "server_full", "game_mode", "spore_removed", "player_left", "player", "players_batch"}; !slices.Equal(names, expected) {
+		t.Fatalf("A stalled client held on to %v instead of %v", names, expected)
+	}
+
+	if player := held[7].GetPlayer(); player.GetId() != 5 || player.GetX() != 9 {
+		t.Errorf("The player update held was %v instead of the latest one for player 5", player)
+	}
+
+	if batch := held[8].GetPlayersBatch().GetPlayers(); len(batch) != 1 || batch[0].GetId() != 5 || batch[0].GetX() != 2 {
+		t.Errorf("The batch of players held was %v instead of the latest one without player 6", batch)
+	}
+
+	if remaining := client.overflow.take(); len(remaining) > 0 {
+		t.Errorf("Taking the held packets left %v behind", remaining)
+	}
+}
+
+// Hazards are only sent once as they're placed and removed, so a stalled client must hold on to both rather than
+// never learning of one or keeping one which is gone
+func TestSlowWriterKeepsHazards(t *testing.T) {
+	client := newUnregisteredClient(t)
+
+	for len(client.sendChan) < cap(client.sendChan) {
+		client.SocketSendAs(packets.NewChat("filler"), 0)
+	}
+
+	client.SocketSendAs(packets.NewHazard(1, &objects.Hazard{X: 10, Y: 20, Radius: 30}), 0)
+	client.SocketSendAs(packets.NewChat("dropped"), 7)
+	client.SocketSendAs(packets.NewHazardRemoved(2, 8), 0)
+
+	held := client.overflow.take()
+	names := make([]string, 0, len(held))
+
+	for _, packet := range held {
+		names = append(names, packets.MsgName(packet))
+	}
+
+	if expected := []string{"hazard", "hazard_removed"}; !slices.Equal(names, expected) {
+		t.Fatalf("A stalled client held on to %v instead of %v", names, expected)
+	}
+
+	if hazard := held[0].GetHazard(); hazard.GetId() != 1 || hazard.GetRadius() != 30 {
+		t.Errorf("The hazard held was %v instead of the one placed", hazard)
+	}
+
+	if removed := held[1].GetHazardRemoved(); removed.GetHazardId() != 2 || removed.GetPlayerId() != 8 {
+		t.Errorf("The hazard removal held was %v instead of the one sent", removed)
+	}
+}
\ No newline at end of file
@@ -1,32 +1,69 @@
 package clients
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"server/internal/server"
 	"server/internal/server/states"
 	"server/pkg/packets"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/websocket"
 	"google.golang.org/protobuf/proto"
 )
 
+// Websocket close codes for the known reasons a client can be closed. Any other reason is a normal closure
+var closeCodes = map[string]int{
+	server.CloseReasonReadPumpClosed: websocket.CloseNormalClosure,
+	server.CloseReasonWritePumpClosed: websocket.CloseInternalServerErr,
+	server.CloseReasonMaxLifetime: websocket.CloseServiceRestart,
+	server.CloseReasonUpdateLoopFailed: websocket.CloseInternalServerErr,
+	server.CloseReasonShutdown: websocket.CloseGoingAway,
+	server.CloseReasonServerFull: websocket.CloseTryAgainLater,
+}
+
 type WebsocketClient struct {
-	id   uint64
+	// Changes from the connection's ID to the account's as the client logs in
+	id atomic.Uint64
 	conn *websocket.Conn
 	hub *server.Hub
+	room *server.Room
 	sendChan chan *packets.Packet
+
+	// Closed once the client is closing, after which nothing more is sent. The send channel itself is never closed, so
+	// late senders can't panic
+	done chan struct{}
+	closeOnce sync.Once
+
+	// Where packets go when the send channel is full
+	overflow *sendOverflow
 	state server.ClientStateHandler
-	logger *log.Logger
+	stateMux sync.Mutex
+	closing bool
+	logger atomic.Pointer[slog.Logger]
 	dbTransaction *server.DbTransaction
+	eventLog *server.EventLog
+	ip string
+	sentTraffic *server.TrafficCounter
 }
 
-func NewWebsocketClient(hub *server.Hub, writer http.ResponseWriter, request *http.Request) (server.ClientInterfacer, error) {
+func NewWebsocketClient(hub *server.Hub, room *server.Room, writer http.ResponseWriter, request *http.Request) (server.ClientInterfacer, error) {
 	upgrader := websocket.Upgrader{
 		ReadBufferSize: 1024,
 		WriteBufferSize: 1024,
 		CheckOrigin: func(r *http.Request) bool { return true },
+
+		// Only offered to clients, which decompress compressed messages themselves. Whether each message is compressed
+		// is decided when writing it
+		EnableCompression: hub.Config().CompressionThreshold > 0,
 	}
 
 	conn, err := upgrader.Upgrade(writer, request, nil)
@@ -37,25 +74,42 @@ func NewWebsocketClient(hub *server.Hub, writer http.ResponseWriter, request *ht
 
 	client := &WebsocketClient{
 		hub: hub,
+		room: room,
 		conn: conn,
 		sendChan: make(chan *packets.Packet, 256),
-		logger: log.New(log.Writer(), "Client unknown: ", log.LstdFlags),
+		overflow: newSendOverflow(),
+		done: make(chan struct{}),
 		dbTransaction: hub.NewDbTransaction(),
+		eventLog: server.NewEventLog(hub.Config().EventLogSize),
+		ip: server.RequestIp(request, hub.Config().TrustForwardedFor),
+	}
+
+	client.logger.Store(slog.Default())
+
+	if hub.Config().CountTraffic {
+		client.sentTraffic = server.NewTrafficCounter()
 	}
 
 	return client, nil
 }
 
 func (client *WebsocketClient) Id() uint64 {
-	return client.id;
+	return client.id.Load()
 }
 
+// Messages can be delivered from other goroutines while the state changes, so no state receives messages while it's
+// being entered or exited. The lock is never held while the states run, since they can change the state themselves
 func (client *WebsocketClient) SetState(state server.ClientStateHandler) {
+	client.stateMux.Lock()
+	prevState := client.state
+	client.state = nil
+	client.stateMux.Unlock()
+
 	prevStateName := "None"
 
-	if client.state != nil {
-		prevStateName = client.state.Name()
-		client.state.OnExit()
+	if prevState != nil {
+		prevStateName = prevState.Name()
+		prevState.OnExit()
 	}
 
 	newStateName := "None"
@@ -64,60 +118,135 @@ func (client *WebsocketClient) SetState(state server.ClientStateHandler) {
 		newStateName = state.Name()
 	}
 
-	client.logger.Printf("Switching from state %s to %s", prevStateName, newStateName)
-	
-	client.state = state
+	client.Logger().Debug("Switching state", "from", prevStateName, "to", newStateName)
 
-	if client.state != nil {
-		client.state.SetClient(client)
-		client.state.OnEnter()
+	if state == nil {
+		return
+	}
+
+	state.SetClient(client)
+	state.OnEnter()
+
+	client.stateMux.Lock()
+	closing := client.closing
+
+	if !closing {
+		client.state = state
+	}
+
+	client.stateMux.Unlock()
+
+	// The client was closed while entering the state, so leave it again straight away
+	if closing {
+		client.Logger().Info("Client closed while entering state, leaving it", "state", newStateName)
+		state.OnExit()
 	}
 }
 
 func (client *WebsocketClient) ProcessMessage (senderId uint64, message packets.Msg) {
-	client.state.HandleMessage(senderId, message)
+	client.stateMux.Lock()
+	state := client.state
+	client.stateMux.Unlock()
+
+	// Messages arriving before the first state is set, during a state change or after closing are dropped
+	if state == nil {
+		return
+	}
+
+	start := time.Now()
+	state.HandleMessage(senderId, message)
+
+	if threshold := client.Config().SlowHandlerThreshold; threshold > 0 {
+		if elapsed := time.Since(start); elapsed > threshold {
+			client.Logger().Warn("Slow handler", "state", state.Name(), "packet_type", packets.MsgName(&packets.Packet{Msg: message}), "sender_id", senderId, "elapsed", elapsed)
+		}
+	}
 }
 
 func (client *WebsocketClient) Initialize(id uint64) {
-	client.id = id
-	client.logger.SetPrefix(fmt.Sprintf("Client %d: ", client.id))
+	client.id.Store(id)
+	client.logger.Store(slog.Default().With("client_id", id))
 	client.SetState(&states.Connected{})
 }
 
 func (client *WebsocketClient) SocketSend(message packets.Msg) {
-	client.SocketSendAs(message, client.id)
+	client.SocketSendAs(message, client.Id())
 }
 
+// Sends to a client which has since closed, e.g. one found in a snapshot of the players, are skipped
 func (client *WebsocketClient) SocketSendAs(message packets.Msg, senderId uint64) {
 	select {
-		case client.sendChan <- &packets.Packet{SenderId: senderId, Msg: message}:
+		case <-client.done:
+			client.hub.SkippedSends.Add(1)
+			return
+		default:
+	}
+
+	packet := &packets.Packet{SenderId: senderId, Msg: message}
+
+	select {
+		case client.sendChan <- packet:
 		default:
-			client.logger.Printf("Send channel full, dropping message: %T", message)
+			if !client.overflow.add(packet) {
+				client.Logger().Warn("Send channel full, dropping message", "packet_type", packets.MsgName(packet))
+			}
 	}
 }
 
+func (client *WebsocketClient) RunAsync(task func()) {
+	client.hub.WorkerPool.Submit(task)
+}
+
 func (client *WebsocketClient) PassToPeer(message packets.Msg, peerId uint64) {
 	if peer, exists := client.hub.Clients.Get(peerId); exists {
-		peer.ProcessMessage(client.id, message)
+		peer.ProcessMessage(client.Id(), message)
 	}
 }
 
+// Broadcasts are given up on if the room stops running, e.g. during shutdown, rather than blocking forever
 func (client *WebsocketClient) Broadcast(message packets.Msg) {
-	client.hub.BroadcastChan <- &packets.Packet{SenderId: client.id, Msg: message}
+	client.hub.Broadcasts.Add(1)
+
+	select {
+		case client.room.BroadcastChan <- &packets.Packet{SenderId: client.Id(), Msg: message}:
+		case <-client.room.Context().Done():
+	}
+}
+
+func (client *WebsocketClient) BroadcastExcept(message packets.Msg, excludedIds ...uint64) {
+	broadcast := &server.ExclusiveBroadcast{
+		Packet: &packets.Packet{SenderId: client.Id(), Msg: message},
+		ExcludedIds: excludedIds,
+	}
+
+	client.hub.Broadcasts.Add(1)
+
+	select {
+		case client.room.ExclusiveBroadcastChan <- broadcast:
+		case <-client.room.Context().Done():
+	}
 }
 
 func (client *WebsocketClient) ReadPump() {
 	defer func() {
-		client.logger.Println("Closing read pump")
-		client.Close("Read pump closed")
+		client.Logger().Info("Closing read pump")
+		client.Close(server.CloseReasonReadPumpClosed)
 	}()
 
+	// Connections which stop answering pings without closing properly time out on the read deadline
+	if pongWait := client.hub.Config().PongWait; pongWait > 0 {
+		client.conn.SetReadDeadline(time.Now().Add(pongWait))
+		client.conn.SetPongHandler(func(string) error {
+			return client.conn.SetReadDeadline(time.Now().Add(pongWait))
+		})
+	}
+
 	for {
 		_, data, err := client.conn.ReadMessage()
 
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				client.logger.Printf("Error: %v", err)
+				client.Logger().Error("Unexpected close", "err", err)
 			}
 
 			break
@@ -127,13 +256,30 @@ func (client *WebsocketClient) ReadPump() {
 		err = proto.Unmarshal(data, packet)
 
 		if err != nil {
-			client.logger.Printf("Error unmarshalling data: %v", err)
+			client.Logger().Warn("Error unmarshalling data", "err", err)
+			continue
+		}
+
+		if packet.Msg == nil {
+			if unknown := packets.UnknownMsgNumbers(packet); len(unknown) > 0 {
+				client.hub.UnknownPackets.Add(1)
+				client.Logger().Warn("Received packet with unknown message types, the client may be using a newer protocol", "numbers", unknown)
+			}
+
 			continue
 		}
 
+		client.hub.ReceivedPackets.Add(packet)
+
 		// To allow the client to lazily not send the sender ID, we'll assume they want to send it to themselves
 		if packet.SenderId == 0 {
-			packet.SenderId = client.id
+			packet.SenderId = client.Id()
+		}
+
+		// Clock syncing works the same in every state, so answer it straight away
+		if timeSync, ok := packet.Msg.(*packets.Packet_TimeSync); ok {
+			client.SocketSend(packets.NewTimeSync(timeSync.TimeSync.ClientTime, client.hub.ServerTime()))
+			continue
 		}
 
 		client.ProcessMessage(packet.SenderId, packet.Msg)
@@ -141,59 +287,244 @@ func (client *WebsocketClient) ReadPump() {
 }
 
 func (client *WebsocketClient) WritePump() {
+	closeReason := server.CloseReasonWritePumpClosed
+
 	defer func() {
-		client.logger.Println("Closing write pump")
-		client.Close("Write pump closed")
+		client.Logger().Info("Closing write pump")
+		client.Close(closeReason)
 	}()
 
-	for packet := range client.sendChan {
-		writer, err := client.conn.NextWriter(websocket.BinaryMessage)
+	// Stays nil when connections can live forever, so it never fires
+	var lifetimeChan <-chan time.Time
 
-		if err != nil {
-			client.logger.Printf("Error getting writer for %T packet, closing client %v", packet.Msg, err)
-			return
-		}
+	if lifetime := client.connectionLifetime(); lifetime > 0 {
+		lifetimeTimer := time.NewTimer(lifetime)
+		defer lifetimeTimer.Stop()
+		lifetimeChan = lifetimeTimer.C
+	}
 
-		data, err := proto.Marshal(packet)
+	// Stays nil when pings are disabled
+	var pingChan <-chan time.Time
 
-		if err != nil {
-			client.logger.Printf("Error marshalling %T packet, closing client %v", packet.Msg, err)
-			continue
+	if client.hub.Config().PongWait > 0 {
+		pingTicker := time.NewTicker(client.hub.Config().PingInterval)
+		defer pingTicker.Stop()
+		pingChan = pingTicker.C
+	}
+
+	for {
+		select {
+			case <-client.done:
+				return
+			case packet := <-client.sendChan:
+				if err := client.writePacket(packet); err != nil {
+					client.Logger().Error("Error writing packet, closing client", "packet_type", packets.MsgName(packet), "err", err)
+					return
+				}
+
+				// The hub turned the client away because the server is full, and it's been told so it can stop
+				if serverFull := packet.GetServerFull(); serverFull != nil && serverFull.QueuePosition == 0 {
+					closeReason = server.CloseReasonServerFull
+					return
+				}
+			case <-client.overflow.ready:
+				for _, packet := range client.overflow.take() {
+					if err := client.writePacket(packet); err != nil {
+						client.Logger().Error("Error writing packet, closing client", "packet_type", packets.MsgName(packet), "err", err)
+						return
+					}
+				}
+			case <-pingChan:
+				if err := client.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)); err != nil {
+					client.Logger().Error("Error sending ping, closing client", "err", err)
+					return
+				}
+			case <-lifetimeChan:
+				// Ask the client to reconnect before closing, so it knows this isn't an error
+				closeReason = server.CloseReasonMaxLifetime
+				client.writePacket(&packets.Packet{SenderId: client.Id(), Msg: packets.NewReconnect(closeReason)})
+				return
 		}
+	}
+}
 
-		_, err = writer.Write(data)
+// Write a packet to the socket. Only returns an error if the connection can no longer be written to
+func (client *WebsocketClient) writePacket(packet *packets.Packet) error {
+	// Marshal before getting a writer so a failure doesn't leave an empty message behind
+	data, err := proto.Marshal(packet)
 
-		if err != nil {
-			client.logger.Printf("Error writing %T packet: %v", packet.Msg, err)
-			continue
-		}
+	if err != nil && client.hub.Config().RetryFailedMarshal {
+		data, err = proto.Marshal(packet)
+	}
+
+	if err != nil {
+		// This is a bug on our end rather than a problem with the connection, so drop the packet and carry on
+		client.hub.MarshalErrors.Add(1)
+		client.Logger().Error("Error marshalling packet, dropping it", "packet_type", packets.MsgName(packet), "err", err)
+		return nil
+	}
 
-		writer.Write([]byte{'\n'})
+	// Only does anything if the client negotiated compression when connecting
+	threshold := client.hub.Config().CompressionThreshold
+	client.conn.EnableWriteCompression(threshold > 0 && len(data) >= threshold)
 
-		if err = writer.Close(); err != nil {
-			client.logger.Printf("Error closing writer for %T packet: %v", packet.Msg, err)
-			continue
-		}
+	writer, err := client.conn.NextWriter(websocket.BinaryMessage)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err = writer.Write(data); err != nil {
+		return err
+	}
+
+	if _, err = writer.Write([]byte{'\n'}); err != nil {
+		return err
+	}
+
+	// The message is only flushed to the connection as the writer is closed
+	if err = writer.Close(); err != nil {
+		return err
 	}
+
+	client.hub.SentPackets.Add(packet)
+
+	packetType := strings.TrimPrefix(fmt.Sprintf("%T", packet.Msg), "*packets.Packet_")
+	client.sentTraffic.Add(packetType, len(data) + 1)
+	client.hub.SentTraffic.Add(packetType, len(data) + 1)
+
+	return nil
+}
+
+// How long this connection may stay open, spread out a little so clients connected together don't all reconnect
+// at the same time. Zero means forever
+func (client *WebsocketClient) connectionLifetime() time.Duration {
+	lifetime := client.hub.Config().MaxConnectionLifetime
+
+	if lifetime <= 0 {
+		return 0
+	}
+
+	jitter := client.hub.Config().ConnectionLifetimeJitter * (2 * rand.Float64() - 1)
+
+	return time.Duration(float64(lifetime) * (1 + jitter))
 }
 
 func (client *WebsocketClient) DbTransaction() *server.DbTransaction {
 	return client.dbTransaction
 }
 
+func (client *WebsocketClient) Room() *server.Room {
+	return client.room
+}
+
+func (client *WebsocketClient) Sessions() *server.SessionStore {
+	return client.hub.Sessions
+}
+
 func (client *WebsocketClient) SharedGameObjects() *server.SharedGameObjects {
-	return client.hub.SharedGameObjects
+	return client.room.SharedGameObjects
+}
+
+func (client *WebsocketClient) Config() *server.ServerConfig {
+	return client.hub.Config()
+}
+
+func (client *WebsocketClient) SentTraffic() *server.TrafficCounter {
+	return client.sentTraffic
+}
+
+func (client *WebsocketClient) Ip() string {
+	return client.ip
+}
+
+func (client *WebsocketClient) Context() context.Context {
+	return client.hub.Context()
+}
+
+func (client *WebsocketClient) EventLog() *server.EventLog {
+	return client.eventLog
+}
+
+func (client *WebsocketClient) Logger() *slog.Logger {
+	return client.logger.Load()
+}
+
+// Holds the state lock throughout, so the client can't be closing, and unregistered under its old ID, while the ID
+// changes
+func (client *WebsocketClient) ClaimId(id uint64) bool {
+	client.stateMux.Lock()
+	defer client.stateMux.Unlock()
+
+	oldId := client.Id()
+
+	if id == oldId {
+		return true
+	}
+
+	if client.closing || !client.hub.Clients.Move(oldId, id) {
+		return false
+	}
+
+	client.room.Clients.Move(oldId, id)
+	client.id.Store(id)
+	client.logger.Store(slog.Default().With("client_id", id))
+	client.Logger().Info("Claimed ID", "connection_id", oldId)
+
+	return true
 }
 
+// Both pumps close the client when they stop, and so can the states, but only the first call does anything
 func (client *WebsocketClient) Close(reason string) {
-	client.logger.Printf("Closing client connection because: %s", reason)
+	client.closeOnce.Do(func() {
+		client.Logger().Info("Closing client connection", "reason", reason)
+
+		client.stateMux.Lock()
+		client.closing = true
+		client.stateMux.Unlock()
+
+		// Stop the write pump and anything more being sent
+		close(client.done)
+
+		client.SetState(nil)
+
+		select {
+			case client.hub.UnregisterChan <- client:
+			case <-client.hub.Context().Done():
+		}
+
+		client.sendCloseMessage(reason)
+		client.conn.Close()
+	})
+}
+
+// Let the client know why the connection is closing before it's torn down
+func (client *WebsocketClient) sendCloseMessage(reason string) {
+	code, exists := closeCodes[reason]
 
-	client.SetState(nil)
+	if !exists {
+		code = websocket.CloseNormalClosure
+	}
 
-	client.hub.UnregisterChan <- client
-	client.conn.Close()
+	// Control frames can only carry 125 bytes, 2 of which are the close code
+	message := websocket.FormatCloseMessage(code, truncateUtf8(reason, 123))
+	err := client.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
 
-	if _, closed := <-client.sendChan; !closed {
-		close(client.sendChan)
+	if err != nil && !errors.Is(err, websocket.ErrCloseSent) {
+		client.Logger().Warn("Error sending close message", "err", err)
 	}
 }
+
+// Cut the string down to at most the given number of bytes without splitting a character, since the reason in a close
+// frame has to be valid UTF-8
+func truncateUtf8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+
+	for maxBytes > 0 && !utf8.RuneStart(s[maxBytes]) {
+		maxBytes--
+	}
+
+	return s[:maxBytes]
+}
\ No newline at end of file
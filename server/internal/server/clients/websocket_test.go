@@ -0,0 +1,790 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"server/internal/server"
+	"server/internal/server/objects"
+	"server/pkg/packets"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// How long to wait for the server to get round to something before failing
+const testTimeout time.Duration = 5 * time.Second
+
+// A running hub serving websocket connections, and the URL to connect to it on. It runs in a directory of its own so
+// its database doesn't end up in the source tree, and is shut down when the test ends
+func newTestServer(t *testing.T, configure ...func(config *server.ServerConfig)) (*server.Hub, string) {
+	t.Chdir(t.TempDir())
+
+	config := server.NewServerConfig()
+	config.MaxSpores = 0
+
+	for _, configureFunc := range configure {
+		configureFunc(config)
+	}
+
+	hub := server.NewHub(config)
+	go hub.Run()
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		hub.Serve(NewWebsocketClient, writer, request)
+	}))
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+		defer cancel()
+
+		if err := hub.Shutdown(ctx); err != nil {
+			t.Errorf("Couldn't shut down the hub: %v", err)
+		}
+
+		httpServer.Close()
+	})
+
+	return hub, "ws" + strings.TrimPrefix(httpServer.URL, "http")
+}
+
+// Connect to the test server, closing the connection when the test ends
+func dial(t *testing.T, url string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+
+	if err != nil {
+		t.Fatalf("Couldn't connect to %s: %v", url, err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func sendPacket(t *testing.T, conn *websocket.Conn, packet *packets.Packet) {
+	data, err := proto.Marshal(packet)
+
+	if err != nil {
+		t.Fatalf("Couldn't marshal %v: %v", packet, err)
+	}
+
+	if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+		t.Fatalf("Couldn't send %v: %v", packet, err)
+	}
+}
+
+// Read packets until one the condition holds for, failing if the connection closes or nothing turns up in time
+func readUntil(t *testing.T, conn *websocket.Conn, condition func(*packets.Packet) bool) *packets.Packet {
+	conn.SetReadDeadline(time.Now().Add(testTimeout))
+
+	for {
+		_, data, err := conn.ReadMessage()
+
+		if err != nil {
+			t.Fatalf("Stopped reading packets: %v", err)
+		}
+
+		// Every packet is followed by a newline
+		packet := &packets.Packet{}
+
+		if err := proto.Unmarshal(bytes.TrimSuffix(data, []byte{'\n'}), packet); err != nil {
+			t.Fatalf("Couldn't unmarshal %v: %v", data, err)
+		}
+
+		if condition(packet) {
+			return packet
+		}
+	}
+}
+
+// Connect to the test server and wait to be told the client's ID
+func connect(t *testing.T, url string) (*websocket.Conn, uint64) {
+	conn := dial(t, url)
+	idPacket := readUntil(t, conn, func(packet *packets.Packet) bool { return packet.GetId() != nil })
+
+	return conn, idPacket.GetId().Id
+}
+
+// Read until the server closes the connection, returning the close frame it sent
+func readCloseError(t *testing.T, conn *websocket.Conn) *websocket.CloseError {
+	conn.SetReadDeadline(time.Now().Add(testTimeout))
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			closeErr := &websocket.CloseError{}
+
+			if !errors.As(err, &closeErr) {
+				t.Fatalf("The connection ended without a close frame: %v", err)
+			}
+
+			return closeErr
+		}
+	}
+}
+
+// Fail unless the condition holds within the timeout
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(testTimeout)
+
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Collects logs written from any goroutine
+type logBuffer struct {
+	mux sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (logs *logBuffer) Write(data []byte) (int, error) {
+	logs.mux.Lock()
+	defer logs.mux.Unlock()
+
+	return logs.buffer.Write(data)
+}
+
+func (logs *logBuffer) String() string {
+	logs.mux.Lock()
+	defer logs.mux.Unlock()
+
+	return logs.buffer.String()
+}
+
+// Send the default logger's output to a buffer until the test ends. Only loggers made afterwards pick it up
+func captureLogs(t *testing.T) *logBuffer {
+	logs := &logBuffer{}
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	return logs
+}
+
+// A packet holding a message type this build of the server doesn't know about, and one it knows but which means
+// nothing before logging in, must be told apart: only the first is counted and logged as unknown
+func TestUnknownPacketCounted(t *testing.T) {
+	const unknownNumber protowire.Number = 99
+
+	logs := captureLogs(t)
+	hub, url := newTestServer(t)
+	conn, _ := connect(t, url)
+
+	unknownMessage := protowire.AppendTag(nil, unknownNumber, protowire.BytesType)
+	unknownMessage = protowire.AppendBytes(unknownMessage, nil)
+
+	if err := conn.WriteMessage(websocket.BinaryMessage, unknownMessage); err != nil {
+		t.Fatalf("Couldn't send a packet of an unknown type: %v", err)
+	}
+
+	sendPacket(t, conn, &packets.Packet{Msg: &packets.Packet_PlayerDirection{PlayerDirection: &packets.PlayerDirectionMessage{Direction: 1}}})
+	waitFor(t, "the direction to be received", func() bool { return hub.ReceivedPackets.Counts()["player_direction"] == 1 })
+
+	if unknown := hub.UnknownPackets.Load(); unknown != 1 {
+		t.Errorf("Counted %d unknown packets instead of 1", unknown)
+	}
+
+	if warnings := strings.Count(logs.String(), "unknown message types"); warnings != 1 {
+		t.Errorf("Logged %d warnings about unknown message types instead of 1:\n%s", warnings, logs.String())
+	}
+
+	if !strings.Contains(logs.String(), "numbers=[99]") {
+		t.Errorf("The unknown message type wasn't logged:\n%s", logs.String())
+	}
+}
+
+// A client closed for a reason too long for a close frame must still be sent the code for it and as much of the
+// reason as fits, cut between characters
+func TestCloseFrame(t *testing.T) {
+	hub, url := newTestServer(t)
+	conn, id := connect(t, url)
+	reason := strings.Repeat("é", 100)
+
+	if !hub.Kick(id, reason) {
+		t.Fatal("Kicking a connected client claimed there was no such client")
+	}
+
+	closeErr := readCloseError(t, conn)
+	fullReason := server.CloseReasonKicked + ": " + reason
+
+	if closeErr.Code != websocket.CloseNormalClosure {
+		t.Errorf("A kicked client was sent close code %d instead of %d", closeErr.Code, websocket.CloseNormalClosure)
+	}
+
+	if !utf8.ValidString(closeErr.Text) || len(closeErr.Text) < 122 || !strings.HasPrefix(fullReason, closeErr.Text) {
+		t.Errorf("A kicked client was sent reason %q instead of the start of %q", closeErr.Text, fullReason)
+	}
+}
+
+func TestTruncateUtf8(t *testing.T) {
+	tests := []struct {
+		s string
+		maxBytes int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"exactly", 7, "exactly"},
+		{"abcdef", 3, "abc"},
+		{"aé", 2, "a"},
+		{"éé", 3, "é"},
+		{"日本語", 5, "日"},
+		{"日本語", 6, "日本"},
+		{"é", 1, ""},
+	}
+
+	for _, test := range tests {
+		if truncated := truncateUtf8(test.s, test.maxBytes); truncated != test.expected {
+			t.Errorf("Truncating %q to %d bytes gave %q instead of %q", test.s, test.maxBytes, truncated, test.expected)
+		}
+	}
+}
+
+// A client for a connection to a test server, with neither its pumps nor a hub running
+func newUnregisteredClient(t *testing.T) *WebsocketClient {
+	hub := server.NewHub(server.NewServerConfig())
+	clients := make(chan server.ClientInterfacer, 1)
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		client, err := NewWebsocketClient(hub, nil, writer, request)
+
+		if err != nil {
+			t.Errorf("Couldn't upgrade the connection: %v", err)
+		}
+
+		clients <- client
+	}))
+
+	t.Cleanup(httpServer.Close)
+	dial(t, "ws" + strings.TrimPrefix(httpServer.URL, "http"))
+
+	return (<-clients).(*WebsocketClient)
+}
+
+// Writing to a connection which has gone must fail, so the write pump stops rather than carrying on into the void
+func TestWritePacketError(t *testing.T) {
+	client := newUnregisteredClient(t)
+	client.conn.Close()
+
+	if err := client.writePacket(&packets.Packet{Msg: packets.NewOkResponse()}); err == nil {
+		t.Error("Writing to a closed connection succeeded")
+	}
+}
+
+// The client connected with the given ID, as the server sees it
+func serverSideClient(t *testing.T, hub *server.Hub, id uint64) *WebsocketClient {
+	client, exists := hub.Clients.Get(id)
+
+	if !exists {
+		t.Fatalf("Client %d isn't connected", id)
+	}
+
+	return client.(*WebsocketClient)
+}
+
+// A packet which can't be marshalled must be counted and dropped, leaving the connection open for the packets after it
+func TestMarshalFailure(t *testing.T) {
+	for _, retry := range []bool{false, true} {
+		hub, url := newTestServer(t, func(config *server.ServerConfig) { config.RetryFailedMarshal = retry })
+		conn, id := connect(t, url)
+		client := serverSideClient(t, hub, id)
+
+		// Strings have to be valid UTF-8 to be marshalled
+		client.SocketSend(packets.NewChat("\xff"))
+		client.SocketSend(packets.NewChat("still here"))
+
+		received := readUntil(t, conn, func(packet *packets.Packet) bool { return packet.GetChat() != nil })
+
+		if msg := received.GetChat().GetMsg(); msg != "still here" {
+			t.Errorf("Received chat %q after a packet which couldn't be marshalled, with retries %t", msg, retry)
+		}
+
+		if marshalErrors := hub.MarshalErrors.Load(); marshalErrors != 1 {
+			t.Errorf("Counted %d marshal errors instead of 1, with retries %t", marshalErrors, retry)
+		}
+	}
+}
+
+// Once a connection has been open for its lifetime, the client must be asked to reconnect and then sent the close code
+// for a restart, so it knows to come straight back
+func TestMaxConnectionLifetime(t *testing.T) {
+	_, url := newTestServer(t, func(config *server.ServerConfig) {
+		config.MaxConnectionLifetime = 100 * time.Millisecond
+		config.ConnectionLifetimeJitter = 0
+	})
+	conn, _ := connect(t, url)
+
+	reconnect := readUntil(t, conn, func(packet *packets.Packet) bool { return packet.GetReconnect() != nil })
+
+	if reason := reconnect.GetReconnect().GetReason(); reason != server.CloseReasonMaxLifetime {
+		t.Errorf("The client was asked to reconnect because %q", reason)
+	}
+
+	if closeErr := readCloseError(t, conn); closeErr.Code != websocket.CloseServiceRestart {
+		t.Errorf("A connection past its lifetime was closed with code %d instead of %d", closeErr.Code, websocket.CloseServiceRestart)
+	}
+}
+
+// Connections from one IP past the limit for the window must be refused before upgrading, leaving other IPs free to
+// connect
+func TestConnectionRateLimit(t *testing.T) {
+	const limit int = 3
+
+	_, url := newTestServer(t, func(config *server.ServerConfig) {
+		config.ConnectionsPerWindow = limit
+		config.ConnectionWindow = time.Hour
+		config.TrustForwardedFor = true
+	})
+
+	dialFrom := func(ip string) (*websocket.Conn, *http.Response, error) {
+		conn, response, err := websocket.DefaultDialer.Dial(url, http.Header{"X-Forwarded-For": {ip}})
+
+		if err == nil {
+			t.Cleanup(func() { conn.Close() })
+		}
+
+		return conn, response, err
+	}
+
+	for i := range limit {
+		if _, _, err := dialFrom("10.0.0.1"); err != nil {
+			t.Fatalf("Connection %d of %d from one IP was refused: %v", i + 1, limit, err)
+		}
+	}
+
+	_, response, err := dialFrom("10.0.0.1")
+
+	if err == nil || response == nil || response.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("A connection past the limit wasn't refused with a 429: %v", err)
+	}
+
+	if _, _, err := dialFrom("10.0.0.2"); err != nil {
+		t.Errorf("A connection from another IP was refused: %v", err)
+	}
+}
+
+// A time sync must be answered with the client's own time and the server's, whatever state the client is in
+func TestTimeSync(t *testing.T) {
+	const clientTime int64 = 12345
+
+	_, url := newTestServer(t)
+	conn, _ := connect(t, url)
+
+	before := time.Now().UnixMilli()
+	sendPacket(t, conn, &packets.Packet{Msg: &packets.Packet_TimeSync{TimeSync: &packets.TimeSyncMessage{ClientTime: clientTime}}})
+	reply := readUntil(t, conn, func(packet *packets.Packet) bool { return packet.GetTimeSync() != nil }).GetTimeSync()
+	after := time.Now().UnixMilli()
+
+	if reply.ClientTime != clientTime {
+		t.Errorf("A time sync was answered with client time %d instead of %d", reply.ClientTime, clientTime)
+	}
+
+	// The server's time is counted on the monotonic clock and rounded down to the millisecond, so allow one either way
+	if reply.ServerTime < before - 1 || reply.ServerTime > after + 1 {
+		t.Errorf("A time sync was answered with server time %d outside of %d to %d", reply.ServerTime, before, after)
+	}
+}
+
+// Counts the messages it's handed
+type countingState struct {
+	handled atomic.Int64
+}
+
+func (state *countingState) Name() string {
+	return "Counting"
+}
+
+func (state *countingState) SetClient(_ server.ClientInterfacer) {}
+
+func (state *countingState) OnEnter() {}
+
+func (state *countingState) HandleMessage(_ uint64, _ packets.Msg) {
+	state.handled.Add(1)
+}
+
+func (state *countingState) OnExit() {}
+
+// Messages delivered from other goroutines while the client switches states and closes must not race on its state (run
+// with -race), and none may reach a state once the client is closed
+func TestStateChangeWhileDelivering(t *testing.T) {
+	const deliverers int = 8
+
+	hub, url := newTestServer(t)
+	_, id := connect(t, url)
+	client := serverSideClient(t, hub, id)
+	state := &countingState{}
+	client.SetState(state)
+
+	var delivering sync.WaitGroup
+	stop := make(chan struct{})
+
+	for range deliverers {
+		delivering.Go(func() {
+			for {
+				select {
+					case <-stop:
+						return
+					default:
+						client.ProcessMessage(id, packets.NewChat("hello"))
+				}
+			}
+		})
+	}
+
+	waitFor(t, "messages to reach the state", func() bool { return state.handled.Load() > 0 })
+
+	for range 100 {
+		client.SetState(&countingState{})
+		client.SetState(state)
+	}
+
+	client.Close("test")
+	close(stop)
+	delivering.Wait()
+
+	handled := state.handled.Load()
+	client.ProcessMessage(id, packets.NewChat("too late"))
+
+	if late := state.handled.Load() - handled; late > 0 {
+		t.Errorf("The state handled %d messages after the client closed", late)
+	}
+}
+
+// Takes as long as it's told to over chat messages, and no time over anything else
+type slowState struct {
+	countingState
+	delay time.Duration
+}
+
+func (state *slowState) Name() string {
+	return "Slow"
+}
+
+func (state *slowState) HandleMessage(senderId uint64, message packets.Msg) {
+	if _, ok := message.(*packets.Packet_Chat); ok {
+		time.Sleep(state.delay)
+	}
+
+	state.countingState.HandleMessage(senderId, message)
+}
+
+// A message which takes longer than the slow handler threshold to handle must be logged as a warning naming the state,
+// the packet type and the clients involved, while quick ones aren't logged at all
+func TestSlowHandlerLogged(t *testing.T) {
+	const threshold time.Duration = 20 * time.Millisecond
+	const senderId uint64 = 42
+
+	logs := captureLogs(t)
+	hub, url := newTestServer(t, func(config *server.ServerConfig) { config.SlowHandlerThreshold = threshold })
+	_, id := connect(t, url)
+	client := serverSideClient(t, hub, id)
+	client.SetState(&slowState{delay: 2 * threshold})
+
+	client.ProcessMessage(senderId, &packets.Packet_PlayerDirection{PlayerDirection: &packets.PlayerDirectionMessage{Direction: 1}})
+
+	if strings.Contains(logs.String(), "Slow handler") {
+		t.Fatalf("A quick handler was logged as slow:\n%s", logs)
+	}
+
+	client.ProcessMessage(senderId, packets.NewChat("take your time"))
+
+	var warning string
+
+	for line := range strings.Lines(logs.String()) {
+		if strings.Contains(line, "Slow handler") {
+			warning = line
+		}
+	}
+
+	for _, detail := range []string{"level=WARN", "state=Slow", "packet_type=chat", fmt.Sprintf("client_id=%d", id), fmt.Sprintf("sender_id=%d", senderId)} {
+		if !strings.Contains(warning, detail) {
+			t.Errorf("The slow handler warning didn't include %s: %q", detail, warning)
+		}
+	}
+}
+
+// Every byte written for a packet, its trailing newline included, must be counted for the client and for the hub, under
+// the packet's type
+func TestTrafficCounted(t *testing.T) {
+	hub, url := newTestServer(t, func(config *server.ServerConfig) { config.CountTraffic = true })
+	conn, id := connect(t, url)
+	client := serverSideClient(t, hub, id)
+
+	clientBefore := client.SentTraffic().Snapshot()
+	hubBefore := hub.SentTraffic.Snapshot()
+	expected := uint64(0)
+
+	for _, msg := range []string{"a", "a longer chat message", "done"} {
+		chat := packets.NewChat(msg)
+		data, err := proto.Marshal(&packets.Packet{SenderId: id, Msg: chat})
+
+		if err != nil {
+			t.Fatalf("Couldn't marshal a chat packet: %v", err)
+		}
+
+		expected += uint64(len(data) + 1)
+		client.SocketSend(chat)
+	}
+
+	readUntil(t, conn, func(packet *packets.Packet) bool { return packet.GetChat().GetMsg() == "done" })
+
+	// The counters are updated just after the packet is written
+	waitFor(t, "the chats to be counted", func() bool {
+		return client.SentTraffic().Snapshot().ByType["Chat"] - clientBefore.ByType["Chat"] >= expected
+	})
+
+	clientAfter := client.SentTraffic().Snapshot()
+	hubAfter := hub.SentTraffic.Snapshot()
+
+	if counted := clientAfter.ByType["Chat"] - clientBefore.ByType["Chat"]; counted != expected {
+		t.Errorf("Counted %d bytes of chat for the client instead of %d", counted, expected)
+	}
+
+	if counted := hubAfter.ByType["Chat"] - hubBefore.ByType["Chat"]; counted != expected {
+		t.Errorf("Counted %d bytes of chat for the hub instead of %d", counted, expected)
+	}
+
+	if clientAfter.Bytes - clientBefore.Bytes < expected || hubAfter.Bytes - hubBefore.Bytes < expected {
+		t.Errorf("The chats weren't counted in the totals: the client's went from %d to %d and the hub's from %d to %d", clientBefore.Bytes, clientAfter.Bytes, hubBefore.Bytes, hubAfter.Bytes)
+	}
+}
+
+// Sending a leaderboard to every client in a snapshot must carry on past a client which closes partway through, with
+// the sends to it skipped and counted rather than panicking, and the other clients still getting theirs
+func TestSendToClosedClientSkipped(t *testing.T) {
+	const rounds int = 200
+
+	hub, url := newTestServer(t)
+	stayingConn, stayingId := connect(t, url)
+	_, leavingId := connect(t, url)
+	leaving := serverSideClient(t, hub, leavingId)
+	recipients := hub.Clients.Snapshot()
+	skippedBefore := hub.SkippedSends.Load()
+
+	leaderboard := packets.NewLeaderboard([]*packets.LeaderboardEntry{{PlayerId: stayingId, Name: "staying", Radius: 40}})
+	var sending sync.WaitGroup
+
+	sending.Go(func() {
+		for range rounds {
+			for _, recipient := range recipients {
+				recipient.SocketSend(leaderboard)
+			}
+		}
+	})
+
+	leaving.Close("test")
+	sending.Wait()
+
+	for _, recipient := range recipients {
+		recipient.SocketSend(leaderboard)
+	}
+
+	if skipped := hub.SkippedSends.Load() - skippedBefore; skipped == 0 {
+		t.Error("No sends to the closed client were counted as skipped")
+	}
+
+	readUntil(t, stayingConn, func(packet *packets.Packet) bool { return packet.GetLeaderboard() != nil })
+}
+
+// Connecting with a room name must put the client in that room, apart from clients in the default room
+func TestRoomQueryParam(t *testing.T) {
+	hub, url := newTestServer(t)
+	_, lobbyId := connect(t, url)
+	_, arenaId := connect(t, url + "?room=arena")
+	lobby := serverSideClient(t, hub, lobbyId).Room()
+	arena := serverSideClient(t, hub, arenaId).Room()
+
+	if lobby.Name != server.DefaultRoomName || arena.Name != "arena" {
+		t.Fatalf("Clients connecting without and with ?room=arena are in rooms %q and %q", lobby.Name, arena.Name)
+	}
+
+	if lobby == arena || lobby.SharedGameObjects == arena.SharedGameObjects {
+		t.Error("Clients in different rooms share a world")
+	}
+
+	if _, exists := arena.Clients.Get(lobbyId); exists {
+		t.Error("A client in the default room is among the arena's clients")
+	}
+}
+
+// Closing a client several times over, from other goroutines and from both of its pumps as the connection goes, while
+// packets are still being sent to it, must close it exactly once without panicking and take it out of the hub
+func TestDoubleClose(t *testing.T) {
+	const closers int = 8
+
+	hub, url := newTestServer(t)
+	conn, id := connect(t, url)
+	client := serverSideClient(t, hub, id)
+
+	var closing sync.WaitGroup
+	start := make(chan struct{})
+
+	closing.Go(func() {
+		<-start
+
+		for range 1000 {
+			client.SocketSend(packets.NewChat("still talking"))
+		}
+	})
+
+	for range closers {
+		closing.Go(func() {
+			<-start
+			client.Close("test")
+		})
+	}
+
+	close(start)
+	closing.Wait()
+	client.Close("test")
+
+	if closeErr := readCloseError(t, conn); closeErr.Code != websocket.CloseNormalClosure {
+		t.Errorf("The connection was closed with code %d instead of a normal closure", closeErr.Code)
+	}
+
+	waitFor(t, "the client to leave the hub", func() bool {
+		_, connected := hub.Clients.Get(id)
+		return !connected
+	})
+}
+
+// A client which stops answering pings must be dropped once the pong wait runs out, while one which answers them stays
+// connected however long it's quiet for
+func TestPingPong(t *testing.T) {
+	const pongWait = 300 * time.Millisecond
+
+	hub, url := newTestServer(t, func(config *server.ServerConfig) {
+		config.PongWait = pongWait
+		config.PingInterval = pongWait / 3
+	})
+
+	answering, answeringId := connect(t, url)
+	silent, silentId := connect(t, url)
+
+	// Pings are only answered while reading, by the default ping handler for the answering client
+	var pingsIgnored atomic.Int32
+	silent.SetPingHandler(func(string) error {
+		pingsIgnored.Add(1)
+		return nil
+	})
+
+	for _, conn := range []*websocket.Conn{answering, silent} {
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	waitFor(t, "the silent client to be dropped", func() bool {
+		_, connected := hub.Clients.Get(silentId)
+		return !connected
+	})
+
+	if pingsIgnored.Load() == 0 {
+		t.Error("The silent client was dropped without being pinged")
+	}
+
+	time.Sleep(2 * pongWait)
+
+	if _, connected := hub.Clients.Get(answeringId); !connected {
+		t.Error("A client answering pings was dropped")
+	}
+}
+
+// Counts the bytes read off a connection
+type countingConn struct {
+	net.Conn
+	read *atomic.Int64
+}
+
+func (conn countingConn) Read(data []byte) (int, error) {
+	n, err := conn.Conn.Read(data)
+	conn.read.Add(int64(n))
+	return n, err
+}
+
+// A big spores batch must come out the same on a client which negotiated compression as it went in, taking fewer bytes
+// over the wire than it would on a client without compression, and small packets must still get through unharmed
+func TestCompression(t *testing.T) {
+	const threshold int = 1024
+
+	hub, url := newTestServer(t, func(config *server.ServerConfig) { config.CompressionThreshold = threshold })
+
+	spores := make(map[uint64]*objects.Spore, 1000)
+
+	for i := range 1000 {
+		spores[uint64(i)] = &objects.Spore{X: float64(i % 40) * 10, Y: float64(i / 40) * 10, Radius: 10}
+	}
+
+	batch := packets.NewSporesBatch(spores)
+
+	if size := proto.Size(&packets.Packet{Msg: batch}); size < 10 * threshold {
+		t.Fatalf("The spores batch is only %d bytes, too small to test compression with", size)
+	}
+
+	// The bytes it took to receive the batch, on a client which does or doesn't support compression
+	receiveBatch := func(compress bool) int64 {
+		var read atomic.Int64
+		netDialer := &net.Dialer{}
+		dialer := &websocket.Dialer{
+			EnableCompression: compress,
+			NetDialContext: func(ctx context.Context, network string, address string) (net.Conn, error) {
+				conn, err := netDialer.DialContext(ctx, network, address)
+				return countingConn{Conn: conn, read: &read}, err
+			},
+		}
+
+		conn, _, err := dialer.Dial(url, nil)
+
+		if err != nil {
+			t.Fatalf("Couldn't connect to %s: %v", url, err)
+		}
+
+		t.Cleanup(func() { conn.Close() })
+
+		id := readUntil(t, conn, func(packet *packets.Packet) bool { return packet.GetId() != nil }).GetId().Id
+		client := serverSideClient(t, hub, id)
+		before := read.Load()
+
+		client.SocketSend(batch)
+		received := readUntil(t, conn, func(packet *packets.Packet) bool { return packet.GetSporesBatch() != nil })
+
+		if expected := (&packets.Packet{SenderId: id, Msg: batch}); !proto.Equal(received, expected) {
+			t.Errorf("The spores batch came out different with compression %t", compress)
+		}
+
+		client.SocketSend(packets.NewChat("small"))
+
+		if chat := readUntil(t, conn, func(packet *packets.Packet) bool { return packet.GetChat() != nil }); chat.GetChat().Msg != "small" {
+			t.Errorf("A small chat message came out as %q with compression %t", chat.GetChat().Msg, compress)
+		}
+
+		return read.Load() - before
+	}
+
+	compressed := receiveBatch(true)
+	uncompressed := receiveBatch(false)
+
+	if compressed * 2 > uncompressed {
+		t.Errorf("The spores batch took %d bytes compressed, not much less than the %d it took uncompressed", compressed, uncompressed)
+	}
+}
\ No newline at end of file
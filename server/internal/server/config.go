@@ -0,0 +1,332 @@
+package server
+
+import (
+	"server/internal/server/objects"
+	"time"
+)
+
+// How close a player needs to get to another player to consume them
+const (
+	// The two players' circles touch
+	ConsumeModeContact = "contact"
+
+	// The other player's center is inside the consuming player's circle
+	ConsumeModeEngulf = "engulf"
+)
+
+// Gameplay settings shared by the hub and the client state handlers
+type ServerConfig struct {
+	// Shown to players on the menu
+	ServerName string
+	Motd string
+
+	// Name of the game mode, sent to players as they join so they can show the right UI
+	GameMode string
+
+	// Speed at which players are pulled toward the center of the world on top of their own movement (0 disables)
+	DriftStrength float64
+
+	// Starting radius of the safe zone around the world center (0 disables the zone)
+	ZoneStartRadius float64
+
+	// Smallest radius the safe zone will shrink to
+	ZoneMinRadius float64
+
+	// How many units the safe zone's radius shrinks each second
+	ZoneShrinkRate float64
+
+	// Fraction of mass lost every damage interval by players outside the safe zone
+	ZoneDamage float64
+	ZoneDamageInterval time.Duration
+
+	// How long a player can go without changing direction before they start losing mass (0 disables)
+	AfkThreshold time.Duration
+
+	// Fraction of mass lost every second by players who are AFK
+	AfkDecay float64
+
+	// Fraction of mass every player bigger than the decay floor radius loses every decay interval, to keep leaders from
+	// running away with the game (0 disables). Players never decay below the floor or their starting size
+	MassDecay float64
+	MassDecayInterval time.Duration
+	MassDecayMinRadius float64
+
+	// Half the width of the square world, which spans from -WorldBound to WorldBound on both axes
+	WorldBound float64
+
+	// How many spores the world is kept topped up to, and how often missing ones are put back
+	MaxSpores int
+	SporeReplenishInterval time.Duration
+
+	// New spores' radii are normally distributed around the mean, but never smaller than the minimum
+	SporeRadiusMean float64
+	SporeRadiusDeviation float64
+	MinSporeRadius float64
+
+	// How many hazards the world is kept topped up to (0 disables), and how big they are. Players bigger than the
+	// minimum radius who run into one pop it and lose the penalty fraction of their mass, while smaller players pass
+	// over them unharmed
+	MaxHazards int
+	HazardRadius float64
+	HazardMinPlayerRadius float64
+	HazardPenalty float64
+
+	// How often new spores are each type relative to the others, indexed by type. Rarer types are worth more mass, but
+	// only plain spores spawn by default
+	SporeTypeWeights [objects.SporeTypeCount]float64
+
+	// How big players start out, and how fast they move at that size. Bigger players slow down, but never below the
+	// minimum speed
+	PlayerStartRadius float64
+	PlayerSpeed float64
+	MinPlayerSpeed float64
+
+	// Most spores allowed in a single cell of the world grid when placing new spores (0 disables)
+	MaxSporesPerCell int
+
+	// Width and height of the world grid cells used to spread out spores
+	SporeCellSize float64
+
+	// Whether direction changes are queued up and each followed for part of the next tick, rather than only the last one
+	// before a tick counting
+	BufferInputs bool
+
+	// How close spores need to be to a player to be sent to their client, with the rest sent as the player gets near
+	// them (0 sends every spore)
+	SporeSyncRadius float64
+
+	// How close a consumption needs to happen to a player for their client to be told about it, besides the players
+	// involved (0 tells everyone)
+	ConsumptionViewRange float64
+
+	// How close the edge of another player needs to be to a player for their client to be sent the other player's
+	// movements. Players going out of range are removed from view until they come back (0 sends every player)
+	PlayerViewRange float64
+
+	// Whether to try marshalling an outgoing packet a second time before dropping it
+	RetryFailedMarshal bool
+
+	// How long a connection can stay open before the client is asked to reconnect (0 disables)
+	MaxConnectionLifetime time.Duration
+
+	// Fraction the maximum connection lifetime is randomly lengthened or shortened by for each connection
+	ConnectionLifetimeJitter float64
+
+	// How many times more massive than another player a player needs to be to consume them
+	ConsumeRatio float64
+
+	// One of the ConsumeMode constants
+	ConsumeMode string
+
+	// In engulf mode, the fraction of the consuming player's radius the other player's center must be past the edge
+	EngulfFraction float64
+
+	// Whether each room moves all of its players together in one simulation loop, rather than each on their own loop
+	SharedTick bool
+
+	// Players who haven't changed since they were last sent aren't sent again until this long has passed, so clients
+	// which start watching them still find out where they are (0 sends every player every tick)
+	PlayerKeepaliveInterval time.Duration
+
+	// Number of goroutines running the clients' short background tasks, and how many tasks can wait for them before
+	// any more get goroutines of their own
+	WorkerPoolSize int
+	WorkerQueueSize int
+
+	// How long a player must wait after consuming another player before they can consume another (0 disables)
+	PlayerConsumeCooldown time.Duration
+
+	// How long a player has to wait after spawning before they can consume other players (0 disables)
+	SpawnConsumeGrace time.Duration
+
+	// Most spores and players in the world together. New spores stop being placed once it's reached (0 disables)
+	MaxWorldObjects int
+
+	// Most spores put back into the world each time the spore count is topped up
+	SporeReplenishBatch int
+
+	// Until this many players are in the game, growing from spores stops at the fair start radius (0 disables)
+	FairStartPlayers int
+	FairStartMaxRadius float64
+
+	// Fraction of extra speed a player gets right after consuming another player (0 disables), and how long it takes
+	// to wear off
+	KillSpeedBoost float64
+	KillSpeedBoostDuration time.Duration
+
+	// Fraction of the usual starting mass a player respawns with after being consumed by a much larger player, growing
+	// back to the full starting mass the closer the fight was (1 disables)
+	MinRespawnMass float64
+
+	// Most players in the game at once from a single IP address (0 disables)
+	MaxPlayersPerIp int
+
+	// Whether clients' IP addresses are taken from the X-Forwarded-For header, set by a proxy in front of the server
+	TrustForwardedFor bool
+
+	// Most connections accepted from one IP address in each connection window (0 disables)
+	ConnectionsPerWindow int
+	ConnectionWindow time.Duration
+
+	// Whether new spores are placed ahead of players rather than anywhere in the world
+	SmartReplenish bool
+
+	// How long a spore lasts before it's removed and replaced somewhere else (0 disables)
+	SporeLifetime time.Duration
+
+	// How close spores need to be to each other to be merged into one bigger spore with their combined mass, which
+	// happens every few seconds (0 disables)
+	SporeMergeRadius float64
+
+	// Most registrations which can be using the database at once, beyond which players are asked to try again
+	// (0 disables)
+	MaxConcurrentRegistrations int
+
+	// How often the player count and biggest player are sent to everyone in the game (0 disables)
+	ServerStatsInterval time.Duration
+
+	// Packets at least this many bytes are compressed for clients which support it, leaving small ones like movement
+	// updates alone since compressing them isn't worth it (0 disables)
+	CompressionThreshold int
+
+	// How long to wait for a pong after pinging a client before giving up on the connection, and how often to ping.
+	// The ping interval has to be shorter than the pong wait (0 disables both)
+	PongWait time.Duration
+	PingInterval time.Duration
+
+	// How long a player in the game can go without sending anything before they're disconnected (0 disables)
+	IdleTimeout time.Duration
+
+	// How long players who drop out are held on to, so they can resume with their session token (0 disables)
+	ResumeWindow time.Duration
+
+	// Most clients connected at once (0 disables), and how many more can wait in line for a place when that many are
+	// connected. Clients beyond those are turned away
+	MaxClients int
+	ClientQueueSize int
+
+	// Most rooms which can be played in at once, including the default room (0 disables), and how long a room is kept
+	// around after its last player leaves
+	MaxRooms int
+	RoomIdleTimeout time.Duration
+
+	// How many of the biggest players are sent to everyone in the game every second (0 disables)
+	LeaderboardSize int
+
+	// Whether players are kept from chatting with each other
+	ChatDisabled bool
+
+	// How many chat messages each player can send per chat rate period (0 disables)
+	ChatRateLimit int
+	ChatRatePeriod time.Duration
+
+	// How long handling a single message can take before a warning is logged (0 disables)
+	SlowHandlerThreshold time.Duration
+
+	// Whether the bytes sent to each client are counted, for the admin traffic report
+	CountTraffic bool
+
+	// Number of recent significant events kept for each client, for debugging (0 disables)
+	EventLogSize int
+
+	// Most bytes in a username or password sent to log in or register, checked before any other work is done with them
+	// (0 disables). Passwords are also held to the 72 bytes bcrypt hashes, less the pepper's length
+	MaxCredentialLength int
+
+	// Whether players are kept from joining the game, while those already playing carry on
+	Maintenance bool
+
+	// Whether the game is frozen: nobody moves, grows, or shrinks and no spores come or go
+	Paused bool
+
+	// Secret required to use the admin endpoints, which are disabled when it's empty
+	AdminToken string
+}
+
+// Creates a config with the default settings
+func NewServerConfig() *ServerConfig {
+	return &ServerConfig{
+		ServerName: "Radius Rumble",
+		Motd: "",
+		GameMode: "ffa",
+		DriftStrength: 0,
+		ZoneStartRadius: 0,
+		ZoneMinRadius: 500,
+		ZoneShrinkRate: 5,
+		ZoneDamage: 0.05,
+		ZoneDamageInterval: time.Second,
+		AfkThreshold: 0,
+		AfkDecay: 0.02,
+		MassDecay: 0,
+		MassDecayInterval: 5 * time.Second,
+		MassDecayMinRadius: 50,
+		WorldBound: objects.DefaultWorldBound,
+		MaxSpores: 1000,
+		SporeReplenishInterval: 2 * time.Second,
+		SporeRadiusMean: 10,
+		SporeRadiusDeviation: 3,
+		MinSporeRadius: 5,
+		SporeTypeWeights: [objects.SporeTypeCount]float64{objects.SporeTypePlain: 1},
+		MaxHazards: 0,
+		HazardRadius: 40,
+		HazardMinPlayerRadius: 60,
+		HazardPenalty: 0.3,
+		PlayerStartRadius: 20,
+		PlayerSpeed: 15,
+		MinPlayerSpeed: 5,
+		MaxSporesPerCell: 0,
+		SporeCellSize: 500,
+		BufferInputs: false,
+		SporeSyncRadius: 0,
+		ConsumptionViewRange: 0,
+		PlayerViewRange: 0,
+		RetryFailedMarshal: false,
+		MaxConnectionLifetime: 0,
+		ConnectionLifetimeJitter: 0.1,
+		ConsumeRatio: 1.5,
+		ConsumeMode: ConsumeModeContact,
+		EngulfFraction: 0,
+		SharedTick: true,
+		PlayerKeepaliveInterval: time.Second,
+		WorkerPoolSize: 16,
+		WorkerQueueSize: 1024,
+		PlayerConsumeCooldown: 0,
+		SpawnConsumeGrace: 0,
+		MaxWorldObjects: 0,
+		SporeReplenishBatch: 10,
+		FairStartPlayers: 0,
+		FairStartMaxRadius: 40,
+		KillSpeedBoost: 0,
+		KillSpeedBoostDuration: 2 * time.Second,
+		MinRespawnMass: 1,
+		MaxPlayersPerIp: 0,
+		TrustForwardedFor: false,
+		ConnectionsPerWindow: 0,
+		ConnectionWindow: time.Minute,
+		SmartReplenish: false,
+		SporeLifetime: 0,
+		SporeMergeRadius: 0,
+		MaxConcurrentRegistrations: 0,
+		ServerStatsInterval: 0,
+		LeaderboardSize: 10,
+		MaxClients: 0,
+		ClientQueueSize: 0,
+		MaxRooms: 16,
+		ResumeWindow: 30 * time.Second,
+		IdleTimeout: time.Minute,
+		CompressionThreshold: 1024,
+		PongWait: 60 * time.Second,
+		PingInterval: 54 * time.Second,
+		RoomIdleTimeout: time.Minute,
+		ChatDisabled: false,
+		ChatRateLimit: 3,
+		ChatRatePeriod: 2 * time.Second,
+		SlowHandlerThreshold: 0,
+		CountTraffic: false,
+		EventLogSize: 0,
+		MaxCredentialLength: 256,
+		Maintenance: false,
+		Paused: false,
+		AdminToken: "",
+	}
+}
\ No newline at end of file
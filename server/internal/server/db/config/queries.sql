@@ -2,4 +2,22 @@
 SELECT * FROM users WHERE username = ? LIMIT 1;
 
 -- name: CreateUser :one
-INSERT INTO users (username, password) VALUES (?, ?) RETURNING *;
\ No newline at end of file
+INSERT INTO users (username, password) VALUES (?, ?) RETURNING *;
+
+-- name: CreateMatch :one
+INSERT INTO matches (user_id, peak_mass, duration_ms, kills, end_reason) VALUES (?, ?, ?, ?, ?) RETURNING *;
+
+-- name: GetRecentMatches :many
+SELECT * FROM matches WHERE user_id = ? ORDER BY ended_at DESC, id DESC LIMIT ?;
+
+-- name: BanUser :exec
+INSERT INTO bans (username, reason) VALUES (?, ?) ON CONFLICT (username) DO UPDATE SET reason = excluded.reason;
+
+-- name: UnbanUser :execrows
+DELETE FROM bans WHERE username = ?;
+
+-- name: GetBan :one
+SELECT * FROM bans WHERE username = ? LIMIT 1;
+
+-- name: ListBans :many
+SELECT * FROM bans ORDER BY banned_at DESC, username;
\ No newline at end of file
@@ -2,4 +2,21 @@ CREATE TABLE IF NOT EXISTS users (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
   username VARCHAR(20) NOT NULL UNIQUE,
   password VARCHAR(60) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS matches (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  peak_mass REAL NOT NULL,
+  duration_ms INTEGER NOT NULL,
+  kills INTEGER NOT NULL,
+  end_reason VARCHAR(20) NOT NULL,
+  ended_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS bans (
+  username VARCHAR(20) PRIMARY KEY,
+  reason TEXT NOT NULL,
+  banned_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 )
\ No newline at end of file
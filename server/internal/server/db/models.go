@@ -4,6 +4,26 @@
 
 package db
 
+import (
+	"time"
+)
+
+type Ban struct {
+	Username string
+	Reason   string
+	BannedAt time.Time
+}
+
+type Match struct {
+	ID         int64
+	UserID     int64
+	PeakMass   float64
+	DurationMs int64
+	Kills      int64
+	EndReason  string
+	EndedAt    time.Time
+}
+
 type User struct {
 	ID       int64
 	Username string
@@ -9,6 +9,53 @@ import (
 	"context"
 )
 
+const banUser = `-- name: BanUser :exec
+INSERT INTO bans (username, reason) VALUES (?, ?) ON CONFLICT (username) DO UPDATE SET reason = excluded.reason
+`
+
+type BanUserParams struct {
+	Username string
+	Reason   string
+}
+
+func (q *Queries) BanUser(ctx context.Context, arg BanUserParams) error {
+	_, err := q.db.ExecContext(ctx, banUser, arg.Username, arg.Reason)
+	return err
+}
+
+const createMatch = `-- name: CreateMatch :one
+INSERT INTO matches (user_id, peak_mass, duration_ms, kills, end_reason) VALUES (?, ?, ?, ?, ?) RETURNING id, user_id, peak_mass, duration_ms, kills, end_reason, ended_at
+`
+
+type CreateMatchParams struct {
+	UserID     int64
+	PeakMass   float64
+	DurationMs int64
+	Kills      int64
+	EndReason  string
+}
+
+func (q *Queries) CreateMatch(ctx context.Context, arg CreateMatchParams) (Match, error) {
+	row := q.db.QueryRowContext(ctx, createMatch,
+		arg.UserID,
+		arg.PeakMass,
+		arg.DurationMs,
+		arg.Kills,
+		arg.EndReason,
+	)
+	var i Match
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.PeakMass,
+		&i.DurationMs,
+		&i.Kills,
+		&i.EndReason,
+		&i.EndedAt,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (username, password) VALUES (?, ?) RETURNING id, username, password
 `
@@ -25,6 +72,57 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 	return i, err
 }
 
+const getBan = `-- name: GetBan :one
+SELECT username, reason, banned_at FROM bans WHERE username = ? LIMIT 1
+`
+
+func (q *Queries) GetBan(ctx context.Context, username string) (Ban, error) {
+	row := q.db.QueryRowContext(ctx, getBan, username)
+	var i Ban
+	err := row.Scan(&i.Username, &i.Reason, &i.BannedAt)
+	return i, err
+}
+
+const getRecentMatches = `-- name: GetRecentMatches :many
+SELECT id, user_id, peak_mass, duration_ms, kills, end_reason, ended_at FROM matches WHERE user_id = ? ORDER BY ended_at DESC, id DESC LIMIT ?
+`
+
+type GetRecentMatchesParams struct {
+	UserID int64
+	Limit  int64
+}
+
+func (q *Queries) GetRecentMatches(ctx context.Context, arg GetRecentMatchesParams) ([]Match, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentMatches, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Match
+	for rows.Next() {
+		var i Match
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.PeakMass,
+			&i.DurationMs,
+			&i.Kills,
+			&i.EndReason,
+			&i.EndedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserByUsername = `-- name: GetUserByUsername :one
 SELECT id, username, password FROM users WHERE username = ? LIMIT 1
 `
@@ -35,3 +133,42 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 	err := row.Scan(&i.ID, &i.Username, &i.Password)
 	return i, err
 }
+
+const listBans = `-- name: ListBans :many
+SELECT username, reason, banned_at FROM bans ORDER BY banned_at DESC, username
+`
+
+func (q *Queries) ListBans(ctx context.Context) ([]Ban, error) {
+	rows, err := q.db.QueryContext(ctx, listBans)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Ban
+	for rows.Next() {
+		var i Ban
+		if err := rows.Scan(&i.Username, &i.Reason, &i.BannedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const unbanUser = `-- name: UnbanUser :execrows
+DELETE FROM bans WHERE username = ?
+`
+
+func (q *Queries) UnbanUser(ctx context.Context, username string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unbanUser, username)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
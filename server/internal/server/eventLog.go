@@ -0,0 +1,68 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// Something significant that happened to a client, kept for debugging disagreements between the client and server
+type Event struct {
+	Time   time.Time `json:"time"`
+	Kind   string    `json:"kind"`
+	Detail string    `json:"detail"`
+
+	// Where the client's player was at the time
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Keeps the most recent events up to a fixed size, overwriting the oldest. A nil event log records nothing
+type EventLog struct {
+	events []Event
+	next   int
+	full   bool
+	mux    sync.Mutex
+}
+
+// Creates an event log keeping the given number of events, or nil if the size isn't positive
+func NewEventLog(size int) *EventLog {
+	if size <= 0 {
+		return nil
+	}
+
+	return &EventLog{
+		events: make([]Event, size),
+	}
+}
+
+func (eventLog *EventLog) Record(kind string, detail string, x float64, y float64) {
+	if eventLog == nil {
+		return
+	}
+
+	eventLog.mux.Lock()
+	defer eventLog.mux.Unlock()
+
+	eventLog.events[eventLog.next] = Event{Time: time.Now(), Kind: kind, Detail: detail, X: x, Y: y}
+	eventLog.next = (eventLog.next + 1) % len(eventLog.events)
+
+	if eventLog.next == 0 {
+		eventLog.full = true
+	}
+}
+
+// A copy of the recorded events, oldest first
+func (eventLog *EventLog) Events() []Event {
+	if eventLog == nil {
+		return []Event{}
+	}
+
+	eventLog.mux.Lock()
+	defer eventLog.mux.Unlock()
+
+	if !eventLog.full {
+		return append([]Event{}, eventLog.events[:eventLog.next]...)
+	}
+
+	return append(append([]Event{}, eventLog.events[eventLog.next:]...), eventLog.events[:eventLog.next]...)
+}
\ No newline at end of file
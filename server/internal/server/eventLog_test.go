@@ -0,0 +1,30 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// Once full, the event log must drop the oldest events first and keep the rest in the order they happened
+func TestEventLogWraps(t *testing.T) {
+	eventLog := NewEventLog(3)
+
+	for i := range 5 {
+		eventLog.Record("input", fmt.Sprint(i), 0, 0)
+	}
+
+	details := make([]string, 0, 3)
+
+	for _, event := range eventLog.Events() {
+		details = append(details, event.Detail)
+	}
+
+	if !slices.Equal(details, []string{"2", "3", "4"}) {
+		t.Errorf("After 5 events, an event log of 3 kept %v", details)
+	}
+
+	if events := NewEventLog(0).Events(); len(events) != 0 {
+		t.Errorf("A disabled event log returned events %v", events)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,16 @@
+package server
+
+// Ways into the hub for the tests outside the package
+
+func (hub *Hub) Register(client ClientInterfacer) {
+	hub.register(client)
+}
+
+func (hub *Hub) Unregister(client ClientInterfacer) {
+	hub.unregister(client)
+}
+
+// How many clients are waiting for a place
+func (hub *Hub) Waiting() int {
+	return len(hub.waiting)
+}
\ No newline at end of file
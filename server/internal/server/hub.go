@@ -5,30 +5,66 @@ import (
 	"database/sql"
 	_ "embed"
 	"log"
-	"math/rand"
+	"log/slog"
+	"net"
 	"net/http"
 	"server/internal/server/db"
 	"server/internal/server/objects"
 	"server/pkg/packets"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "modernc.org/sqlite"
 )
 
-const MaxSpores = 1000
-
 //go:embed db/config/schema.sql
 var schemaGenSql string
 
 type DbTransaction struct {
 	Ctx context.Context
 	Queries *db.Queries
+
+	// Shared by all clients to limit how many registrations use the database at once
+	RegistrationSlots Semaphore
+
+	// Shared by all clients to save their matches without waiting on the database
+	Matches *MatchWriter
 }
 
 type SharedGameObjects struct {
 	// The ID of the player is the ID of the client
 	Players *objects.SharedCollection[*objects.Player]
 	Spores *objects.SharedCollection[*objects.Spore]
+	Hazards *objects.SharedCollection[*objects.Hazard]
+	Zone *objects.Zone
+
+	// How many players are in the game from each IP address
+	PlayersPerIp *KeyCounter
+}
+
+// Clients are numbered by their connection until they log in and take their account's user ID. Connections are numbered
+// from far above any user ID, so they never clash
+const FirstConnectionId uint64 = 1 << 40
+
+// Reasons for closing a client's connection, which are sent to the client along with a matching close code
+const (
+	CloseReasonReadPumpClosed = "Read pump closed"
+	CloseReasonWritePumpClosed = "Write pump closed"
+	CloseReasonMaxLifetime = "Connection reached its maximum lifetime, please reconnect"
+	CloseReasonUpdateLoopFailed = "Player update loop failed"
+	CloseReasonShutdown = "Server is shutting down"
+	CloseReasonIdle = "Disconnected for being idle too long"
+	CloseReasonKicked = "Kicked by an admin"
+	CloseReasonServerFull = "Server is full"
+)
+
+// A packet to be processed by all connected clients except the sender and the excluded clients
+type ExclusiveBroadcast struct {
+	Packet *packets.Packet
+	ExcludedIds []uint64
 }
 
 type ClientStateHandler interface {
@@ -59,11 +95,17 @@ type ClientInterfacer interface {
 	// Puts data from another client into the write pump
 	SocketSendAs(message packets.Msg, senderId uint64)
 
+	// Run a short task in the background on the hub's worker pool
+	RunAsync(task func())
+
 	// Foward message to another client for processing
 	PassToPeer(message packets.Msg, peerId uint64)
 
 	// Foward message to all other clients for processing
 	Broadcast(message packets.Msg)
+
+	// Foward message to all other clients for processing, except the given ones
+	BroadcastExcept(message packets.Msg, excludedIds ...uint64)
 	
 	// Pump data from the connected socket directly to the client
 	ReadPump()
@@ -74,8 +116,36 @@ type ClientInterfacer interface {
 	// A reference to the database transaction context for this client
 	DbTransaction() *DbTransaction
 
+	// The room the client is playing in
+	Room() *Room
+
+	// Session tokens, and the players waiting to resume with them
+	Sessions() *SessionStore
+
 	SharedGameObjects() *SharedGameObjects
 
+	// The server's current settings, which can change at runtime
+	Config() *ServerConfig
+
+	// Take on the given ID in place of the one the client connected with, so it's known by its account's user ID once
+	// logged in. Returns false if another client already has that ID
+	ClaimId(id uint64) bool
+
+	// Recent significant events for debugging, if enabled
+	EventLog() *EventLog
+
+	// Logs with the client's ID attached, for the states to add their own details to
+	Logger() *slog.Logger
+
+	// Cancelled when the server shuts down, for the client's background loops to stop with
+	Context() context.Context
+
+	// The IP address the client connected from
+	Ip() string
+
+	// The bytes sent to the client, if they're being counted
+	SentTraffic() *TrafficCounter
+
 	// Close the client's connections and cleanup
 	Close(reason string)
 }
@@ -83,129 +153,464 @@ type ClientInterfacer interface {
 type Hub struct {
 	Clients *objects.SharedCollection[ClientInterfacer]
 
-	// Packets in this channel will be processed by all connected clients except the sender
-	BroadcastChan chan *packets.Packet
+	// The rooms being played in, by name
+	rooms map[string]*Room
+	roomsMux sync.Mutex
 
 	// Clients in this channel will be registered to the hub
 	RegisterChan chan ClientInterfacer
 
+	// Every background loop stops when this is cancelled by shutting down
+	ctx context.Context
+	cancel context.CancelFunc
+
+	// Closed once the run loop has stopped
+	stopped chan struct{}
+
+	// The ID for the next client to connect, only used by the run loop
+	nextConnectionId uint64
+
+	// Clients waiting for a place while the server is full, in the order they came. Only used by the run loop
+	waiting []ClientInterfacer
+
 	// Clients in this channel will be unregistered from the hub
 	UnregisterChan chan ClientInterfacer
 
+	// Admin requests for the list of connected clients, each answered on the channel sent
+	clientListChan chan chan []clientInfo
+
 	// Database connection pool
 	dbPool *sql.DB
+	matchWriter *MatchWriter
+	registrationSlots Semaphore
+
+	// How many players are in the game from each IP address, across all rooms
+	playersPerIp *KeyCounter
+
+	// Session tokens, and the players waiting to resume with them
+	Sessions *SessionStore
+
+	// The server's settings, which are replaced as a whole whenever they change at runtime
+	config atomic.Pointer[ServerConfig]
+
+	// Runs the clients' short background tasks
+	WorkerPool *WorkerPool
+
+	startTime time.Time
+
+	// The stats last worked out for GET /stats, and when
+	cachedStats ServerStats
+	statsCachedAt time.Time
+	statsMux sync.Mutex
 
-	SharedGameObjects *SharedGameObjects 
+	// Limits how often new connections are accepted from each IP address
+	connectionLimiter *WindowLimiter
+
+	// Number of packets received with a message type this build of the server doesn't recognise
+	UnknownPackets atomic.Uint64
+
+	// Number of outgoing packets dropped because they couldn't be marshalled
+	MarshalErrors atomic.Uint64
+
+	// Number of spawns skipped to keep within the world object budget
+	SpawnsThrottled atomic.Uint64
+
+	// Number of packets not sent because the client they were for had already closed
+	SkippedSends atomic.Uint64
+
+	// Packets received from and written to clients, and the number of broadcasts, for the metrics
+	ReceivedPackets *PacketCounter
+	SentPackets *PacketCounter
+	Broadcasts atomic.Uint64
+
+	// The bytes sent to all clients together, if they're being counted
+	SentTraffic *TrafficCounter
 }
 
 func (hub *Hub) NewDbTransaction() *DbTransaction {
 	return &DbTransaction{
 		Ctx: context.Background(),
 		Queries: db.New(hub.dbPool),
+		RegistrationSlots: hub.registrationSlots,
+		Matches: hub.matchWriter,
 	}
 }
 
-func NewHub() *Hub {
+// Create the server's tables in the given database, leaving any which are already there
+func InitDatabase(ctx context.Context, dbPool *sql.DB) error {
+	_, err := dbPool.ExecContext(ctx, schemaGenSql)
+	return err
+}
+
+func NewHub(config *ServerConfig) *Hub {
 	dbPool, err:= sql.Open("sqlite", "db.sqlite")
 
 	if err != nil {
 		log.Fatalf("Error opening database: %v", err)
 	}
 
-	return &Hub{
+	ctx, cancel := context.WithCancel(context.Background())
+
+	hub := &Hub{
+		ctx: ctx,
+		cancel: cancel,
+		stopped: make(chan struct{}),
 		Clients: objects.NewSharedCollection[ClientInterfacer](),
-		BroadcastChan: make(chan *packets.Packet),
+		nextConnectionId: FirstConnectionId,
+		rooms: make(map[string]*Room),
 		RegisterChan: make(chan ClientInterfacer),
 		UnregisterChan: make(chan ClientInterfacer),
-		SharedGameObjects: &SharedGameObjects{
-			Players: objects.NewSharedCollection[*objects.Player](),
-			Spores: objects.NewSharedCollection[*objects.Spore](),
-		},
+		clientListChan: make(chan chan []clientInfo),
+		playersPerIp: NewKeyCounter(),
+		Sessions: NewSessionStore(),
 		dbPool: dbPool,
+		matchWriter: NewMatchWriter(db.New(dbPool)),
+		registrationSlots: NewSemaphore(config.MaxConcurrentRegistrations),
+		startTime: time.Now(),
+		WorkerPool: NewWorkerPool(config.WorkerPoolSize, config.WorkerQueueSize),
+		connectionLimiter: NewWindowLimiter(config.ConnectionsPerWindow, config.ConnectionWindow),
+		ReceivedPackets: NewPacketCounter(),
+		SentPackets: NewPacketCounter(),
 	}
+
+	if config.CountTraffic {
+		hub.SentTraffic = NewTrafficCounter()
+	}
+
+	hub.config.Store(config)
+
+	return hub
+}
+
+// Cancelled when the hub shuts down
+func (hub *Hub) Context() context.Context {
+	return hub.ctx
+}
+
+// Stop the hub's run loop and all the background loops running off its context, then let every client know the server
+// is going away and close the database once they've been cleaned up. Gives up waiting when the context is done
+func (hub *Hub) Shutdown(ctx context.Context) error {
+	hub.cancel()
+
+	var clientsClosed sync.WaitGroup
+
+	hub.Clients.ForEach(func(_ uint64, client ClientInterfacer) {
+		clientsClosed.Add(1)
+
+		go func() {
+			defer clientsClosed.Done()
+			client.Close(CloseReasonShutdown)
+		}()
+	})
+
+	done := make(chan struct{})
+
+	go func() {
+		clientsClosed.Wait()
+		<-hub.stopped
+		close(done)
+	}()
+
+	select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+	}
+
+	// Clients record their matches as they're closed, so the database has to stay open until they're all saved
+	hub.matchWriter.Close()
+
+	return hub.dbPool.Close()
+}
+
+// The server's current settings. These can change at runtime, so look them up again rather than holding on to them
+func (hub *Hub) Config() *ServerConfig {
+	return hub.config.Load()
+}
+
+// Change the server's settings at runtime. The update is made to a copy which then replaces the current settings in
+// one go, so nobody sees a half-applied change. Returns the new settings
+func (hub *Hub) UpdateConfig(update func(config *ServerConfig)) *ServerConfig {
+	for {
+		oldConfig := hub.config.Load()
+		newConfig := *oldConfig
+		update(&newConfig)
+
+		if hub.config.CompareAndSwap(oldConfig, &newConfig) {
+			return &newConfig
+		}
+	}
+}
+
+// Milliseconds since the Unix epoch, counted on the monotonic clock from when the server started so it never jumps
+func (hub *Hub) ServerTime() int64 {
+	return hub.startTime.UnixMilli() + time.Since(hub.startTime).Milliseconds()
 }
 
 func (hub *Hub) Run() {
+	defer close(hub.stopped)
+
 	log.Println("Initializing database...")
 
-	if _, err := hub.dbPool.ExecContext(context.Background(), schemaGenSql); err != nil {
+	if err := InitDatabase(context.Background(), hub.dbPool); err != nil {
 		log.Fatalf("Error initializing database: %v", err)
 	}
 
-	log.Println("Placing spores...")
-	for i := 0; i < MaxSpores; i++ {
-		hub.SharedGameObjects.Spores.Add(hub.newSpore())
-	}
-
-	go hub.replenishSporesLoop(2 * time.Second)
+	// The default room is always there, so its world is ready before anyone joins
+	hub.GetOrCreateRoom(DefaultRoomName)
 
 	log.Println("Awaiting client registrations")
 
 	for {
 		select {
+			case <-hub.ctx.Done():
+				// Nobody else closes the waiting clients, since they aren't among the hub's clients yet
+				for _, client := range hub.waiting {
+					go client.Close(CloseReasonShutdown)
+				}
+
+				log.Println("Hub shut down")
+				return
 			case client := <-hub.RegisterChan:
-				client.Initialize(hub.Clients.Add(client))
+				hub.register(client)
 			case client := <-hub.UnregisterChan:
-				hub.Clients.Remove(client.Id())
-			case packet := <-hub.BroadcastChan:
-				hub.Clients.ForEach(func(clientId uint64, client ClientInterfacer) {
-					if clientId != packet.SenderId {
-						client.ProcessMessage(packet.SenderId, packet.Msg)
-					}
-				})
+				hub.unregister(client)
+			case reply := <-hub.clientListChan:
+				reply <- hub.listClients()
 		}
 	}
 }
 
-func (hub *Hub) Serve(getNewClient func (*Hub, http.ResponseWriter, *http.Request) (ClientInterfacer, error), writer http.ResponseWriter, request *http.Request) {
-	log.Println("New client connected from", request.RemoteAddr)
+// Let the client in, or if the server is full, put it at the back of the queue or turn it away. Clients are told
+// their place in the queue, and those turned away are told there's no place for them, after which their connection
+// closes
+func (hub *Hub) register(client ClientInterfacer) {
+	config := hub.Config()
 
-	client, err := getNewClient(hub, writer, request)
+	// Nobody jumps the queue, even if a place frees up before the queue is drained
+	if config.MaxClients <= 0 || (hub.Clients.Len() < config.MaxClients && len(hub.waiting) == 0) {
+		hub.admit(client)
+		return
+	}
 
-	if err != nil {
-		log.Printf("Error obtaining client for new connection: %v", err)
+	if len(hub.waiting) < config.ClientQueueSize {
+		hub.waiting = append(hub.waiting, client)
+		client.SocketSend(packets.NewServerFull(len(hub.waiting)))
 		return
 	}
 
-	hub.RegisterChan <- client
+	log.Printf("Server is full, turning away a client from %s", client.Ip())
+	client.SocketSend(packets.NewServerFull(0))
+	hub.LeaveRoom(client.Room())
+}
 
-	go client.WritePump()
-	go client.ReadPump()
+func (hub *Hub) admit(client ClientInterfacer) {
+	clientId := hub.Clients.Add(client, hub.nextConnectionId)
+	hub.nextConnectionId++
+	client.Room().Clients.Add(client, clientId)
+	client.Initialize(clientId)
 }
 
-func (hub *Hub) newSpore() *objects.Spore {
-	sporeRadius := max(10 + rand.NormFloat64() * 3, 5)
-	x, y := objects.SpawnCoords(sporeRadius, hub.SharedGameObjects.Players, hub.SharedGameObjects.Spores)
+// Forget a client which has closed, letting in whoever is next in the queue. Clients which were turned away have
+// already been forgotten
+func (hub *Hub) unregister(client ClientInterfacer) {
+	// Clients can drop out while they're still waiting
+	if index := slices.Index(hub.waiting, client); index >= 0 {
+		hub.waiting = slices.Delete(hub.waiting, index, index + 1)
+		hub.LeaveRoom(client.Room())
+		hub.sendQueuePositions(index)
+		return
+	}
+
+	// Make sure a client unregistered twice doesn't leave its room twice
+	if registered, exists := hub.Clients.Get(client.Id()); !exists || registered != client {
+		return
+	}
+
+	hub.Clients.Remove(client.Id())
+	client.Room().Clients.Remove(client.Id())
+	hub.LeaveRoom(client.Room())
+
+	admitted := 0
+
+	for maxClients := hub.Config().MaxClients; len(hub.waiting) > admitted && (maxClients <= 0 || hub.Clients.Len() < maxClients); admitted++ {
+		hub.admit(hub.waiting[admitted])
+	}
+
+	if admitted > 0 {
+		hub.waiting = slices.Delete(hub.waiting, 0, admitted)
+		hub.sendQueuePositions(0)
+	}
+}
+
+// Tell the waiting clients from the given place in the queue onwards where they are now
+func (hub *Hub) sendQueuePositions(from int) {
+	for i := from; i < len(hub.waiting); i++ {
+		hub.waiting[i].SocketSend(packets.NewServerFull(i + 1))
+	}
+}
+
+// Get the room with the given name, creating it if it doesn't exist yet. The caller counts as a member of the room
+// until they call LeaveRoom, so it isn't torn down from under them. Returns nil if the room would need creating but
+// there are already as many rooms as allowed
+func (hub *Hub) GetOrCreateRoom(name string) *Room {
+	hub.roomsMux.Lock()
+	defer hub.roomsMux.Unlock()
+
+	room, exists := hub.rooms[name]
+
+	if !exists {
+		if maxRooms := hub.Config().MaxRooms; maxRooms > 0 && len(hub.rooms) >= maxRooms {
+			return nil
+		}
+
+		log.Printf("Creating room %s", name)
+		room = newRoom(hub, name)
+		hub.rooms[name] = room
+		go room.Run()
+	}
+
+	room.members++
+
+	if room.idleTimer != nil {
+		room.idleTimer.Stop()
+		room.idleTimer = nil
+	}
+
+	return room
+}
+
+// Stop counting a member of the room. Once nobody is left, the room is torn down after a grace period, unless
+// somebody joins again in the meantime
+func (hub *Hub) LeaveRoom(room *Room) {
+	hub.roomsMux.Lock()
+	defer hub.roomsMux.Unlock()
+
+	room.members--
+
+	if room.members > 0 || room.Name == DefaultRoomName {
+		return
+	}
+
+	room.idleTimer = time.AfterFunc(hub.Config().RoomIdleTimeout, func() {
+		hub.roomsMux.Lock()
+		defer hub.roomsMux.Unlock()
+
+		if room.members > 0 || hub.rooms[room.Name] != room {
+			return
+		}
+
+		log.Printf("Tearing down room %s, which has been empty for %v", room.Name, hub.Config().RoomIdleTimeout)
+		delete(hub.rooms, room.Name)
+		room.cancel()
+	})
+}
+
+// Close the client's connection, telling them why. A player kicked out of the game can't resume their session, though
+// they can log in again unless they're banned. Returns false if there's no such client
+func (hub *Hub) Kick(id uint64, reason string) bool {
+	client, exists := hub.Clients.Get(id)
+
+	if !exists {
+		return false
+	}
+
+	if reason != "" {
+		reason = CloseReasonKicked + ": " + reason
+	} else {
+		reason = CloseReasonKicked
+	}
+
+	// The player is only held for resuming once the client has closed, so it has to be looked up first
+	player, playing := client.Room().SharedGameObjects.Players.Get(id)
+	client.Close(reason)
+
+	if playing {
+		client.Sessions().Revoke(player)
+	}
+
+	slog.Info("Admin kicked client", "client_id", id, "reason", reason)
 
-	return &objects.Spore{X: x, Y: y, Radius: sporeRadius}
+	return true
 }
 
-func (hub *Hub) replenishSporesLoop(rate time.Duration) {
-	ticker := time.NewTicker(rate)
-	defer ticker.Stop()
+// Kick every client playing as the given user, returning how many were kicked
+func (hub *Hub) KickUser(username string, reason string) int {
+	kicked := 0
 
-	for range ticker.C {
-		sporesRemaining := hub.SharedGameObjects.Spores.Len()
-		diff := MaxSpores - sporesRemaining
+	hub.Clients.ForEach(func(id uint64, client ClientInterfacer) {
+		player, playing := client.Room().SharedGameObjects.Players.Get(id)
 
-		if diff <= 0 {
-			continue
+		if playing && strings.EqualFold(player.Name, username) && hub.Kick(id, reason) {
+			kicked++
 		}
+	})
+
+	return kicked
+}
+
+func (hub *Hub) Serve(getNewClient func (*Hub, *Room, http.ResponseWriter, *http.Request) (ClientInterfacer, error), writer http.ResponseWriter, request *http.Request) {
+	log.Println("New client connected from", request.RemoteAddr)
+
+	if ip := RequestIp(request, hub.Config().TrustForwardedFor); !hub.connectionLimiter.Allow(ip) {
+		log.Printf("Too many connections from %s, refusing", ip)
+		http.Error(writer, "Too many connections", http.StatusTooManyRequests)
+		return
+	}
+
+	roomName := request.URL.Query().Get("room")
+
+	if roomName == "" {
+		roomName = DefaultRoomName
+	}
+
+	if len(roomName) > MaxRoomNameLength {
+		http.Error(writer, "Room name too long", http.StatusBadRequest)
+		return
+	}
+
+	room := hub.GetOrCreateRoom(roomName)
+
+	if room == nil {
+		log.Printf("Too many rooms to create room %s, refusing", roomName)
+		http.Error(writer, "Too many rooms", http.StatusServiceUnavailable)
+		return
+	}
 
-		log.Printf("%d spores remain - going to replenish %d spores", sporesRemaining, diff)
+	client, err := getNewClient(hub, room, writer, request)
 
-		// Don't really want to spawn too many at a time, otherwise it can cause lag spikes
-		for i := 0; i < min(diff, 10); i++ {
-			spore := hub.newSpore()
-			sporeId := hub.SharedGameObjects.Spores.Add(spore)
+	if err != nil {
+		log.Printf("Error obtaining client for new connection: %v", err)
+		hub.LeaveRoom(room)
+		return
+	}
 
-			hub.BroadcastChan <- &packets.Packet{
-				SenderId: 0,
-				Msg: packets.NewSpore(sporeId, spore),
-			}
+	select {
+		case hub.RegisterChan <- client:
+		case <-hub.ctx.Done():
+			client.Close(CloseReasonShutdown)
+			return
+	}
 
-			// Sleep a little bit to avoid lag spikes
-			time.Sleep(50 * time.Millisecond)
+	go client.WritePump()
+	go client.ReadPump()
+}
+
+// Get the IP address a request came from. If the server is behind a trusted proxy, this is the first address in the
+// X-Forwarded-For header, since the request's remote address would just be the proxy's
+func RequestIp(request *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if forwardedFor := request.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+			ip, _, _ := strings.Cut(forwardedFor, ",")
+			return strings.TrimSpace(ip)
 		}
 	}
+
+	ip, _, err := net.SplitHostPort(request.RemoteAddr)
+
+	if err != nil {
+		return request.RemoteAddr
+	}
+
+	return ip
 }
\ No newline at end of file
@@ -0,0 +1,266 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"server/internal/server"
+	"server/internal/server/objects"
+	"server/internal/server/servertest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Kicking a player must close their client with the reason given and keep them from resuming their session, and
+// kicking a client which isn't there must do nothing
+func TestKick(t *testing.T) {
+	config := server.NewServerConfig()
+	hub := &server.Hub{Clients: objects.NewSharedCollection[server.ClientInterfacer]()}
+	client := servertest.NewFakeClient(1, config)
+	hub.Clients.Add(client, client.Id())
+
+	player := &objects.Player{Name: "test", Radius: 20}
+	client.SharedGameObjects().Players.Add(player, client.Id())
+
+	// Stands in for the session the client's state would hold on to as it's closed
+	token := client.Sessions().Issue(1)
+	client.Sessions().Hold(token, &server.HeldSession{UserId: 1, RoomName: client.Room().Name, Player: player}, time.Minute)
+
+	if hub.Kick(client.Id() + 1, "spamming") {
+		t.Error("Kicking a client which isn't connected claimed to kick it")
+	}
+
+	if reason := client.CloseReason(); reason != "" {
+		t.Errorf("Kicking another client closed this one because: %s", reason)
+	}
+
+	if !hub.Kick(client.Id(), "spamming") {
+		t.Error("Kicking a connected client claimed there was no such client")
+	}
+
+	if reason := client.CloseReason(); !strings.HasPrefix(reason, server.CloseReasonKicked) || !strings.Contains(reason, "spamming") {
+		t.Errorf("Kicking a client closed it because %q instead of the reason given", reason)
+	}
+
+	if _, err := client.Sessions().Resume(token, client.Room().Name); err == nil {
+		t.Error("A kicked player resumed their session")
+	}
+}
+
+// A client logging in must take its account's user ID, keeping its place in the room, and no other client may take the
+// same ID while it has it
+func TestClaimId(t *testing.T) {
+	const userId uint64 = 7
+
+	client := servertest.NewFakeClient(server.FirstConnectionId, server.NewServerConfig())
+	peer := client.NewPeer(server.FirstConnectionId + 1)
+
+	if !client.ClaimId(userId) || client.Id() != userId {
+		t.Errorf("Claiming user ID %d left the client with ID %d", userId, client.Id())
+	}
+
+	if found, exists := client.Room().Clients.Get(userId); !exists || found != client {
+		t.Errorf("The client isn't in its room under user ID %d", userId)
+	}
+
+	if _, exists := client.Room().Clients.Get(server.FirstConnectionId); exists {
+		t.Error("The client is still in its room under its connection ID")
+	}
+
+	if !client.ClaimId(userId) {
+		t.Error("Claiming the user ID the client already has failed")
+	}
+
+	if peer.ClaimId(userId) || peer.Id() != server.FirstConnectionId + 1 {
+		t.Errorf("Another client took user ID %d while it was in use, and has ID %d", userId, peer.Id())
+	}
+}
+
+// Once the server is full, clients must wait in line up to the queue size and be let in in turn, and any more must be
+// told there's no place for them. Clients dropping out of the line mustn't be let in or hold up those behind them
+func TestServerFull(t *testing.T) {
+	config := server.NewServerConfig()
+	config.MaxClients = 1
+	config.ClientQueueSize = 2
+
+	hub := server.NewHub(config)
+
+	playing := servertest.NewFakeClient(0, config)
+	dropped := playing.NewPeer(0)
+	waiting := playing.NewPeer(0)
+	turnedAway := playing.NewPeer(0)
+
+	for _, client := range []*servertest.FakeClient{playing, dropped, waiting, turnedAway} {
+		hub.Register(client)
+	}
+
+	if position, told := lastQueuePosition(playing); told {
+		t.Errorf("The first client was put in the queue at %d with room to spare", position)
+	}
+
+	if position, _ := lastQueuePosition(waiting); position != 2 {
+		t.Errorf("The third client was put at %d in the queue instead of 2", position)
+	}
+
+	if position, told := lastQueuePosition(turnedAway); !told || position != 0 {
+		t.Errorf("The client registering past the queue wasn't told the server is full, but %d", position)
+	}
+
+	if hub.Clients.Len() != 1 || hub.Waiting() != 2 {
+		t.Errorf("%d clients were let in and %d queued instead of 1 and 2", hub.Clients.Len(), hub.Waiting())
+	}
+
+	hub.Unregister(dropped)
+
+	if position, _ := lastQueuePosition(waiting); position != 1 {
+		t.Errorf("The client behind one which dropped out is at %d in the queue instead of 1", position)
+	}
+
+	hub.Unregister(playing)
+
+	if found, exists := hub.Clients.Get(waiting.Id()); !exists || found != waiting || hub.Waiting() != 0 {
+		t.Error("The waiting client wasn't let in when a place freed up")
+	}
+
+	if _, exists := hub.Clients.Get(dropped.Id()); exists {
+		t.Error("A client which dropped out of the queue was let in")
+	}
+}
+
+// The queue position the client was last told about, and whether it was told about one at all
+func lastQueuePosition(client *servertest.FakeClient) (uint32, bool) {
+	sent := client.Sent()
+
+	for i := len(sent) - 1; i >= 0; i-- {
+		if serverFull := sent[i].GetServerFull(); serverFull != nil {
+			return serverFull.QueuePosition, true
+		}
+	}
+
+	return 0, false
+}
+
+// Shutting down must stop the run loop, close every client with the shutdown reason, including those waiting in line,
+// and close the database, all without hanging
+func TestShutdown(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	config := server.NewServerConfig()
+	config.MaxSpores = 0
+	config.MaxClients = 1
+	config.ClientQueueSize = 1
+
+	hub := server.NewHub(config)
+	go hub.Run()
+
+	playing := servertest.NewFakeClient(0, config)
+	waiting := playing.NewPeer(0)
+
+	hub.RegisterChan <- playing
+	hub.RegisterChan <- waiting
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5 * time.Second)
+	defer cancel()
+
+	if err := hub.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutting down didn't finish: %v", err)
+	}
+
+	if reason := playing.CloseReason(); reason != server.CloseReasonShutdown {
+		t.Errorf("Shutting down closed a connected client because %q instead of %q", reason, server.CloseReasonShutdown)
+	}
+
+	// Clients waiting in line are closed in the background, since the hub isn't waiting on them
+	for deadline := time.Now().Add(5 * time.Second); waiting.CloseReason() == "" && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+
+	if reason := waiting.CloseReason(); reason != server.CloseReasonShutdown {
+		t.Errorf("Shutting down closed a waiting client because %q instead of %q", reason, server.CloseReasonShutdown)
+	}
+
+	select {
+		case <-hub.Context().Done():
+		default:
+			t.Error("The hub's context is still live after shutting down")
+	}
+}
+
+// The clients an admin lists, as decoded from the response
+func listClients(t *testing.T, hub *server.Hub) []map[string]any {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	hub.HandleClients(recorder, httptest.NewRequest(http.MethodGet, "/admin/clients", nil))
+
+	var clients []map[string]any
+
+	if err := json.Unmarshal(recorder.Body.Bytes(), &clients); err != nil {
+		t.Fatalf("Listing clients gave %q, which isn't a JSON list: %v", recorder.Body.String(), err)
+	}
+
+	return clients
+}
+
+// Listing the clients while they come and go and players join must not race, and once they've settled must show each
+// client in order with the name they're playing as
+func TestHandleClients(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	config := server.NewServerConfig()
+	config.MaxSpores = 0
+
+	hub := server.NewHub(config)
+	go hub.Run()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+		defer cancel()
+		hub.Shutdown(ctx)
+	})
+
+	first := servertest.NewFakeClient(0, config)
+	var churning sync.WaitGroup
+
+	// Clients are given IDs in the order they're let in, so the players can be added without waiting on the run loop
+	churning.Go(func() {
+		for i := range uint64(100) {
+			client := first.NewPeer(0)
+			hub.RegisterChan <- client
+			client.SharedGameObjects().Players.Add(&objects.Player{Name: "churn"}, server.FirstConnectionId + i)
+			hub.UnregisterChan <- client
+			client.SharedGameObjects().Players.Remove(server.FirstConnectionId + i)
+		}
+	})
+
+	for range 100 {
+		listClients(t, hub)
+	}
+
+	churning.Wait()
+
+	hub.RegisterChan <- first
+	watching := first.NewPeer(0)
+	hub.RegisterChan <- watching
+
+	// Listing goes through the run loop, so both clients have been let in by the time it's answered
+	listClients(t, hub)
+	first.SharedGameObjects().Players.Add(&objects.Player{Name: "player"}, first.Id())
+
+	clients := listClients(t, hub)
+
+	if len(clients) != 2 {
+		t.Fatalf("Listing clients gave %v instead of the 2 connected", clients)
+	}
+
+	if clients[0]["id"] != float64(first.Id()) || clients[0]["player"] != "player" || clients[0]["room"] != server.DefaultRoomName {
+		t.Errorf("The playing client was listed as %v", clients[0])
+	}
+
+	if _, playing := clients[1]["player"]; clients[1]["id"] != float64(watching.Id()) || playing {
+		t.Errorf("The client which isn't playing was listed as %v", clients[1])
+	}
+}
\ No newline at end of file
@@ -0,0 +1,42 @@
+package server
+
+import "sync"
+
+// Counts how many of something each key currently holds, up to a limit per key
+type KeyCounter struct {
+	counts map[string]int
+	mux    sync.Mutex
+}
+
+func NewKeyCounter() *KeyCounter {
+	return &KeyCounter{
+		counts: make(map[string]int),
+	}
+}
+
+// Count one more for the key, unless it already holds the limit. A limit of 0 allows everything.
+// Returns whether it was counted, in which case it must be released later
+func (counter *KeyCounter) Acquire(key string, limit int) bool {
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	if limit > 0 && counter.counts[key] >= limit {
+		return false
+	}
+
+	counter.counts[key]++
+
+	return true
+}
+
+func (counter *KeyCounter) Release(key string) {
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	counter.counts[key]--
+
+	// Don't let keys which are no longer in use linger
+	if counter.counts[key] <= 0 {
+		delete(counter.counts, key)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"server/internal/server/db"
+	"sync"
+)
+
+// How many finished matches can wait to be saved before more are dropped
+const matchQueueSize int = 1024
+
+// Saves finished matches to the database on a goroutine of its own, so ending a player's life never waits on the
+// database, e.g. when it happens on the room's goroutine as they're consumed
+type MatchWriter struct {
+	queries *db.Queries
+	matches chan db.CreateMatchParams
+
+	// Held to write, so the queue isn't closed under a writer
+	mux sync.RWMutex
+	closed bool
+
+	// Closed once every queued match has been saved after closing
+	done chan struct{}
+}
+
+func NewMatchWriter(queries *db.Queries) *MatchWriter {
+	writer := &MatchWriter{
+		queries: queries,
+		matches: make(chan db.CreateMatchParams, matchQueueSize),
+		done: make(chan struct{}),
+	}
+
+	go writer.run()
+
+	return writer
+}
+
+// Queue the match to be saved, without waiting for it. Matches are dropped if the queue is full or the writer has
+// been closed. Returns whether the match was queued
+func (writer *MatchWriter) Write(match db.CreateMatchParams) bool {
+	writer.mux.RLock()
+	defer writer.mux.RUnlock()
+
+	if writer.closed {
+		slog.Warn("Match writer closed, dropping match", "user_id", match.UserID)
+		return false
+	}
+
+	select {
+		case writer.matches <- match:
+			return true
+		default:
+			slog.Warn("Match queue full, dropping match", "user_id", match.UserID)
+			return false
+	}
+}
+
+func (writer *MatchWriter) run() {
+	defer close(writer.done)
+
+	for match := range writer.matches {
+		if _, err := writer.queries.CreateMatch(context.Background(), match); err != nil {
+			slog.Error("Error recording match", "user_id", match.UserID, "err", err)
+		}
+	}
+}
+
+// Stop taking matches and wait for the ones already queued to be saved. Only the first call does anything
+func (writer *MatchWriter) Close() {
+	writer.mux.Lock()
+
+	if !writer.closed {
+		writer.closed = true
+		close(writer.matches)
+	}
+
+	writer.mux.Unlock()
+	<-writer.done
+}
\ No newline at end of file
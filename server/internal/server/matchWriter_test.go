@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"server/internal/server/db"
+	"testing"
+)
+
+// Matches written must all be saved by the time the writer is closed, and any written afterwards dropped
+func TestMatchWriter(t *testing.T) {
+	const matchCount int = 50
+
+	dbPool, err := sql.Open("sqlite", ":memory:")
+
+	if err != nil {
+		t.Fatalf("Couldn't open a database: %v", err)
+	}
+
+	defer dbPool.Close()
+
+	// Every connection to an in-memory database gets a database of its own
+	dbPool.SetMaxOpenConns(1)
+
+	if err := InitDatabase(context.Background(), dbPool); err != nil {
+		t.Fatalf("Couldn't initialize the database: %v", err)
+	}
+
+	queries := db.New(dbPool)
+	writer := NewMatchWriter(queries)
+
+	for i := range matchCount {
+		if !writer.Write(db.CreateMatchParams{UserID: 1, PeakMass: float64(i), DurationMs: int64(i), EndReason: "left"}) {
+			t.Fatalf("Match %d wasn't queued", i)
+		}
+	}
+
+	writer.Close()
+
+	if writer.Write(db.CreateMatchParams{UserID: 1, EndReason: "left"}) {
+		t.Error("A match was queued after the writer was closed")
+	}
+
+	matches, err := queries.GetRecentMatches(context.Background(), db.GetRecentMatchesParams{UserID: 1, Limit: int64(2 * matchCount)})
+
+	if err != nil {
+		t.Fatalf("Couldn't read the matches back: %v", err)
+	}
+
+	if len(matches) != matchCount {
+		t.Fatalf("Saved %d matches instead of %d", len(matches), matchCount)
+	}
+
+	// The most recent come first
+	for i, match := range matches {
+		if expected := matchCount - 1 - i; match.PeakMass != float64(expected) || match.DurationMs != int64(expected) {
+			t.Errorf("Match %d came back as %+v", expected, match)
+		}
+	}
+}
+
+// With the database tied up, writing matches must carry on without waiting, dropping them once the queue is full
+func TestMatchWriterNeverBlocks(t *testing.T) {
+	dbPool, err := sql.Open("sqlite", ":memory:")
+
+	if err != nil {
+		t.Fatalf("Couldn't open a database: %v", err)
+	}
+
+	defer dbPool.Close()
+	dbPool.SetMaxOpenConns(1)
+
+	if err := InitDatabase(context.Background(), dbPool); err != nil {
+		t.Fatalf("Couldn't initialize the database: %v", err)
+	}
+
+	// Holds the only connection, so the writer can't save anything until it's done
+	transaction, err := dbPool.Begin()
+
+	if err != nil {
+		t.Fatalf("Couldn't start a transaction: %v", err)
+	}
+
+	writer := NewMatchWriter(db.New(dbPool))
+	queued := 0
+
+	for range 2 * matchQueueSize {
+		if writer.Write(db.CreateMatchParams{UserID: 1, EndReason: "left"}) {
+			queued++
+		}
+	}
+
+	// The writer may have taken one match off the queue to wait on the database with
+	if queued < matchQueueSize || queued > matchQueueSize + 1 {
+		t.Errorf("Queued %d matches with the database tied up instead of the queue size %d", queued, matchQueueSize)
+	}
+
+	transaction.Rollback()
+	writer.Close()
+}
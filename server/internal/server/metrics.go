@@ -0,0 +1,112 @@
+package server
+
+import (
+	"server/pkg/packets"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Counts packets by message type. Every type is known up front, so counting takes no locks
+type PacketCounter struct {
+	counts map[string]*atomic.Uint64
+}
+
+func NewPacketCounter() *PacketCounter {
+	counter := &PacketCounter{
+		counts: make(map[string]*atomic.Uint64),
+	}
+
+	for _, name := range packets.MsgNames() {
+		counter.counts[name] = &atomic.Uint64{}
+	}
+
+	return counter
+}
+
+func (counter *PacketCounter) Add(packet *packets.Packet) {
+	if count, found := counter.counts[packets.MsgName(packet)]; found {
+		count.Add(1)
+	}
+}
+
+// The counts so far for each message type
+func (counter *PacketCounter) Counts() map[string]uint64 {
+	counts := make(map[string]uint64, len(counter.counts))
+
+	for name, count := range counter.counts {
+		counts[name] = count.Load()
+	}
+
+	return counts
+}
+
+// Reports the hub's metrics to Prometheus. Everything is read as it's scraped rather than kept up to date as it
+// happens, so the game loops and pumps pay for nothing beyond the atomic counters they already bump
+type metricsCollector struct {
+	hub *Hub
+	uptime *prometheus.Desc
+	clients *prometheus.Desc
+	rooms *prometheus.Desc
+	players *prometheus.Desc
+	spores *prometheus.Desc
+	packetsReceived *prometheus.Desc
+	packetsSent *prometheus.Desc
+	broadcasts *prometheus.Desc
+	unknownPackets *prometheus.Desc
+	marshalErrors *prometheus.Desc
+	skippedSends *prometheus.Desc
+	spawnsThrottled *prometheus.Desc
+}
+
+// A collector of the hub's metrics, for registering with a Prometheus registry
+func (hub *Hub) MetricsCollector() prometheus.Collector {
+	return &metricsCollector{
+		hub: hub,
+		uptime: prometheus.NewDesc("radius_rumble_uptime_seconds", "Time since the server started", nil, nil),
+		clients: prometheus.NewDesc("radius_rumble_clients", "Connected clients, whether playing or not", nil, nil),
+		rooms: prometheus.NewDesc("radius_rumble_rooms", "Rooms being played in", nil, nil),
+		players: prometheus.NewDesc("radius_rumble_players", "Players in the game in each room", []string{"room"}, nil),
+		spores: prometheus.NewDesc("radius_rumble_spores", "Spores in the world in each room", []string{"room"}, nil),
+		packetsReceived: prometheus.NewDesc("radius_rumble_packets_received_total", "Packets received from clients by message type", []string{"type"}, nil),
+		packetsSent: prometheus.NewDesc("radius_rumble_packets_sent_total", "Packets written to clients by message type", []string{"type"}, nil),
+		broadcasts: prometheus.NewDesc("radius_rumble_broadcasts_total", "Packets broadcast by clients to the others in their room", nil, nil),
+		unknownPackets: prometheus.NewDesc("radius_rumble_unknown_packets_total", "Packets received with a message type this server doesn't recognise", nil, nil),
+		marshalErrors: prometheus.NewDesc("radius_rumble_marshal_errors_total", "Outgoing packets dropped because they couldn't be marshalled", nil, nil),
+		skippedSends: prometheus.NewDesc("radius_rumble_skipped_sends_total", "Packets not sent because their client had already closed", nil, nil),
+		spawnsThrottled: prometheus.NewDesc("radius_rumble_spawns_throttled_total", "Spore spawns skipped to stay within the world object budget", nil, nil),
+	}
+}
+
+func (collector *metricsCollector) Describe(descs chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(collector, descs)
+}
+
+func (collector *metricsCollector) Collect(metrics chan<- prometheus.Metric) {
+	hub := collector.hub
+
+	metrics <- prometheus.MustNewConstMetric(collector.uptime, prometheus.GaugeValue, hub.Uptime().Seconds())
+	metrics <- prometheus.MustNewConstMetric(collector.clients, prometheus.GaugeValue, float64(hub.Clients.Len()))
+
+	rooms := hub.Rooms()
+	metrics <- prometheus.MustNewConstMetric(collector.rooms, prometheus.GaugeValue, float64(len(rooms)))
+
+	for _, room := range rooms {
+		metrics <- prometheus.MustNewConstMetric(collector.players, prometheus.GaugeValue, float64(room.SharedGameObjects.Players.Len()), room.Name)
+		metrics <- prometheus.MustNewConstMetric(collector.spores, prometheus.GaugeValue, float64(room.SharedGameObjects.Spores.Len()), room.Name)
+	}
+
+	for packetType, count := range hub.ReceivedPackets.Counts() {
+		metrics <- prometheus.MustNewConstMetric(collector.packetsReceived, prometheus.CounterValue, float64(count), packetType)
+	}
+
+	for packetType, count := range hub.SentPackets.Counts() {
+		metrics <- prometheus.MustNewConstMetric(collector.packetsSent, prometheus.CounterValue, float64(count), packetType)
+	}
+
+	metrics <- prometheus.MustNewConstMetric(collector.broadcasts, prometheus.CounterValue, float64(hub.Broadcasts.Load()))
+	metrics <- prometheus.MustNewConstMetric(collector.unknownPackets, prometheus.CounterValue, float64(hub.UnknownPackets.Load()))
+	metrics <- prometheus.MustNewConstMetric(collector.marshalErrors, prometheus.CounterValue, float64(hub.MarshalErrors.Load()))
+	metrics <- prometheus.MustNewConstMetric(collector.skippedSends, prometheus.CounterValue, float64(hub.SkippedSends.Load()))
+	metrics <- prometheus.MustNewConstMetric(collector.spawnsThrottled, prometheus.CounterValue, float64(hub.SpawnsThrottled.Load()))
+}
\ No newline at end of file
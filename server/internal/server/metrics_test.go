@@ -0,0 +1,84 @@
+package server_test
+
+import (
+	"context"
+	"fmt"
+	"server/internal/server"
+	"server/internal/server/objects"
+	"server/internal/server/servertest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// The player gauge scraped from the registry must follow the clients registered with the hub as they join the game
+// and leave, as must the count of connected clients
+func TestMetricsTrackRegisteredClients(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	config := server.NewServerConfig()
+	config.MaxSpores = 0
+
+	hub := server.NewHub(config)
+	go hub.Run()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+		defer cancel()
+		hub.Shutdown(ctx)
+	})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(hub.MetricsCollector())
+
+	expectGauges := func(clients int, players int) {
+		t.Helper()
+
+		// Listing goes through the run loop, so every client sent to it has been let in or out by the time it's answered
+		listClients(t, hub)
+
+		expected := fmt.Sprintf(`
+			# HELP radius_rumble_clients Connected clients, whether playing or not
+			# TYPE radius_rumble_clients gauge
+			radius_rumble_clients %d
+			# HELP radius_rumble_players Players in the game in each room
+			# TYPE radius_rumble_players gauge
+			radius_rumble_players{room=%q} %d
+		`, clients, server.DefaultRoomName, players)
+
+		if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "radius_rumble_clients", "radius_rumble_players"); err != nil {
+			t.Errorf("With %d clients and %d players, the metrics scraped didn't match: %v", clients, players, err)
+		}
+	}
+
+	first := servertest.NewFakeClient(0, config)
+	registered := []*servertest.FakeClient{first, first.NewPeer(0), first.NewPeer(0)}
+
+	for _, client := range registered {
+		hub.RegisterChan <- client
+	}
+
+	listClients(t, hub)
+	rooms := hub.Rooms()
+
+	if len(rooms) != 1 {
+		t.Fatalf("The hub started with %d rooms instead of just the default one", len(rooms))
+	}
+
+	// Stands in for each client entering the game, which the fake clients don't do on their own
+	players := rooms[0].SharedGameObjects.Players
+
+	for _, client := range registered {
+		players.Add(&objects.Player{Name: "player"}, client.Id())
+	}
+
+	expectGauges(3, 3)
+
+	hub.UnregisterChan <- registered[0]
+	players.Remove(registered[0].Id())
+
+	expectGauges(2, 2)
+}
\ No newline at end of file
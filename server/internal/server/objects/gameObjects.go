@@ -1,5 +1,73 @@
 package objects
 
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Kinds of spore, worth different amounts of mass for their size. The zero value is the plain spore every spore used to
+// be
+type SporeType uint32
+
+const (
+	SporeTypePlain SporeType = iota
+	SporeTypeRich
+	SporeTypeGolden
+
+	// How many types there are, for sizing anything with a value per type
+	SporeTypeCount
+)
+
+// How many times the mass of a plain spore of the same size a spore of this type is worth
+func (sporeType SporeType) MassMultiplier() float64 {
+	switch sporeType {
+		case SporeTypeRich:
+			return 2
+		case SporeTypeGolden:
+			return 5
+		default:
+			return 1
+	}
+}
+
+// Pick a spore type at random, each type as likely as its share of the total weight. The weights are indexed by type,
+// and plain spores are picked if no type has any weight
+func RandomSporeType(weights []float64, rng ...*rand.Rand) SporeType {
+	total := 0.0
+
+	for _, weight := range weights {
+		total += max(weight, 0)
+	}
+
+	if total <= 0 {
+		return SporeTypePlain
+	}
+
+	pick := pickRand(rng).Float64() * total
+
+	for sporeType, weight := range weights {
+		if weight <= 0 {
+			continue
+		}
+
+		if pick < weight {
+			return SporeType(sporeType)
+		}
+
+		pick -= weight
+	}
+
+	// Rounding can leave a sliver past the last weight, which belongs to the last type with any
+	for sporeType := len(weights) - 1; sporeType > 0; sporeType-- {
+		if weights[sporeType] > 0 {
+			return SporeType(sporeType)
+		}
+	}
+
+	return SporeTypePlain
+}
+
 type Player struct {
 	Name      string
 	X         float64
@@ -7,10 +75,54 @@ type Player struct {
 	Radius    float64
 	Direction float64
 	Speed     float64
+
+	// Unix time in milliseconds of the tick the player's position was last computed on
+	TickTime int64
+
+	// Stats for the current life, which start over on respawn
+	SporesEaten      uint64
+	PlayersEaten     uint64
+	DistanceTraveled float64
 }
 
 type Spore struct {
+	X         float64
+	Y         float64
+	Radius    float64
+	Type      SporeType
+	SpawnedAt time.Time
+}
+
+// The mass the spore is worth to whoever consumes it
+func (spore *Spore) Mass() float64 {
+	return RadiusToMass(spore.Radius) * spore.Type.MassMultiplier()
+}
+
+// Pops when a big enough player runs into it, taking a share of their mass. Smaller players pass over it unharmed
+type Hazard struct {
 	X      float64
 	Y      float64
 	Radius float64
+}
+
+func (player *Player) Circle() (float64, float64, float64) {
+	return player.X, player.Y, player.Radius
+}
+
+func (spore *Spore) Circle() (float64, float64, float64) {
+	return spore.X, spore.Y, spore.Radius
+}
+
+func (hazard *Hazard) Circle() (float64, float64, float64) {
+	return hazard.X, hazard.Y, hazard.Radius
+}
+
+// The mass of a player or spore with the given radius
+func RadiusToMass(radius float64) float64 {
+	return math.Pi * radius * radius
+}
+
+// The radius of a player or spore with the given mass
+func MassToRadius(mass float64) float64 {
+	return math.Sqrt(mass / math.Pi)
 }
\ No newline at end of file
@@ -0,0 +1,43 @@
+package objects
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// Picking spore types many times must give each type about its share of the weight, and never a type with no weight
+func TestSporeTypeWeights(t *testing.T) {
+	const samples int = 100000
+	const tolerance float64 = 0.01
+
+	weights := []float64{70, 0, 25, 5}
+	totalWeight := 0.0
+
+	for _, weight := range weights {
+		totalWeight += weight
+	}
+
+	counts := make([]int, len(weights))
+	rng := rand.New(rand.NewPCG(3, 4))
+
+	for range samples {
+		counts[RandomSporeType(weights, rng)]++
+	}
+
+	for sporeType, weight := range weights {
+		share := float64(counts[sporeType]) / float64(samples)
+
+		if weight == 0 && counts[sporeType] > 0 {
+			t.Errorf("Spore type %d has no weight but was picked %d times", sporeType, counts[sporeType])
+		}
+
+		if expected := weight / totalWeight; math.Abs(share - expected) > tolerance {
+			t.Errorf("Spore type %d was picked %f of the time instead of about %f", sporeType, share, expected)
+		}
+	}
+
+	if sporeType := RandomSporeType([]float64{0, 0, 0}); sporeType != SporeTypePlain {
+		t.Errorf("Picking from no weights gave spore type %d instead of plain", sporeType)
+	}
+}
\ No newline at end of file
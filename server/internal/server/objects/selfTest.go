@@ -0,0 +1,81 @@
+package objects
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+// Finds a free spot in the world, the way SpawnCoords does
+type spawnFunc func(float64, float64, *SharedCollection[*Player], *SharedCollection[*Spore], *SharedCollection[*Hazard], ...*rand.Rand) (float64, float64, bool)
+
+// Sanity checks for the game's math, for catching regressions before the server starts. Returns every failure found
+func SelfTest() error {
+	return errors.Join(checkMassConversions(RadiusToMass, MassToRadius), checkSpawnCoords(SpawnCoords))
+}
+
+func checkMassConversions(radiusToMass func(float64) float64, massToRadius func(float64) float64) error {
+	const tolerance float64 = 1e-9
+
+	var errs []error
+
+	for _, radius := range []float64{1, 10, 20, 123.456, 5000} {
+		roundTrip := massToRadius(radiusToMass(radius))
+
+		if math.Abs(roundTrip - radius) > tolerance * radius {
+			errs = append(errs, fmt.Errorf("Radius %f became %f converting to mass and back", radius, roundTrip))
+		}
+
+		// Gaining mass must always grow the radius, and the radii must add up the same way the masses do
+		grown := massToRadius(radiusToMass(radius) + radiusToMass(radius))
+
+		if grown <= radius || math.Abs(grown - radius * math.Sqrt2) > tolerance * radius {
+			errs = append(errs, fmt.Errorf("Doubling the mass of radius %f gave radius %f", radius, grown))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Spawning in a seeded world must keep every object inside the world and clear of the others
+func checkSpawnCoords(spawnCoords spawnFunc) error {
+	const bound float64 = DefaultWorldBound
+	const sporeCount int = 200
+	const spawnCount int = 100
+	const hazardCount int = 20
+	const radius float64 = 20
+
+	var errs []error
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	spores := NewSpatialCollection[*Spore](100)
+	players := NewSpatialCollection[*Player](100)
+	hazards := NewSpatialCollection[*Hazard](100)
+
+	for range hazardCount {
+		x, y, _ := spawnCoords(DefaultWorldBound, 40, nil, nil, hazards, rng)
+		hazards.Add(&Hazard{X: x, Y: y, Radius: 40})
+	}
+
+	for range sporeCount {
+		x, y, _ := spawnCoords(DefaultWorldBound, 5, nil, spores, hazards, rng)
+		spores.Add(&Spore{X: x, Y: y, Radius: 5})
+	}
+
+	for range spawnCount {
+		x, y, _ := spawnCoords(DefaultWorldBound, radius, players, spores, hazards, rng)
+
+		if math.Abs(x) > bound || math.Abs(y) > bound {
+			errs = append(errs, fmt.Errorf("Spawned at (%f, %f), outside the world bound %f", x, y, bound))
+		}
+
+		if isTooClose(x, y, radius, players) || isTooClose(x, y, radius, spores) || isTooClose(x, y, radius, hazards) {
+			errs = append(errs, fmt.Errorf("Spawned at (%f, %f), overlapping another object", x, y))
+		}
+
+		players.Add(&Player{X: x, Y: y, Radius: radius})
+	}
+
+	return errors.Join(errs...)
+}
\ No newline at end of file
@@ -0,0 +1,52 @@
+package objects
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestSelfTestPasses(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Errorf("The self-test failed on the game's own math:\n%v", err)
+	}
+}
+
+func TestSelfTestCatchesBrokenMassConversions(t *testing.T) {
+	// Mass growing with the radius rather than its square
+	linearMass := func(radius float64) float64 {
+		return math.Pi * radius
+	}
+
+	if err := checkMassConversions(linearMass, MassToRadius); err == nil {
+		t.Error("The self-test passed mass conversions which don't round trip")
+	}
+
+	// Round trips, but doubling the mass doubles the radius
+	linearRadius := func(mass float64) float64 {
+		return mass / math.Pi
+	}
+
+	if err := checkMassConversions(linearMass, linearRadius); err == nil {
+		t.Error("The self-test passed mass conversions which grow the radius too fast")
+	}
+}
+
+func TestSelfTestCatchesBrokenSpawning(t *testing.T) {
+	outOfBounds := func(worldBound float64, _ float64, _ *SharedCollection[*Player], _ *SharedCollection[*Spore], _ *SharedCollection[*Hazard], _ ...*rand.Rand) (float64, float64, bool) {
+		return 2 * worldBound, 0, true
+	}
+
+	if err := checkSpawnCoords(outOfBounds); err == nil {
+		t.Error("The self-test passed spawning outside the world")
+	}
+
+	// Every object lands on the same spot
+	overlapping := func(float64, float64, *SharedCollection[*Player], *SharedCollection[*Spore], *SharedCollection[*Hazard], ...*rand.Rand) (float64, float64, bool) {
+		return 0, 0, true
+	}
+
+	if err := checkSpawnCoords(overlapping); err == nil {
+		t.Error("The self-test passed spawning objects on top of each other")
+	}
+}
\ No newline at end of file
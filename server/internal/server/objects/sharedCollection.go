@@ -7,7 +7,10 @@ import (
 type SharedCollection[T any] struct {
 	objectsMap map[uint64]T
 	nextId     uint64
-	mapMux     sync.Mutex
+	mapMux     sync.RWMutex
+
+	// Optional index of where the objects are, kept in step with the map by Add, Remove and Reindex
+	grid *spatialGrid[T]
 }
 
 func NewSharedCollection[T any](capacity ...int) *SharedCollection[T] {
@@ -25,6 +28,15 @@ func NewSharedCollection[T any](capacity ...int) *SharedCollection[T] {
 	}
 }
 
+// A collection of objects in the world which can be queried by position with QueryRadius. Objects are bucketed into
+// square cells of the given size, which should be a few times the radius usually queried
+func NewSpatialCollection[T Circle](cellSize float64, capacity ...int) *SharedCollection[T] {
+	collection := NewSharedCollection[T](capacity...)
+	collection.grid = newSpatialGrid(cellSize, func(obj T) (float64, float64, float64) { return obj.Circle() })
+
+	return collection
+}
+
 // Add an object to the map with the given ID (if provided) or the next available ID.
 // Returns the ID of the object added
 func (collection *SharedCollection[T]) Add(obj T, id ...uint64) uint64 {
@@ -40,31 +52,153 @@ func (collection *SharedCollection[T]) Add(obj T, id ...uint64) uint64 {
 	collection.objectsMap[thisId] = obj
 	collection.nextId++
 
+	if collection.grid != nil {
+		collection.grid.insert(thisId, obj)
+	}
+
 	return thisId
 }
 
-// Removes an object from the map by ID, if it exists
-func (collection *SharedCollection[T]) Remove(id uint64) {
+// Removes an object from the map by ID, if it exists. Returns whether it did, so of several goroutines racing to
+// remove the same object only one is told it succeeded
+func (collection *SharedCollection[T]) Remove(id uint64) bool {
+	_, removed := collection.GetAndRemove(id)
+
+	return removed
+}
+
+// Removes an object from the map by ID and returns it, if it exists. The lookup and removal happen together, so of
+// several goroutines racing to take the same object exactly one gets it
+func (collection *SharedCollection[T]) GetAndRemove(id uint64) (T, bool) {
+	return collection.RemoveIf(id, func(T) bool { return true })
+}
+
+// Removes an object from the map by ID and returns it, if it exists and the condition holds for it. The condition is
+// checked while the collection is locked, so it must be quick and mustn't use the collection
+func (collection *SharedCollection[T]) RemoveIf(id uint64, condition func(T) bool) (T, bool) {
 	collection.mapMux.Lock()
 	defer collection.mapMux.Unlock()
 
+	obj, found := collection.objectsMap[id]
+
+	if !found || !condition(obj) {
+		var zero T
+		return zero, false
+	}
+
 	delete(collection.objectsMap, id)
+
+	if collection.grid != nil {
+		collection.grid.remove(id)
+	}
+
+	return obj, true
 }
 
-//  Call the callback function for each object in the map
-func (collection *SharedCollection[T]) ForEach(callback func(uint64, T)) {
-	// Create a local copy while holding the lock
+// Give the object with the first ID the second ID instead, unless there's already an object with that one. Returns
+// whether the object was there to move and was moved
+func (collection *SharedCollection[T]) Move(fromId uint64, toId uint64) bool {
+	collection.mapMux.Lock()
+	defer collection.mapMux.Unlock()
+
+	obj, found := collection.objectsMap[fromId]
+
+	if _, taken := collection.objectsMap[toId]; !found || taken {
+		return false
+	}
+
+	delete(collection.objectsMap, fromId)
+	collection.objectsMap[toId] = obj
+
+	if collection.grid != nil {
+		collection.grid.remove(fromId)
+		collection.grid.insert(toId, obj)
+	}
+
+	return true
+}
+
+// Update the spatial index for an object which has moved or changed size since it was added. Objects are indexed
+// where they were when added, so ones which move must be reindexed for QueryRadius to find them where they are
+func (collection *SharedCollection[T]) Reindex(id uint64) {
 	collection.mapMux.Lock()
+	defer collection.mapMux.Unlock()
+
+	obj, found := collection.objectsMap[id]
+
+	if found && collection.grid != nil {
+		collection.grid.insert(id, obj)
+	}
+}
+
+// Get the objects whose circle overlaps or touches the circle with the given centre and radius, looking only at the
+// cells nearby. Only collections made with NewSpatialCollection are indexed, others never return anything
+func (collection *SharedCollection[T]) QueryRadius(x float64, y float64, radius float64) []T {
+	collection.mapMux.RLock()
+	defer collection.mapMux.RUnlock()
+
+	if collection.grid == nil {
+		return nil
+	}
+
+	var found []T
+
+	collection.grid.query(x, y, radius, func(_ uint64, obj T) {
+		found = append(found, obj)
+	})
+
+	return found
+}
+
+// The IDs of the objects QueryRadius would find, for when they're needed to look up or remove the objects
+func (collection *SharedCollection[T]) QueryRadiusIds(x float64, y float64, radius float64) []uint64 {
+	collection.mapMux.RLock()
+	defer collection.mapMux.RUnlock()
+
+	if collection.grid == nil {
+		return nil
+	}
+
+	var found []uint64
+
+	collection.grid.query(x, y, radius, func(id uint64, _ T) {
+		found = append(found, id)
+	})
+
+	return found
+}
+
+// Get a copy of the map as it is right now. The copy belongs to the caller, who can iterate, sort and index it freely
+// without locking, but it won't reflect objects added or removed afterwards
+func (collection *SharedCollection[T]) Snapshot() map[uint64]T {
+	collection.mapMux.RLock()
+	defer collection.mapMux.RUnlock()
+
 	localCopy := make(map[uint64]T, len(collection.objectsMap))
 
 	for id, obj := range collection.objectsMap {
 		localCopy[id] = obj
 	}
 
-	collection.mapMux.Unlock()
+	return localCopy
+}
+
+//  Call the callback function for each object in the map
+func (collection *SharedCollection[T]) ForEach(callback func(uint64, T)) {
+	// Iterate over a copy so the lock isn't held while the callback runs
+	for id, obj := range collection.Snapshot() {
+		callback(id, obj)
+	}
+}
+
+// Call the callback function for each object in the map without copying it, for read-only iterations.
+// The callback must not add or remove objects in this collection, which would deadlock, and should be quick, since
+// nothing can be added or removed until the iteration is done
+func (collection *SharedCollection[T]) Range(callback func(uint64, T)) {
+	collection.mapMux.RLock()
+	defer collection.mapMux.RUnlock()
 
-	// Iterate over the local copy without holding the lock
-	for id, obj := range localCopy {
+	for id, obj := range collection.objectsMap {
 		callback(id, obj)
 	}
 }
@@ -72,16 +206,18 @@ func (collection *SharedCollection[T]) ForEach(callback func(uint64, T)) {
 // Get an object with the given ID, if it exists, otherwise nil
 // Also returns a boolean indicating whether the object was found
 func (collection *SharedCollection[T]) Get(id uint64) (T, bool) {
-	collection.mapMux.Lock()
-	defer collection.mapMux.Unlock()
+	collection.mapMux.RLock()
+	defer collection.mapMux.RUnlock()
 
 	obj, found := collection.objectsMap[id]
 
 	return obj, found
 }
 
-// Get the approximate number of objects in the map
-// The reason this is approximate is because the map is read without holding the lock
+// Get the number of objects in the map. Only the read lock is taken, so this stays cheap enough for hot loops
 func (collection *SharedCollection[T]) Len() int {
+	collection.mapMux.RLock()
+	defer collection.mapMux.RUnlock()
+
 	return len(collection.objectsMap)
 }
\ No newline at end of file
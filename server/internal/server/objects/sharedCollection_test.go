@@ -0,0 +1,243 @@
+package objects
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// The spatial index must find exactly what a scan of every object would, including after objects move and are removed
+func TestSpatialIndex(t *testing.T) {
+	const objectCount int = 1000
+	const queryCount int = 200
+
+	players := NewSpatialCollection[*Player](100)
+	ids := make([]uint64, 0, objectCount)
+
+	for range objectCount {
+		player := &Player{X: DefaultWorldBound * (2 * rand.Float64() - 1), Y: DefaultWorldBound * (2 * rand.Float64() - 1), Radius: 5 + 100 * rand.Float64()}
+		ids = append(ids, players.Add(player))
+	}
+
+	for i, id := range ids {
+		switch i % 3 {
+			case 0:
+				players.Remove(id)
+			case 1:
+				player, _ := players.Get(id)
+				player.X = ClampToWorld(player.X + 500 * (2 * rand.Float64() - 1), player.Radius, DefaultWorldBound)
+				player.Radius *= 2
+				players.Reindex(id)
+		}
+	}
+
+	for range queryCount {
+		x := DefaultWorldBound * (2 * rand.Float64() - 1)
+		y := DefaultWorldBound * (2 * rand.Float64() - 1)
+		radius := 200 * rand.Float64()
+
+		expected := 0
+		players.Range(func(_ uint64, player *Player) {
+			if math.Hypot(player.X - x, player.Y - y) <= radius + player.Radius {
+				expected++
+			}
+		})
+
+		if found := len(players.QueryRadius(x, y, radius)); found != expected {
+			t.Errorf("Found %d objects within %f of (%f, %f) but %d are there", found, radius, x, y, expected)
+		}
+	}
+}
+
+// Finding the objects near a point among 1000 spread over the world, by scanning every object and through the grid
+func BenchmarkQueryRadius(b *testing.B) {
+	const objectCount int = 1000
+	const radius float64 = 100
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	players := NewSpatialCollection[*Player](100)
+
+	for range objectCount {
+		players.Add(&Player{X: DefaultWorldBound * (2 * rng.Float64() - 1), Y: DefaultWorldBound * (2 * rng.Float64() - 1), Radius: 20})
+	}
+
+	b.Run("linear", func(b *testing.B) {
+		for b.Loop() {
+			x := DefaultWorldBound * (2 * rng.Float64() - 1)
+			y := DefaultWorldBound * (2 * rng.Float64() - 1)
+
+			var found []*Player
+			players.Range(func(_ uint64, player *Player) {
+				if math.Hypot(player.X - x, player.Y - y) <= radius + player.Radius {
+					found = append(found, player)
+				}
+			})
+		}
+	})
+
+	b.Run("grid", func(b *testing.B) {
+		for b.Loop() {
+			players.QueryRadius(DefaultWorldBound * (2 * rng.Float64() - 1), DefaultWorldBound * (2 * rng.Float64() - 1), radius)
+		}
+	})
+}
+
+// Of many goroutines racing to take the same object, exactly one must get it
+func TestGetAndRemoveRace(t *testing.T) {
+	const racers int = 100
+
+	spores := NewSpatialCollection[*Spore](100)
+	sporeId := spores.Add(&Spore{Radius: 5})
+
+	var winners atomic.Int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+
+	for range racers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			<-start
+
+			if _, taken := spores.GetAndRemove(sporeId); taken {
+				winners.Add(1)
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	if winners.Load() != 1 {
+		t.Errorf("%d of %d goroutines racing to take the same object got it", winners.Load(), racers)
+	}
+}
+
+// Numbers of spores to time an iteration over a collection with
+var benchmarkSporeCounts = []int{100, 1000, 10000}
+
+// A spatial collection of spores spread over the world
+func newBenchmarkSpores(sporeCount int) *SharedCollection[*Spore] {
+	spores := NewSpatialCollection[*Spore](500, sporeCount)
+
+	for range sporeCount {
+		spores.Add(&Spore{X: DefaultWorldBound * (2 * rand.Float64() - 1), Y: DefaultWorldBound * (2 * rand.Float64() - 1), Radius: 10})
+	}
+
+	return spores
+}
+
+// Time a read-only pass over every spore with ForEach, which copies the collection first
+func BenchmarkForEach(b *testing.B) {
+	for _, sporeCount := range benchmarkSporeCounts {
+		b.Run(fmt.Sprintf("%d spores", sporeCount), func(b *testing.B) {
+			benchmarkIteration(b, newBenchmarkSpores(sporeCount).ForEach)
+		})
+	}
+}
+
+// Time the same pass with Range, which iterates the collection in place
+func BenchmarkRange(b *testing.B) {
+	for _, sporeCount := range benchmarkSporeCounts {
+		b.Run(fmt.Sprintf("%d spores", sporeCount), func(b *testing.B) {
+			benchmarkIteration(b, newBenchmarkSpores(sporeCount).Range)
+		})
+	}
+}
+
+func benchmarkIteration(b *testing.B, iterate func(func(uint64, *Spore))) {
+	b.ReportAllocs()
+	var totalRadius float64
+
+	for b.Loop() {
+		iterate(func(_ uint64, spore *Spore) { totalRadius += spore.Radius })
+	}
+}
+
+// A read-only pass with Range mustn't allocate at all, where ForEach allocates a copy of the collection every time
+func TestRangeDoesNotCopy(t *testing.T) {
+	spores := newBenchmarkSpores(1000)
+	count := 0
+	countSpore := func(uint64, *Spore) { count++ }
+
+	if allocs := testing.AllocsPerRun(10, func() { spores.Range(countSpore) }); allocs != 0 {
+		t.Errorf("Range allocated %.0f times per pass over the collection", allocs)
+	}
+
+	if allocs := testing.AllocsPerRun(10, func() { spores.ForEach(countSpore) }); allocs == 0 {
+		t.Error("ForEach didn't allocate a copy of the collection")
+	}
+
+	if count != 2 * 11 * spores.Len() {
+		t.Errorf("Visited %d spores over 22 passes of a collection of %d", count, spores.Len())
+	}
+}
+
+// Reading the length while other goroutines add and remove objects must not race (run with -race), and must never see
+// more objects than were ever in the collection at once
+func TestLenRace(t *testing.T) {
+	const writers int = 4
+	const rounds int = 1000
+
+	spores := NewSpatialCollection[*Spore](100)
+	var writing sync.WaitGroup
+	done := make(chan struct{})
+
+	for range writers {
+		writing.Go(func() {
+			for range rounds {
+				spores.Remove(spores.Add(&Spore{Radius: 5}))
+			}
+		})
+	}
+
+	go func() {
+		writing.Wait()
+		close(done)
+	}()
+
+	for {
+		select {
+			case <-done:
+				if length := spores.Len(); length != 0 {
+					t.Errorf("The collection has length %d after every object added was removed", length)
+				}
+
+				return
+			default:
+				if length := spores.Len(); length < 0 || length > writers {
+					t.Fatalf("The collection has length %d with at most %d objects in it at once", length, writers)
+				}
+		}
+	}
+}
+
+// A snapshot must belong to the caller: objects added to or removed from the collection afterwards mustn't show up in
+// it, and changing the snapshot mustn't change the collection
+func TestSnapshotIsolation(t *testing.T) {
+	spores := NewSpatialCollection[*Spore](100)
+	keptId := spores.Add(&Spore{Radius: 5})
+	removedId := spores.Add(&Spore{X: 50, Radius: 5})
+
+	snapshot := spores.Snapshot()
+	addedId := spores.Add(&Spore{X: 100, Radius: 5})
+	spores.Remove(removedId)
+
+	if _, found := snapshot[addedId]; found || len(snapshot) != 2 {
+		t.Errorf("A spore added after the snapshot shows up in it, which has %d spores", len(snapshot))
+	}
+
+	if _, found := snapshot[removedId]; !found {
+		t.Error("A spore removed after the snapshot is missing from it")
+	}
+
+	delete(snapshot, keptId)
+
+	if _, found := spores.Get(keptId); !found {
+		t.Error("Deleting a spore from the snapshot took it out of the collection")
+	}
+}
\ No newline at end of file
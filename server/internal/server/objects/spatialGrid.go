@@ -0,0 +1,109 @@
+package objects
+
+import "math"
+
+// Objects which take up a circle in the world, so they can be indexed by a spatial grid
+type Circle interface {
+	Circle() (x float64, y float64, radius float64)
+}
+
+type gridKey struct {
+	col int
+	row int
+}
+
+// Buckets the objects of a collection by the square cell their centre is in, so proximity queries only need to look at
+// the few cells around a point instead of every object. It isn't safe for concurrent use on its own, the collection
+// owning it keeps it in step with its map under the same lock
+type spatialGrid[T any] struct {
+	cellSize float64
+	getCircle func(T) (float64, float64, float64)
+	cells map[gridKey]map[uint64]T
+	keys map[uint64]gridKey
+
+	// The largest radius seen since the grid was last empty, for how far past the query radius to look for objects
+	// whose centre is in another cell but whose edge reaches the query
+	maxRadius float64
+}
+
+func newSpatialGrid[T any](cellSize float64, getCircle func(T) (float64, float64, float64)) *spatialGrid[T] {
+	return &spatialGrid[T]{
+		cellSize: cellSize,
+		getCircle: getCircle,
+		cells: make(map[gridKey]map[uint64]T),
+		keys: make(map[uint64]gridKey),
+	}
+}
+
+func (grid *spatialGrid[T]) keyOf(x float64, y float64) gridKey {
+	return gridKey{
+		col: int(math.Floor(x / grid.cellSize)),
+		row: int(math.Floor(y / grid.cellSize)),
+	}
+}
+
+// Put the object in the cell it's in now, moving it out of the cell it was in before if needed
+func (grid *spatialGrid[T]) insert(id uint64, obj T) {
+	x, y, radius := grid.getCircle(obj)
+	key := grid.keyOf(x, y)
+
+	if oldKey, found := grid.keys[id]; found && oldKey != key {
+		grid.removeFromCell(id, oldKey)
+	}
+
+	cell, found := grid.cells[key]
+
+	if !found {
+		cell = make(map[uint64]T)
+		grid.cells[key] = cell
+	}
+
+	cell[id] = obj
+	grid.keys[id] = key
+	grid.maxRadius = max(grid.maxRadius, radius)
+}
+
+func (grid *spatialGrid[T]) remove(id uint64) {
+	key, found := grid.keys[id]
+
+	if !found {
+		return
+	}
+
+	grid.removeFromCell(id, key)
+	delete(grid.keys, id)
+
+	if len(grid.keys) == 0 {
+		grid.maxRadius = 0
+	}
+}
+
+func (grid *spatialGrid[T]) removeFromCell(id uint64, key gridKey) {
+	cell := grid.cells[key]
+	delete(cell, id)
+
+	if len(cell) == 0 {
+		delete(grid.cells, key)
+	}
+}
+
+// Call the callback for each object whose circle overlaps or touches the circle with the given centre and radius
+func (grid *spatialGrid[T]) query(x float64, y float64, radius float64, callback func(uint64, T)) {
+	reach := radius + grid.maxRadius
+	minKey := grid.keyOf(x - reach, y - reach)
+	maxKey := grid.keyOf(x + reach, y + reach)
+
+	for col := minKey.col; col <= maxKey.col; col++ {
+		for row := minKey.row; row <= maxKey.row; row++ {
+			for id, obj := range grid.cells[gridKey{col: col, row: row}] {
+				objX, objY, objRadius := grid.getCircle(obj)
+				xDist := objX - x
+				yDist := objY - y
+
+				if xDist * xDist + yDist * yDist <= (radius + objRadius) * (radius + objRadius) {
+					callback(id, obj)
+				}
+			}
+		}
+	}
+}
\ No newline at end of file
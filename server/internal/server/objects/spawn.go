@@ -1,59 +1,105 @@
 package objects
 
-import "math/rand/v2"
+import (
+	"math"
+	"math/rand/v2"
+)
 
-var getPlayerPosition = func(player *Player) (float64, float64) { return player.X, player.Y }
-var getPlayerRadius = func(player *Player) float64 { return player.Radius }
-var getSporePosition = func(spore *Spore) (float64, float64) { return spore.X, spore.Y }
-var getSporeRadius = func(spore *Spore) float64 { return spore.Radius }
+// Half the width of the square world unless configured otherwise, with the world spanning from -bound to bound on both
+// axes
+const DefaultWorldBound float64 = 3000
 
-func isTooClose[T any](x float64, y float64, radius float64, objects *SharedCollection[T], getPosition func(T) (float64, float64), getRadius func(T) float64) bool {
+// Keep a coordinate of an object with the given radius far enough inside the world that its edge doesn't cross the wall
+func ClampToWorld(coord float64, radius float64, worldBound float64) float64 {
+	limit := max(worldBound - radius, 0)
+
+	return min(max(coord, -limit), limit)
+}
+
+// Draws from the package-level generator, so it's safe to share between goroutines
+type globalSource struct{}
+
+func (globalSource) Uint64() uint64 {
+	return rand.Uint64()
+}
+
+// Generator for callers which don't need to seed their own, backed by the package-level one
+var GlobalRand = rand.New(globalSource{})
+
+// The generator passed to an optional RNG argument, if any, otherwise the global one
+func pickRand(rng []*rand.Rand) *rand.Rand {
+	if len(rng) > 0 && rng[0] != nil {
+		return rng[0]
+	}
+
+	return GlobalRand
+}
+
+// Whether a circle with the given centre and radius would overlap any object in the collection, which must have been
+// made with NewSpatialCollection
+func isTooClose[T any](x float64, y float64, radius float64, objects *SharedCollection[T]) bool {
 	if objects == nil {
 		return false
 	}
 
-	tooClose := false
-	objects.ForEach(func(_ uint64, object T) {
-		if tooClose {
-			return
-		}
+	return len(objects.QueryRadius(x, y, radius)) > 0
+}
 
-		objX, objY := getPosition(object)
-		objRad := getRadius(object)
-		xDist := objX - x
-		yDist := objY - y
-		distSq := xDist * xDist + yDist * yDist
+// How far the edge of a circle with the given centre and radius is from the nearest object it overlaps, which is
+// negative or zero, or infinity if it doesn't overlap any
+func clearance[T Circle](x float64, y float64, radius float64, objects *SharedCollection[T]) float64 {
+	nearest := math.Inf(1)
 
-		if distSq <= (radius + objRad) * (radius + objRad) {
-			tooClose = true
-			return
-		}
-	})
+	if objects == nil {
+		return nearest
+	}
+
+	for _, object := range objects.QueryRadius(x, y, radius) {
+		objX, objY, objRadius := object.Circle()
+		nearest = min(nearest, math.Hypot(objX - x, objY - y) - objRadius - radius)
+	}
 
-	return tooClose
+	return nearest
 }
 
-func SpawnCoords(radius float64, playersToAvoid *SharedCollection[*Player], sporesToAvoid *SharedCollection[*Spore]) (float64, float64) {
-	bound := 3000.0
+// Find a free spot anywhere in the world. A seeded generator can be passed to make the spot reproducible
+func SpawnCoords(worldBound float64, radius float64, playersToAvoid *SharedCollection[*Player], sporesToAvoid *SharedCollection[*Spore], hazardsToAvoid *SharedCollection[*Hazard], rng ...*rand.Rand) (float64, float64, bool) {
+	return SpawnCoordsAround(worldBound, 0, 0, worldBound, radius, playersToAvoid, sporesToAvoid, hazardsToAvoid, rng...)
+}
+
+// Find a free spot within the bound of the given center and inside the world, widening the search if the area is too
+// crowded. If the world is so full that no free spot turns up, gives up and returns the least crowded spot tried, with
+// false to say it overlaps something
+func SpawnCoordsAround(worldBound float64, centerX float64, centerY float64, bound float64, radius float64, playersToAvoid *SharedCollection[*Player], sporesToAvoid *SharedCollection[*Spore], hazardsToAvoid *SharedCollection[*Hazard], rng ...*rand.Rand) (float64, float64, bool) {
+	// Widen the search after this many tries in the same area, and give up after this many tries altogether
 	const maxTries int = 25
+	const maxAttempts int = 500
 
+	random := pickRand(rng)
 	tries := 0
+	bestX, bestY, bestClearance := 0.0, 0.0, math.Inf(-1)
 
-	for {
-		x := bound * (2 * rand.Float64() - 1)
-		y := bound * (2 * rand.Float64() - 1)
-		closeToPlayer := isTooClose(x, y, radius, playersToAvoid, getPlayerPosition, getPlayerRadius)
-		closeToSpore := isTooClose(x, y, radius, sporesToAvoid, getSporePosition, getSporeRadius)
+	for range maxAttempts {
+		x := ClampToWorld(centerX + bound * (2 * random.Float64() - 1), radius, worldBound)
+		y := ClampToWorld(centerY + bound * (2 * random.Float64() - 1), radius, worldBound)
+		spotClearance := min(clearance(x, y, radius, playersToAvoid), clearance(x, y, radius, sporesToAvoid), clearance(x, y, radius, hazardsToAvoid))
 
-		if !closeToPlayer && !closeToSpore {
-			return x, y
+		if math.IsInf(spotClearance, 1) {
+			return x, y, true
+		}
+
+		if spotClearance > bestClearance {
+			bestX, bestY, bestClearance = x, y, spotClearance
 		}
 
 		tries++
 
+		// There's no point widening past the whole world, since everything gets clamped back into it
 		if tries >= maxTries {
-			bound *= 2
+			bound = min(bound * 2, 2 * worldBound)
 			tries = 0
 		}
 	}
+
+	return bestX, bestY, false
 }
\ No newline at end of file
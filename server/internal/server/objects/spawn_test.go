@@ -0,0 +1,48 @@
+package objects
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+// The same seed must give the same spot, and a player placed there must push the next spawn with that seed elsewhere
+func TestSeededSpawn(t *testing.T) {
+	const radius float64 = 20
+
+	players := NewSpatialCollection[*Player](100)
+	x, y, _ := SpawnCoords(DefaultWorldBound, radius, players, nil, nil, rand.New(rand.NewPCG(1, 2)))
+	sameX, sameY, _ := SpawnCoords(DefaultWorldBound, radius, players, nil, nil, rand.New(rand.NewPCG(1, 2)))
+
+	if x != sameX || y != sameY {
+		t.Errorf("The same seed spawned at (%f, %f) and then (%f, %f)", x, y, sameX, sameY)
+	}
+
+	players.Add(&Player{X: x, Y: y, Radius: radius})
+	nextX, nextY, _ := SpawnCoords(DefaultWorldBound, radius, players, nil, nil, rand.New(rand.NewPCG(1, 2)))
+
+	if math.Hypot(nextX - x, nextY - y) <= 2 * radius {
+		t.Errorf("Spawned at (%f, %f), overlapping the player placed at (%f, %f)", nextX, nextY, x, y)
+	}
+}
+
+// A world with no free spot left must make spawning give up promptly instead of searching forever
+func TestSaturatedSpawn(t *testing.T) {
+	const radius float64 = 20
+	const timeLimit time.Duration = time.Second
+
+	players := NewSpatialCollection[*Player](100)
+	players.Add(&Player{X: 0, Y: 0, Radius: 2 * DefaultWorldBound})
+
+	start := time.Now()
+	_, _, free := SpawnCoords(DefaultWorldBound, radius, players, nil, nil)
+
+	if elapsed := time.Since(start); elapsed > timeLimit {
+		t.Errorf("Spawning in a full world took %s", elapsed)
+	}
+
+	if free {
+		t.Error("Spawning in a full world claimed to find a free spot")
+	}
+}
\ No newline at end of file
@@ -0,0 +1,50 @@
+package objects
+
+import "sync"
+
+// A circular safe area centered on the world's origin which can shrink over time.
+// A zone with a radius of 0 is disabled.
+type Zone struct {
+	radius float64
+	mux    sync.RWMutex
+}
+
+func NewZone(radius float64) *Zone {
+	return &Zone{radius: radius}
+}
+
+func (zone *Zone) Radius() float64 {
+	zone.mux.RLock()
+	defer zone.mux.RUnlock()
+
+	return zone.radius
+}
+
+func (zone *Zone) SetRadius(radius float64) {
+	zone.mux.Lock()
+	defer zone.mux.Unlock()
+
+	zone.radius = radius
+}
+
+// Shrink the zone by the given amount without going below the minimum radius. The zone never grows.
+// Returns the new radius of the zone
+func (zone *Zone) Shrink(amount float64, minRadius float64) float64 {
+	zone.mux.Lock()
+	defer zone.mux.Unlock()
+
+	zone.radius = max(zone.radius - amount, min(minRadius, zone.radius))
+
+	return zone.radius
+}
+
+// Whether the given point is inside the zone. Every point is inside a disabled zone
+func (zone *Zone) Contains(x float64, y float64) bool {
+	radius := zone.Radius()
+
+	if radius <= 0 {
+		return true
+	}
+
+	return x * x + y * y <= radius * radius
+}
\ No newline at end of file
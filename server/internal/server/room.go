@@ -0,0 +1,612 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"server/internal/server/objects"
+	"server/pkg/packets"
+	"slices"
+	"time"
+)
+
+// The room players join when they don't ask for one. It's never torn down
+const DefaultRoomName = "default"
+
+// Longest room name accepted from a client
+const MaxRoomNameLength int = 32
+
+// Size of the cells players and spores are bucketed into for finding what's near a point
+const spatialCellSize float64 = 100
+
+// A separate arena with its own world, whose players only see each other
+type Room struct {
+	Name string
+	hub *Hub
+
+	// The clients connected to this room
+	Clients *objects.SharedCollection[ClientInterfacer]
+
+	// Packets in this channel will be processed by all clients in the room except the sender
+	BroadcastChan chan *packets.Packet
+
+	// Packets in this channel will be processed by all clients in the room except the sender and the excluded clients
+	ExclusiveBroadcastChan chan *ExclusiveBroadcast
+
+	SharedGameObjects *SharedGameObjects
+
+	// Moves all of the room's players on together, when they aren't each on their own update loop
+	Simulation *Simulation
+
+	// The room's loops stop when this is cancelled by tearing the room down or shutting down the hub
+	ctx context.Context
+	cancel context.CancelFunc
+
+	// Clients which have joined or are about to join, guarded by the hub's rooms lock
+	members int
+	idleTimer *time.Timer
+
+	// Generator for where spores go and how big they are, which can be seeded to reproduce a world
+	rng *rand.Rand
+}
+
+func newRoom(hub *Hub, name string) *Room {
+	ctx, cancel := context.WithCancel(hub.ctx)
+	clients := objects.NewSharedCollection[ClientInterfacer]()
+
+	return &Room{
+		Name: name,
+		hub: hub,
+		Clients: clients,
+		BroadcastChan: make(chan *packets.Packet),
+		ExclusiveBroadcastChan: make(chan *ExclusiveBroadcast),
+		// Players are limited per IP address across the whole server, not per room
+		SharedGameObjects: NewSharedGameObjects(hub.Config().MaxSpores, hub.playersPerIp),
+		Simulation: NewSimulation(clients),
+		ctx: ctx,
+		cancel: cancel,
+		rng: objects.GlobalRand,
+	}
+}
+
+// An empty world, counting players per IP address with the given counter
+func NewSharedGameObjects(maxSpores int, playersPerIp *KeyCounter) *SharedGameObjects {
+	return &SharedGameObjects{
+		Players: objects.NewSpatialCollection[*objects.Player](spatialCellSize),
+		Spores: objects.NewSpatialCollection[*objects.Spore](spatialCellSize, maxSpores),
+		Hazards: objects.NewSpatialCollection[*objects.Hazard](spatialCellSize),
+		Zone: objects.NewZone(0),
+		PlayersPerIp: playersPerIp,
+	}
+}
+
+// Cancelled when the room is torn down or the hub shuts down
+func (room *Room) Context() context.Context {
+	return room.ctx
+}
+
+// Fill the room's world and pass broadcasts on to its clients until the room is torn down
+func (room *Room) Run() {
+	log.Printf("Placing spores in room %s...", room.Name)
+	cellCounts := room.sporeCellCounts()
+	sporeCount := min(room.hub.Config().MaxSpores, room.objectBudgetLeft())
+
+	for i := 0; i < sporeCount; i++ {
+		spore := room.newSpore(cellCounts)
+
+		if spore == nil {
+			log.Printf("No room left for spores in room %s after placing %d", room.Name, i)
+			break
+		}
+
+		// Start the spores at different ages so they don't all expire together
+		if lifetime := room.hub.Config().SporeLifetime; lifetime > 0 {
+			spore.SpawnedAt = spore.SpawnedAt.Add(-time.Duration(room.rng.Int64N(int64(lifetime))))
+		}
+
+		room.SharedGameObjects.Spores.Add(spore)
+	}
+
+	go room.replenishSporesLoop(room.hub.Config().SporeReplenishInterval)
+
+	if room.hub.Config().SporeLifetime > 0 {
+		go room.expireSporesLoop(time.Second)
+	}
+
+	if room.hub.Config().SporeMergeRadius > 0 {
+		go room.mergeSporesLoop(5 * time.Second)
+	}
+
+	if room.hub.Config().MaxHazards > 0 {
+		go room.replenishHazardsLoop(5 * time.Second)
+	}
+
+	if room.hub.Config().SharedTick {
+		paused := func() bool { return room.hub.Config().Paused }
+		// Each tick's batch counts as one broadcast
+		onTick := func(players int) {
+			if players > 0 {
+				room.hub.Broadcasts.Add(1)
+			}
+		}
+
+		go room.Simulation.Run(room.ctx, TickInterval, paused, onTick)
+	}
+
+	if interval := room.hub.Config().ServerStatsInterval; interval > 0 {
+		go room.broadcastServerStatsLoop(interval)
+	}
+
+	if topN := room.hub.Config().LeaderboardSize; topN > 0 {
+		go room.leaderboardLoop(time.Second, topN)
+	}
+
+	if room.hub.Config().ZoneStartRadius > 0 {
+		room.SharedGameObjects.Zone.SetRadius(room.hub.Config().ZoneStartRadius)
+		go room.shrinkZoneLoop(time.Second)
+	}
+
+	for {
+		select {
+			case <-room.ctx.Done():
+				log.Printf("Room %s closed", room.Name)
+				return
+			case packet := <-room.BroadcastChan:
+				room.Clients.ForEach(func(clientId uint64, client ClientInterfacer) {
+					if clientId != packet.SenderId {
+						client.ProcessMessage(packet.SenderId, packet.Msg)
+					}
+				})
+			case broadcast := <-room.ExclusiveBroadcastChan:
+				excludedIds := make(map[uint64]struct{}, len(broadcast.ExcludedIds) + 1)
+				excludedIds[broadcast.Packet.SenderId] = struct{}{}
+
+				for _, id := range broadcast.ExcludedIds {
+					excludedIds[id] = struct{}{}
+				}
+
+				room.Clients.ForEach(func(clientId uint64, client ClientInterfacer) {
+					if _, excluded := excludedIds[clientId]; !excluded {
+						client.ProcessMessage(broadcast.Packet.SenderId, broadcast.Packet.Msg)
+					}
+				})
+		}
+	}
+}
+
+// Identifies a cell of the grid the world is divided into for spreading out spores
+type gridCell struct {
+	x int
+	y int
+}
+
+func (room *Room) cellOf(x float64, y float64) gridCell {
+	cellSize := room.hub.Config().SporeCellSize
+
+	return gridCell{x: int(math.Floor(x / cellSize)), y: int(math.Floor(y / cellSize))}
+}
+
+// Count the spores in each grid cell, or nil if spores aren't capped per cell
+func (room *Room) sporeCellCounts() map[gridCell]int {
+	if room.hub.Config().MaxSporesPerCell <= 0 {
+		return nil
+	}
+
+	cellCounts := make(map[gridCell]int)
+
+	room.SharedGameObjects.Spores.ForEach(func(_ uint64, spore *objects.Spore) {
+		cellCounts[room.cellOf(spore.X, spore.Y)]++
+	})
+
+	return cellCounts
+}
+
+// Create a spore at a free spot. If cell counts are given, the spore avoids full cells and is counted in its cell.
+// Returns nil if the world is too crowded to fit another spore
+func (room *Room) newSpore(cellCounts map[gridCell]int) *objects.Spore {
+	// Give up on finding a cell with room after this many tries, rather than stalling when the cap is too tight
+	const maxCellTries int = 100
+
+	config := room.hub.Config()
+	sporeRadius := max(config.SporeRadiusMean + room.rng.NormFloat64() * config.SporeRadiusDeviation, config.MinSporeRadius)
+	x, y, free := room.sporeSpawnCoords(sporeRadius)
+
+	if !free {
+		return nil
+	}
+
+	if cellCounts != nil {
+		for tries := 1; tries < maxCellTries && cellCounts[room.cellOf(x, y)] >= room.hub.Config().MaxSporesPerCell; tries++ {
+			if nextX, nextY, nextFree := room.sporeSpawnCoords(sporeRadius); nextFree {
+				x, y = nextX, nextY
+			}
+		}
+
+		// Every cell tried was full, so the cap leaves no room for the spore
+		if cellCounts[room.cellOf(x, y)] >= room.hub.Config().MaxSporesPerCell {
+			return nil
+		}
+
+		cellCounts[room.cellOf(x, y)]++
+	}
+
+	sporeType := objects.RandomSporeType(config.SporeTypeWeights[:], room.rng)
+
+	return &objects.Spore{X: x, Y: y, Radius: sporeRadius, Type: sporeType, SpawnedAt: time.Now()}
+}
+
+// Pick a free spot for a new spore. With smart replenishing, this is somewhere ahead of a random player
+func (room *Room) sporeSpawnCoords(radius float64) (float64, float64, bool) {
+	// How far ahead of the player to aim, and how far around that point the spore can land
+	const lead float64 = 300
+	const spread float64 = 500
+
+	players := room.SharedGameObjects.Players
+	spores := room.SharedGameObjects.Spores
+	hazards := room.SharedGameObjects.Hazards
+
+	if room.hub.Config().SmartReplenish {
+		candidates := make([]*objects.Player, 0, players.Len())
+		players.ForEach(func(_ uint64, player *objects.Player) {
+			candidates = append(candidates, player)
+		})
+
+		if len(candidates) > 0 {
+			player := candidates[room.rng.IntN(len(candidates))]
+			aheadX := player.X + lead * math.Cos(player.Direction)
+			aheadY := player.Y + lead * math.Sin(player.Direction)
+
+			return objects.SpawnCoordsAround(room.hub.Config().WorldBound, aheadX, aheadY, spread, radius, players, spores, hazards, room.rng)
+		}
+	}
+
+	return objects.SpawnCoords(room.hub.Config().WorldBound, radius, players, spores, hazards, room.rng)
+}
+
+func (room *Room) replenishSporesLoop(rate time.Duration) {
+	ticker := time.NewTicker(rate)
+	defer ticker.Stop()
+
+	for {
+		select {
+			case <-room.ctx.Done():
+				return
+			case <-ticker.C:
+		}
+
+		if room.hub.Config().Paused {
+			continue
+		}
+
+		sporesRemaining := room.SharedGameObjects.Spores.Len()
+		diff := room.hub.Config().MaxSpores - sporesRemaining
+
+		if diff <= 0 {
+			continue
+		}
+
+		// Players always get to join, so it's the spores which make way when the world gets too full
+		if budgetLeft := max(room.objectBudgetLeft(), 0); budgetLeft < diff {
+			log.Printf("World object budget nearly used up - only replenishing %d of %d missing spores", budgetLeft, diff)
+			room.hub.SpawnsThrottled.Add(uint64(diff - budgetLeft))
+			diff = budgetLeft
+
+			if diff == 0 {
+				continue
+			}
+		}
+
+		log.Printf("%d spores remain - going to replenish %d spores", sporesRemaining, diff)
+
+		cellCounts := room.sporeCellCounts()
+
+		// Don't really want to spawn too many at a time, otherwise it can cause lag spikes
+		for i := 0; i < min(diff, room.hub.Config().SporeReplenishBatch); i++ {
+			spore := room.newSpore(cellCounts)
+
+			if spore == nil {
+				log.Println("No room left for more spores - waiting for some to be eaten")
+				break
+			}
+
+			sporeId := room.SharedGameObjects.Spores.Add(spore)
+
+			room.broadcast(&packets.Packet{
+				SenderId: 0,
+				Msg: packets.NewSpore(sporeId, spore),
+			})
+
+			// Sleep a little bit to avoid lag spikes
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+}
+
+// How many more objects fit in the world before reaching the configured budget
+func (room *Room) objectBudgetLeft() int {
+	budget := room.hub.Config().MaxWorldObjects
+
+	if budget <= 0 {
+		return math.MaxInt
+	}
+
+	return budget - room.SharedGameObjects.Spores.Len() - room.SharedGameObjects.Players.Len() - room.SharedGameObjects.Hazards.Len()
+}
+
+// Keep the world topped up with hazards, starting straight away
+func (room *Room) replenishHazardsLoop(rate time.Duration) {
+	ticker := time.NewTicker(rate)
+	defer ticker.Stop()
+
+	for {
+		if !room.hub.Config().Paused {
+			room.replenishHazards()
+		}
+
+		select {
+			case <-room.ctx.Done():
+				return
+			case <-ticker.C:
+		}
+	}
+}
+
+// Put back any missing hazards, away from everything else in the world
+func (room *Room) replenishHazards() {
+	config := room.hub.Config()
+	hazards := room.SharedGameObjects.Hazards
+	missing := min(config.MaxHazards - hazards.Len(), max(room.objectBudgetLeft(), 0))
+
+	for range missing {
+		x, y, free := objects.SpawnCoords(config.WorldBound, config.HazardRadius, room.SharedGameObjects.Players, room.SharedGameObjects.Spores, hazards, room.rng)
+
+		if !free {
+			slog.Warn("No room left for hazards, waiting for some to be popped", "room", room.Name)
+			return
+		}
+
+		hazard := &objects.Hazard{X: x, Y: y, Radius: config.HazardRadius}
+		hazardId := hazards.Add(hazard)
+
+		room.broadcast(&packets.Packet{
+			SenderId: 0,
+			Msg: packets.NewHazard(hazardId, hazard),
+		})
+	}
+}
+
+// Remove spores which have outlived their lifetime, so the replenish loop puts fresh ones somewhere else
+func (room *Room) expireSporesLoop(rate time.Duration) {
+	ticker := time.NewTicker(rate)
+	defer ticker.Stop()
+
+	for {
+		select {
+			case <-room.ctx.Done():
+				return
+			case <-ticker.C:
+		}
+
+		if room.hub.Config().Paused {
+			continue
+		}
+
+		lifetime := room.hub.Config().SporeLifetime
+
+		room.SharedGameObjects.Spores.ForEach(func(sporeId uint64, spore *objects.Spore) {
+			if time.Since(spore.SpawnedAt) < lifetime {
+				return
+			}
+
+			room.SharedGameObjects.Spores.Remove(sporeId)
+
+			room.broadcast(&packets.Packet{
+				SenderId: 0,
+				Msg: packets.NewSporeRemoved(sporeId),
+			})
+		})
+	}
+}
+
+// Merge spores clustered close together into single bigger spores, to keep the number of objects down
+func (room *Room) mergeSporesLoop(rate time.Duration) {
+	ticker := time.NewTicker(rate)
+	defer ticker.Stop()
+
+	for {
+		select {
+			case <-room.ctx.Done():
+				return
+			case <-ticker.C:
+		}
+
+		if room.hub.Config().Paused {
+			continue
+		}
+
+		room.mergeSpores(room.hub.Config().SporeMergeRadius)
+	}
+}
+
+func (room *Room) mergeSpores(mergeRadius float64) {
+	sporeIds := make([]uint64, 0, room.SharedGameObjects.Spores.Len())
+	spores := make(map[uint64]*objects.Spore, cap(sporeIds))
+
+	room.SharedGameObjects.Spores.ForEach(func(sporeId uint64, spore *objects.Spore) {
+		sporeIds = append(sporeIds, sporeId)
+		spores[sporeId] = spore
+	})
+
+	merged := make(map[uint64]bool)
+
+	for i, sporeId := range sporeIds {
+		if merged[sporeId] {
+			continue
+		}
+
+		spore := spores[sporeId]
+		cluster := []uint64{sporeId}
+
+		for _, otherId := range sporeIds[i + 1:] {
+			other := spores[otherId]
+
+			if !merged[otherId] && math.Hypot(other.X - spore.X, other.Y - spore.Y) <= mergeRadius {
+				cluster = append(cluster, otherId)
+			}
+		}
+
+		if len(cluster) < 2 {
+			continue
+		}
+
+		// The merged spore sits at the cluster's centre of mass and lasts as long as its oldest spore would have. It's a
+		// plain spore worth as much as the cluster, whatever types were in it
+		mergedSpore := &objects.Spore{SpawnedAt: spore.SpawnedAt}
+		totalMass := 0.0
+
+		for _, clusterId := range cluster {
+			// Skip spores eaten since the snapshot was taken, so their mass isn't counted twice
+			clusterSpore, found := room.SharedGameObjects.Spores.Get(clusterId)
+
+			if !found {
+				continue
+			}
+
+			room.SharedGameObjects.Spores.Remove(clusterId)
+			merged[clusterId] = true
+
+			mass := clusterSpore.Mass()
+			mergedSpore.X += clusterSpore.X * mass
+			mergedSpore.Y += clusterSpore.Y * mass
+			totalMass += mass
+
+			if clusterSpore.SpawnedAt.Before(mergedSpore.SpawnedAt) {
+				mergedSpore.SpawnedAt = clusterSpore.SpawnedAt
+			}
+
+			room.broadcast(&packets.Packet{
+				SenderId: 0,
+				Msg: packets.NewSporeRemoved(clusterId),
+			})
+		}
+
+		if totalMass == 0 {
+			continue
+		}
+
+		mergedSpore.X /= totalMass
+		mergedSpore.Y /= totalMass
+		mergedSpore.Radius = objects.MassToRadius(totalMass)
+		mergedId := room.SharedGameObjects.Spores.Add(mergedSpore)
+
+		room.broadcast(&packets.Packet{
+			SenderId: 0,
+			Msg: packets.NewSpore(mergedId, mergedSpore),
+		})
+	}
+}
+
+func (room *Room) shrinkZoneLoop(rate time.Duration) {
+	ticker := time.NewTicker(rate)
+	defer ticker.Stop()
+
+	zone := room.SharedGameObjects.Zone
+
+	for {
+		select {
+			case <-room.ctx.Done():
+				return
+			case <-ticker.C:
+		}
+
+		if room.hub.Config().Paused {
+			continue
+		}
+
+		zone.Shrink(room.hub.Config().ZoneShrinkRate * rate.Seconds(), room.hub.Config().ZoneMinRadius)
+
+		room.broadcast(&packets.Packet{
+			SenderId: 0,
+			Msg: packets.NewZone(zone),
+		})
+	}
+}
+
+// Let everyone know how busy the server is and who's on top
+func (room *Room) broadcastServerStatsLoop(rate time.Duration) {
+	ticker := time.NewTicker(rate)
+	defer ticker.Stop()
+
+	for {
+		select {
+			case <-room.ctx.Done():
+				return
+			case <-ticker.C:
+		}
+
+		playerCount := 0
+		var biggestPlayer *objects.Player
+
+		room.SharedGameObjects.Players.Range(func(_ uint64, player *objects.Player) {
+			playerCount++
+
+			if biggestPlayer == nil || player.Radius > biggestPlayer.Radius {
+				biggestPlayer = player
+			}
+		})
+
+		room.broadcast(&packets.Packet{
+			SenderId: 0,
+			Msg: packets.NewServerStats(playerCount, biggestPlayer),
+		})
+	}
+}
+
+// Let everyone know who the biggest players are
+func (room *Room) leaderboardLoop(rate time.Duration, topN int) {
+	ticker := time.NewTicker(rate)
+	defer ticker.Stop()
+
+	for {
+		select {
+			case <-room.ctx.Done():
+				return
+			case <-ticker.C:
+		}
+
+		entries := make([]*packets.LeaderboardEntry, 0, room.SharedGameObjects.Players.Len())
+
+		room.SharedGameObjects.Players.ForEach(func(playerId uint64, player *objects.Player) {
+			entries = append(entries, &packets.LeaderboardEntry{
+				PlayerId: playerId,
+				Name: player.Name,
+				Radius: player.Radius,
+			})
+		})
+
+		// No need to wake up idle clients when nobody's playing
+		if len(entries) == 0 {
+			continue
+		}
+
+		slices.SortFunc(entries, func(a, b *packets.LeaderboardEntry) int {
+			return cmp.Compare(b.Radius, a.Radius)
+		})
+
+		room.broadcast(&packets.Packet{
+			SenderId: 0,
+			Msg: packets.NewLeaderboard(entries[:min(topN, len(entries))]),
+		})
+	}
+}
+
+// Hand a packet to the run loop to broadcast, giving up if the room is torn down first
+func (room *Room) broadcast(packet *packets.Packet) {
+	select {
+		case room.BroadcastChan <- packet:
+		case <-room.ctx.Done():
+	}
+}
\ No newline at end of file
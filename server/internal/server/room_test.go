@@ -0,0 +1,494 @@
+package server
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"server/internal/server/objects"
+	"server/pkg/packets"
+	"slices"
+	"testing"
+	"time"
+)
+
+// How long to wait for a room's loops to get round to something before failing
+const testTimeout time.Duration = 5 * time.Second
+
+// A room of a hub with the given settings, which isn't run so only the loops the test starts do anything. Both are
+// torn down when the test ends
+func newTestRoom(t *testing.T, configure ...func(config *ServerConfig)) *Room {
+	config := NewServerConfig()
+
+	for _, configureFunc := range configure {
+		configureFunc(config)
+	}
+
+	hub := NewHub(config)
+	t.Cleanup(hub.cancel)
+
+	return newRoom(hub, DefaultRoomName)
+}
+
+// The next packet broadcast in the room, taken in place of the room's run loop
+func receiveBroadcast(t *testing.T, room *Room) *packets.Packet {
+	t.Helper()
+
+	select {
+		case packet := <-room.BroadcastChan:
+			return packet
+		case <-time.After(testTimeout):
+			t.Fatal("Timed out waiting for a broadcast")
+			return nil
+	}
+}
+
+// The zone must shrink by its shrink rate every interval, letting everyone know each time, until it reaches its
+// smallest size
+func TestZoneShrinks(t *testing.T) {
+	const startRadius float64 = 105
+	const minRadius float64 = 100
+	const interval time.Duration = 10 * time.Millisecond
+
+	room := newTestRoom(t, func(config *ServerConfig) {
+		config.ZoneShrinkRate = 200
+		config.ZoneMinRadius = minRadius
+	})
+	room.SharedGameObjects.Zone.SetRadius(startRadius)
+
+	go room.shrinkZoneLoop(interval)
+
+	for i := 1; i <= 4; i++ {
+		expected := max(startRadius - 2 * float64(i), minRadius)
+
+		if radius := receiveBroadcast(t, room).GetZone().GetRadius(); math.Abs(radius - expected) > 1e-9 {
+			t.Errorf("The zone's radius was %f after %d shrinks instead of %f", radius, i, expected)
+		}
+	}
+}
+
+// However many times spores are eaten and put back, no grid cell may end up with more spores than the cap, even when
+// the cap leaves less room than the spore count asks for
+func TestSporeCellCap(t *testing.T) {
+	const cycles int = 20
+	const maxPerCell int = 2
+
+	room := newTestRoom(t, func(config *ServerConfig) {
+		config.MaxSpores = 400
+		config.MaxSporesPerCell = maxPerCell
+		config.SporeCellSize = 500
+	})
+	spores := room.SharedGameObjects.Spores
+
+	for cycle := range cycles {
+		cellCounts := room.sporeCellCounts()
+
+		for spores.Len() < room.hub.Config().MaxSpores {
+			spore := room.newSpore(cellCounts)
+
+			if spore == nil {
+				break
+			}
+
+			spores.Add(spore)
+		}
+
+		for cell, count := range room.sporeCellCounts() {
+			if count > maxPerCell {
+				t.Fatalf("Cell %v has %d spores after %d replenish cycles, over the cap of %d", cell, count, cycle + 1, maxPerCell)
+			}
+		}
+
+		// Eat about half the spores before the next cycle
+		for _, sporeId := range slices.Collect(maps.Keys(spores.Snapshot())) {
+			if rand.IntN(2) == 0 {
+				spores.Remove(sporeId)
+			}
+		}
+	}
+}
+
+// Keeps the messages the room passes on to it. Nothing but the room's broadcast loop may use it
+type recordingClient struct {
+	ClientInterfacer
+	id uint64
+	received chan packets.Msg
+}
+
+func (client *recordingClient) Id() uint64 {
+	return client.id
+}
+
+func (client *recordingClient) ProcessMessage(_ uint64, message packets.Msg) {
+	client.received <- message
+}
+
+// A broadcast excluding some clients must reach everyone else in the room, but neither the excluded clients nor the
+// sender
+func TestBroadcastExcept(t *testing.T) {
+	room := newTestRoom(t, func(config *ServerConfig) { config.MaxSpores = 0 })
+	clients := make(map[uint64]*recordingClient)
+
+	for id := uint64(1); id <= 5; id++ {
+		clients[id] = &recordingClient{id: id, received: make(chan packets.Msg, 2)}
+		room.Clients.Add(clients[id], id)
+	}
+
+	go room.Run()
+
+	room.ExclusiveBroadcastChan <- &ExclusiveBroadcast{
+		Packet: &packets.Packet{SenderId: 1, Msg: packets.NewChat("Not for 2 or 3")},
+		ExcludedIds: []uint64{2, 3},
+	}
+	// Broadcasts are passed on in order, so whoever sees this first didn't get the exclusive one
+	room.BroadcastChan <- &packets.Packet{SenderId: 1, Msg: packets.NewChat("For everyone")}
+
+	for id := uint64(2); id <= 5; id++ {
+		excluded := id == 2 || id == 3
+		expected := "Not for 2 or 3"
+
+		if excluded {
+			expected = "For everyone"
+		}
+
+		select {
+			case message := <-clients[id].received:
+				if msg := message.(*packets.Packet_Chat).Chat.GetMsg(); msg != expected {
+					t.Errorf("Client %d (excluded: %v) first got %q instead of %q", id, excluded, msg, expected)
+				}
+			case <-time.After(testTimeout):
+				t.Fatalf("Timed out waiting for client %d to get a broadcast", id)
+		}
+	}
+
+	if len(clients[1].received) > 0 {
+		t.Error("The sender got its own broadcast")
+	}
+}
+
+// With smart replenishing, new spores must land mostly ahead of a cluster of players, where uniformly placed spores
+// rarely do
+func TestSmartReplenish(t *testing.T) {
+	const samples int = 500
+	const aheadX float64 = 1800
+	const aheadY float64 = 1500
+
+	nearShare := func(smart bool) float64 {
+		room := newTestRoom(t, func(config *ServerConfig) {
+			config.WorldBound = 3000
+			config.SmartReplenish = smart
+		})
+
+		for i := range 5 {
+			room.SharedGameObjects.Players.Add(&objects.Player{X: 1500, Y: 1400 + 50 * float64(i), Radius: 20}, uint64(i + 1))
+		}
+
+		near := 0
+
+		for range samples {
+			if spore := room.newSpore(nil); math.Hypot(spore.X - aheadX, spore.Y - aheadY) < 1000 {
+				near++
+			}
+		}
+
+		return float64(near) / float64(samples)
+	}
+
+	if share := nearShare(true); share < 0.9 {
+		t.Errorf("With smart replenishing only %.0f%% of spores landed ahead of the players", share * 100)
+	}
+
+	if share := nearShare(false); share > 0.2 {
+		t.Errorf("Without smart replenishing %.0f%% of spores landed ahead of the players", share * 100)
+	}
+}
+
+// A spore older than the spore lifetime must be taken out of the world and everyone told it's gone, leaving younger
+// spores alone
+func TestSporeExpiry(t *testing.T) {
+	room := newTestRoom(t, func(config *ServerConfig) { config.SporeLifetime = time.Minute })
+	spores := room.SharedGameObjects.Spores
+	oldId := spores.Add(&objects.Spore{Radius: 5, SpawnedAt: time.Now().Add(-2 * time.Minute)})
+	freshId := spores.Add(&objects.Spore{X: 100, Radius: 5, SpawnedAt: time.Now()})
+
+	go room.expireSporesLoop(10 * time.Millisecond)
+
+	if removedId := receiveBroadcast(t, room).GetSporeRemoved().GetSporeId(); removedId != oldId {
+		t.Errorf("Broadcast the removal of spore %d instead of the expired spore %d", removedId, oldId)
+	}
+
+	if _, left := spores.Get(oldId); left {
+		t.Error("The expired spore was left in the world")
+	}
+
+	if _, left := spores.Get(freshId); !left {
+		t.Error("A spore within its lifetime was taken out of the world")
+	}
+}
+
+// With the world's object budget nearly used up, replenishing must only fill what's left of it and count the spores it
+// held back, while the players already there stay
+func TestObjectBudget(t *testing.T) {
+	const budget int = 20
+	const players int = 15
+
+	room := newTestRoom(t, func(config *ServerConfig) {
+		config.MaxSpores = 100
+		config.MaxWorldObjects = budget
+	})
+
+	for i := range players {
+		room.SharedGameObjects.Players.Add(&objects.Player{X: 100 * float64(i), Radius: 20}, uint64(i + 1))
+	}
+
+	go room.replenishSporesLoop(10 * time.Millisecond)
+
+	for range budget - players {
+		if receiveBroadcast(t, room).GetSpore() == nil {
+			t.Fatal("Replenishing broadcast something other than a new spore")
+		}
+	}
+
+	deadline := time.Now().Add(testTimeout)
+
+	for room.hub.SpawnsThrottled.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if room.hub.SpawnsThrottled.Load() == 0 {
+		t.Error("Spores held back by the object budget weren't counted")
+	}
+
+	select {
+		case packet := <-room.BroadcastChan:
+			t.Errorf("Replenishing went past the object budget, broadcasting %v", packet)
+		case <-time.After(100 * time.Millisecond):
+	}
+
+	if spores, playing := room.SharedGameObjects.Spores.Len(), room.SharedGameObjects.Players.Len(); spores != budget - players || playing != players {
+		t.Errorf("A budget of %d objects left %d spores and %d players", budget, spores, playing)
+	}
+}
+
+// Cancelling the hub's context must stop every one of a room's background loops promptly
+func TestHubContextStopsLoops(t *testing.T) {
+	const rate time.Duration = 10 * time.Millisecond
+
+	room := newTestRoom(t)
+
+	loops := map[string]func(){
+		"replenish spores": func() { room.replenishSporesLoop(rate) },
+		"replenish hazards": func() { room.replenishHazardsLoop(rate) },
+		"expire spores": func() { room.expireSporesLoop(rate) },
+		"merge spores": func() { room.mergeSporesLoop(rate) },
+		"shrink zone": func() { room.shrinkZoneLoop(rate) },
+		"server stats": func() { room.broadcastServerStatsLoop(rate) },
+		"leaderboard": func() { room.leaderboardLoop(rate, 10) },
+	}
+	stopped := make(map[string]chan struct{}, len(loops))
+
+	for name, loop := range loops {
+		done := make(chan struct{})
+		stopped[name] = done
+
+		go func() {
+			defer close(done)
+			loop()
+		}()
+	}
+
+	// Let the loops get going, without anyone taking their broadcasts
+	time.Sleep(5 * rate)
+	room.hub.cancel()
+
+	for name, done := range stopped {
+		select {
+			case <-done:
+			case <-time.After(testTimeout):
+				t.Errorf("The %s loop kept running after the hub's context was cancelled", name)
+		}
+	}
+}
+
+// The server stats must count every player in the room and name the biggest one with their mass, and with nobody
+// playing report no players and no biggest player
+func TestServerStats(t *testing.T) {
+	room := newTestRoom(t)
+	go room.broadcastServerStatsLoop(10 * time.Millisecond)
+
+	if stats := receiveBroadcast(t, room).GetServerStats(); stats.GetPlayerCount() != 0 || stats.GetBiggestPlayerName() != "" || stats.GetBiggestPlayerMass() != 0 {
+		t.Errorf("An empty room's stats were %v", stats)
+	}
+
+	for i, radius := range []float64{20, 75, 40, 60} {
+		room.SharedGameObjects.Players.Add(&objects.Player{Name: fmt.Sprintf("player %d", i), Radius: radius}, uint64(i + 1))
+	}
+
+	// Stats worked out while the players were being added can still be on their way
+	stats := receiveBroadcast(t, room).GetServerStats()
+
+	for range 2 {
+		if stats.GetPlayerCount() != 4 {
+			stats = receiveBroadcast(t, room).GetServerStats()
+		}
+	}
+
+	if stats.GetPlayerCount() != 4 {
+		t.Errorf("A room of 4 players reported %d", stats.GetPlayerCount())
+	}
+
+	if stats.GetBiggestPlayerName() != "player 1" || stats.GetBiggestPlayerMass() != objects.RadiusToMass(75) {
+		t.Errorf("The biggest of 4 players was reported as %q with mass %f instead of %q with mass %f", stats.GetBiggestPlayerName(), stats.GetBiggestPlayerMass(), "player 1", objects.RadiusToMass(75))
+	}
+}
+
+// Two spores within the merge radius of each other must be replaced by one spore with their combined mass at their
+// centre of mass, with everyone told about both removals and the new spore, while a spore further away is left alone
+func TestSporeMerge(t *testing.T) {
+	room := newTestRoom(t)
+	spores := room.SharedGameObjects.Spores
+	firstId := spores.Add(&objects.Spore{X: 100, Y: 100, Radius: 6})
+	secondId := spores.Add(&objects.Spore{X: 108, Y: 100, Radius: 8})
+	loneId := spores.Add(&objects.Spore{X: 500, Y: 100, Radius: 8})
+	expectedMass := objects.RadiusToMass(6) + objects.RadiusToMass(8)
+
+	go room.mergeSpores(10)
+
+	removed := map[uint64]bool{}
+
+	for range 2 {
+		removed[receiveBroadcast(t, room).GetSporeRemoved().GetSporeId()] = true
+	}
+
+	if !removed[firstId] || !removed[secondId] {
+		t.Errorf("Merging broadcast the removal of spores %v instead of %d and %d", slices.Collect(maps.Keys(removed)), firstId, secondId)
+	}
+
+	mergedMessage := receiveBroadcast(t, room).GetSpore()
+	merged, found := spores.Get(mergedMessage.GetId())
+
+	if !found {
+		t.Fatalf("The merged spore %v broadcast isn't in the world", mergedMessage)
+	}
+
+	if mass := objects.RadiusToMass(merged.Radius); math.Abs(mass - expectedMass) > 1e-9 {
+		t.Errorf("The merged spore has mass %f instead of the %f of the two spores merged", mass, expectedMass)
+	}
+
+	// The bigger spore has 16/25 of the combined mass, so the centre of mass is that far along towards it
+	if expectedX := 100 + 8 * 16.0 / 25; math.Abs(merged.X - expectedX) > 1e-9 || merged.Y != 100 {
+		t.Errorf("The merged spore is at (%f, %f) instead of (%f, 100)", merged.X, merged.Y, expectedX)
+	}
+
+	if spores.Len() != 2 {
+		t.Errorf("Merging two of three spores left %d spores", spores.Len())
+	}
+
+	if _, left := spores.Get(loneId); !left {
+		t.Error("A spore too far from the others to merge was taken out of the world")
+	}
+}
+
+// The leaderboard must list only the biggest players, biggest first, and not be broadcast at all while nobody's playing
+func TestLeaderboard(t *testing.T) {
+	const topN int = 3
+
+	room := newTestRoom(t)
+	go room.leaderboardLoop(10 * time.Millisecond, topN)
+
+	select {
+		case packet := <-room.BroadcastChan:
+			t.Fatalf("A leaderboard was broadcast with nobody playing: %v", packet)
+		case <-time.After(100 * time.Millisecond):
+	}
+
+	for i, radius := range []float64{20, 75, 40, 60, 30} {
+		room.SharedGameObjects.Players.Add(&objects.Player{Name: fmt.Sprintf("player %d", i), Radius: radius}, uint64(i + 1))
+	}
+
+	// A leaderboard worked out while the players were being added can still be on its way
+	entries := receiveBroadcast(t, room).GetLeaderboard().GetEntries()
+
+	for range 2 {
+		if len(entries) != topN {
+			entries = receiveBroadcast(t, room).GetLeaderboard().GetEntries()
+		}
+	}
+
+	expectedIds := []uint64{2, 4, 3}
+	ids := make([]uint64, 0, len(entries))
+
+	for _, entry := range entries {
+		ids = append(ids, entry.GetPlayerId())
+	}
+
+	if !slices.Equal(ids, expectedIds) {
+		t.Fatalf("The leaderboard listed players %v instead of %v", ids, expectedIds)
+	}
+
+	if entries[0].GetName() != "player 1" || entries[0].GetRadius() != 75 {
+		t.Errorf("The top of the leaderboard was %v instead of player 1 with radius 75", entries[0])
+	}
+}
+
+// Rooms must be told apart by name, each with a world of its own, and no more may be made than allowed. A room nobody
+// is in must be torn down once it's been empty for the idle timeout, unless someone comes back first, while the default
+// room always stays
+func TestRooms(t *testing.T) {
+	const idleTimeout time.Duration = 50 * time.Millisecond
+
+	hub := newTestRoom(t, func(config *ServerConfig) {
+		config.MaxRooms = 3
+		config.RoomIdleTimeout = idleTimeout
+	}).hub
+	hasRoom := func(name string) bool {
+		hub.roomsMux.Lock()
+		defer hub.roomsMux.Unlock()
+
+		_, exists := hub.rooms[name]
+		return exists
+	}
+
+	lobby := hub.GetOrCreateRoom(DefaultRoomName)
+	arena := hub.GetOrCreateRoom("arena")
+	duel := hub.GetOrCreateRoom("duel")
+
+	if arena == lobby || arena.SharedGameObjects == lobby.SharedGameObjects || arena.Name != "arena" {
+		t.Fatal("Rooms with different names share a room or world")
+	}
+
+	if again := hub.GetOrCreateRoom("arena"); again != arena {
+		t.Error("Joining a room by name again gave a different room")
+	}
+
+	if extra := hub.GetOrCreateRoom("extra"); extra != nil {
+		t.Error("A room was made past the room limit")
+	}
+
+	// Someone comes back to the duel before it's torn down, while the arena's two members both leave
+	hub.LeaveRoom(duel)
+	hub.GetOrCreateRoom("duel")
+	hub.LeaveRoom(arena)
+	hub.LeaveRoom(arena)
+	hub.LeaveRoom(lobby)
+
+	select {
+		case <-arena.ctx.Done():
+		case <-time.After(testTimeout):
+			t.Fatal("An empty room wasn't torn down")
+	}
+
+	if hasRoom("arena") {
+		t.Error("A torn down room can still be joined")
+	}
+
+	time.Sleep(2 * idleTimeout)
+
+	if !hasRoom("duel") || duel.ctx.Err() != nil {
+		t.Error("A room somebody came back to was torn down")
+	}
+
+	if !hasRoom(DefaultRoomName) || lobby.ctx.Err() != nil {
+		t.Error("The default room was torn down when it emptied")
+	}
+}
\ No newline at end of file
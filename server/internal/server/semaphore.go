@@ -0,0 +1,34 @@
+package server
+
+// Limits how many holders can have a slot at once. A nil semaphore has unlimited slots
+type Semaphore chan struct{}
+
+// Creates a semaphore with the given number of slots, or an unlimited one if the size isn't positive
+func NewSemaphore(size int) Semaphore {
+	if size <= 0 {
+		return nil
+	}
+
+	return make(Semaphore, size)
+}
+
+// Take a slot if one is free, returning whether a slot was taken
+func (semaphore Semaphore) TryAcquire() bool {
+	if semaphore == nil {
+		return true
+	}
+
+	select {
+		case semaphore <- struct{}{}:
+			return true
+		default:
+			return false
+	}
+}
+
+// Give back a slot taken with TryAcquire
+func (semaphore Semaphore) Release() {
+	if semaphore != nil {
+		<-semaphore
+	}
+}
\ No newline at end of file
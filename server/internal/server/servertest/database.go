@@ -0,0 +1,37 @@
+package servertest
+
+import (
+	"context"
+	"database/sql"
+	"server/internal/server"
+	"server/internal/server/db"
+)
+
+// A fresh database kept in memory with the server's tables in it, and a transaction for fake clients to use it through.
+// Every connection to an in-memory database gets a database of its own, so it's kept to the one connection. Close the
+// transaction's match writer before the database, to save the matches still queued
+func NewDatabase(registrationSlots int) (*sql.DB, *server.DbTransaction, error) {
+	dbPool, err := sql.Open("sqlite", ":memory:")
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dbPool.SetMaxOpenConns(1)
+
+	if err := server.InitDatabase(context.Background(), dbPool); err != nil {
+		dbPool.Close()
+		return nil, nil, err
+	}
+
+	queries := db.New(dbPool)
+
+	dbTx := &server.DbTransaction{
+		Ctx: context.Background(),
+		Queries: queries,
+		RegistrationSlots: server.NewSemaphore(registrationSlots),
+		Matches: server.NewMatchWriter(queries),
+	}
+
+	return dbPool, dbTx, nil
+}
\ No newline at end of file
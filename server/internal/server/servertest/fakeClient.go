@@ -0,0 +1,259 @@
+package servertest
+
+import (
+	"context"
+	"log/slog"
+	"server/internal/server"
+	"server/internal/server/objects"
+	"server/pkg/packets"
+	"sync"
+)
+
+// A client with no connection behind it, for driving state handlers without a websocket. It's in a room of its own
+// which is never run, shared only with the peers made from it, everything it sends is recorded instead, and its
+// background tasks run straight away so their effects can be checked as soon as a message is handled. It has no
+// database unless it's given one, so states which use one can't be entered or left with it until then
+type FakeClient struct {
+	id uint64
+	state server.ClientStateHandler
+	room *server.Room
+	config *server.ServerConfig
+	sessions *server.SessionStore
+	eventLog *server.EventLog
+	logger *slog.Logger
+	dbTx *server.DbTransaction
+	ip string
+
+	ctx context.Context
+	cancel context.CancelFunc
+
+	mux sync.Mutex
+
+	// Packets sent to the client's socket, and those it broadcast to other clients
+	sent []*packets.Packet
+	broadcasts []*packets.Packet
+
+	closeReason string
+}
+
+func NewFakeClient(id uint64, config *server.ServerConfig) *FakeClient {
+	clients := objects.NewSharedCollection[server.ClientInterfacer]()
+
+	room := &server.Room{
+		Name: server.DefaultRoomName,
+		Clients: clients,
+		SharedGameObjects: server.NewSharedGameObjects(config.MaxSpores, server.NewKeyCounter()),
+		Simulation: server.NewSimulation(clients),
+	}
+
+	return newFakeClientIn(room, id, config, server.NewSessionStore(), nil)
+}
+
+func newFakeClientIn(room *server.Room, id uint64, config *server.ServerConfig, sessions *server.SessionStore, dbTx *server.DbTransaction) *FakeClient {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	client := &FakeClient{
+		id: id,
+		room: room,
+		config: config,
+		sessions: sessions,
+		eventLog: server.NewEventLog(config.EventLogSize),
+		logger: slog.Default().With("client_id", id),
+		dbTx: dbTx,
+		ip: "127.0.0.1",
+		ctx: ctx,
+		cancel: cancel,
+	}
+
+	room.Clients.Add(client, id)
+
+	return client
+}
+
+// Another fake client in the same room as this one, sharing its settings, sessions and database
+func (client *FakeClient) NewPeer(id uint64) *FakeClient {
+	return newFakeClientIn(client.room, id, client.config, client.sessions, client.dbTx)
+}
+
+// Have the client connect from the given address instead of localhost
+func (client *FakeClient) SetIp(ip string) {
+	client.ip = ip
+}
+
+// Give the client a database to use, e.g. one from NewDatabase. Only peers made afterwards share it
+func (client *FakeClient) SetDbTransaction(dbTx *server.DbTransaction) {
+	client.dbTx = dbTx
+}
+
+// The packets sent to the client's socket so far
+func (client *FakeClient) Sent() []*packets.Packet {
+	client.mux.Lock()
+	defer client.mux.Unlock()
+
+	return append([]*packets.Packet(nil), client.sent...)
+}
+
+// The packets the client has broadcast to the others in its room so far
+func (client *FakeClient) Broadcasts() []*packets.Packet {
+	client.mux.Lock()
+	defer client.mux.Unlock()
+
+	return append([]*packets.Packet(nil), client.broadcasts...)
+}
+
+// Forget the packets recorded so far
+func (client *FakeClient) ClearRecorded() {
+	client.mux.Lock()
+	defer client.mux.Unlock()
+
+	client.sent = nil
+	client.broadcasts = nil
+}
+
+// Why the client was closed, or empty if it hasn't been
+func (client *FakeClient) CloseReason() string {
+	client.mux.Lock()
+	defer client.mux.Unlock()
+
+	return client.closeReason
+}
+
+// Send the logs of the client and its states to the given handler instead of the default one, e.g. to check what was
+// logged. Only states set afterwards pick it up
+func (client *FakeClient) SetLogHandler(handler slog.Handler) {
+	client.logger = slog.New(handler).With("client_id", client.id)
+}
+
+func (client *FakeClient) Id() uint64 {
+	return client.id
+}
+
+func (client *FakeClient) ProcessMessage(senderId uint64, message packets.Msg) {
+	if client.state != nil {
+		client.state.HandleMessage(senderId, message)
+	}
+}
+
+// The room stands in for the hub, so only the client's peers can have the ID already
+func (client *FakeClient) ClaimId(id uint64) bool {
+	if id == client.id {
+		return true
+	}
+
+	if !client.room.Clients.Move(client.id, id) {
+		return false
+	}
+
+	client.id = id
+	client.logger = slog.Default().With("client_id", id)
+
+	return true
+}
+
+func (client *FakeClient) Initialize(id uint64) {
+	client.id = id
+	client.logger = slog.Default().With("client_id", id)
+}
+
+func (client *FakeClient) SetState(newState server.ClientStateHandler) {
+	if client.state != nil {
+		client.state.OnExit()
+	}
+
+	client.state = newState
+
+	if newState != nil {
+		newState.SetClient(client)
+		newState.OnEnter()
+	}
+}
+
+// Hand the client's messages to the given state without entering it, for states set up by hand
+func (client *FakeClient) SetStateWithoutEntering(state server.ClientStateHandler) {
+	client.state = state
+	state.SetClient(client)
+}
+
+func (client *FakeClient) SocketSend(message packets.Msg) {
+	client.SocketSendAs(message, client.id)
+}
+
+func (client *FakeClient) SocketSendAs(message packets.Msg, senderId uint64) {
+	client.mux.Lock()
+	defer client.mux.Unlock()
+
+	client.sent = append(client.sent, &packets.Packet{SenderId: senderId, Msg: message})
+}
+
+func (client *FakeClient) RunAsync(task func()) {
+	task()
+}
+
+func (client *FakeClient) PassToPeer(message packets.Msg, peerId uint64) {
+	if peer, exists := client.room.Clients.Get(peerId); exists {
+		peer.ProcessMessage(client.id, message)
+	}
+}
+
+func (client *FakeClient) Broadcast(message packets.Msg) {
+	client.mux.Lock()
+	defer client.mux.Unlock()
+
+	client.broadcasts = append(client.broadcasts, &packets.Packet{SenderId: client.id, Msg: message})
+}
+
+func (client *FakeClient) BroadcastExcept(message packets.Msg, _ ...uint64) {
+	client.Broadcast(message)
+}
+
+func (client *FakeClient) ReadPump() {}
+
+func (client *FakeClient) WritePump() {}
+
+func (client *FakeClient) DbTransaction() *server.DbTransaction {
+	return client.dbTx
+}
+
+func (client *FakeClient) Room() *server.Room {
+	return client.room
+}
+
+func (client *FakeClient) Sessions() *server.SessionStore {
+	return client.sessions
+}
+
+func (client *FakeClient) SharedGameObjects() *server.SharedGameObjects {
+	return client.room.SharedGameObjects
+}
+
+func (client *FakeClient) Config() *server.ServerConfig {
+	return client.config
+}
+
+func (client *FakeClient) EventLog() *server.EventLog {
+	return client.eventLog
+}
+
+func (client *FakeClient) Logger() *slog.Logger {
+	return client.logger
+}
+
+func (client *FakeClient) Context() context.Context {
+	return client.ctx
+}
+
+func (client *FakeClient) Ip() string {
+	return client.ip
+}
+
+func (client *FakeClient) SentTraffic() *server.TrafficCounter {
+	return nil
+}
+
+func (client *FakeClient) Close(reason string) {
+	client.mux.Lock()
+	client.closeReason = reason
+	client.mux.Unlock()
+
+	client.cancel()
+}
\ No newline at end of file
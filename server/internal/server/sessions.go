@@ -0,0 +1,169 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"server/internal/server/objects"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSessionInvalid = errors.New("invalid session token")
+	ErrSessionExpired = errors.New("session expired")
+	ErrSessionInUse = errors.New("already playing elsewhere")
+	ErrSessionOtherRoom = errors.New("session belongs to another room")
+)
+
+// A player who dropped out of the game, held on to for a while so they can resume where they left off
+type HeldSession struct {
+	UserId int64
+	RoomName string
+	Player *objects.Player
+	expiresAt time.Time
+}
+
+// Issues the tokens players resume their sessions with, and holds on to the sessions of players who dropped out
+type SessionStore struct {
+	// Signs the tokens, so forged ones are turned away without looking anything up. Made fresh for every run of the
+	// server, since held sessions don't outlive it anyway
+	secret []byte
+
+	mux sync.Mutex
+	held map[string]*HeldSession
+
+	// How many lives each user has in the game right now
+	online map[int64]int
+}
+
+func NewSessionStore() *SessionStore {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+
+	return &SessionStore{
+		secret: secret,
+		held: make(map[string]*HeldSession),
+		online: make(map[int64]int),
+	}
+}
+
+// Make a new token for the user, in the form "<user ID>.<nonce>.<signature>"
+func (store *SessionStore) Issue(userId int64) string {
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+
+	payload := fmt.Sprintf("%d.%s", userId, base64.RawURLEncoding.EncodeToString(nonce))
+
+	return payload + "." + store.sign(payload)
+}
+
+func (store *SessionStore) sign(payload string) string {
+	mac := hmac.New(sha256.New, store.secret)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Check the token's signature, returning the user it was issued to
+func (store *SessionStore) verify(token string) (int64, bool) {
+	parts := strings.Split(token, ".")
+
+	if len(parts) != 3 || !hmac.Equal([]byte(parts[2]), []byte(store.sign(parts[0] + "." + parts[1]))) {
+		return 0, false
+	}
+
+	userId, err := strconv.ParseInt(parts[0], 10, 64)
+
+	return userId, err == nil
+}
+
+// Hold on to a session for the given time, after which it can no longer be resumed
+func (store *SessionStore) Hold(token string, session *HeldSession, window time.Duration) {
+	store.mux.Lock()
+	defer store.mux.Unlock()
+
+	// Forget sessions nobody came back for, so they don't pile up
+	now := time.Now()
+
+	for heldToken, heldSession := range store.held {
+		if now.After(heldSession.expiresAt) {
+			delete(store.held, heldToken)
+		}
+	}
+
+	session.expiresAt = now.Add(window)
+	store.held[token] = session
+}
+
+// Take the session held for the token, if it can be resumed in the given room. A session can only be resumed once
+func (store *SessionStore) Resume(token string, roomName string) (*HeldSession, error) {
+	userId, ok := store.verify(token)
+
+	if !ok {
+		return nil, ErrSessionInvalid
+	}
+
+	store.mux.Lock()
+	defer store.mux.Unlock()
+
+	session, exists := store.held[token]
+
+	if !exists || time.Now().After(session.expiresAt) {
+		delete(store.held, token)
+		return nil, ErrSessionExpired
+	}
+
+	if session.UserId != userId {
+		return nil, ErrSessionInvalid
+	}
+
+	if store.online[userId] > 0 {
+		return nil, ErrSessionInUse
+	}
+
+	if session.RoomName != roomName {
+		return nil, ErrSessionOtherRoom
+	}
+
+	delete(store.held, token)
+
+	return session, nil
+}
+
+// Forget any session held for the player, so it can't be resumed
+func (store *SessionStore) Revoke(player *objects.Player) {
+	store.mux.Lock()
+	defer store.mux.Unlock()
+
+	for token, session := range store.held {
+		if session.Player == player {
+			delete(store.held, token)
+		}
+	}
+}
+
+// Count a life of the user's starting in the game
+func (store *SessionStore) Join(userId int64) {
+	store.mux.Lock()
+	defer store.mux.Unlock()
+
+	store.online[userId]++
+}
+
+// Count a life of the user's ending
+func (store *SessionStore) Leave(userId int64) {
+	store.mux.Lock()
+	defer store.mux.Unlock()
+
+	store.online[userId]--
+
+	if store.online[userId] <= 0 {
+		delete(store.online, userId)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"errors"
+	"server/internal/server/objects"
+	"testing"
+	"time"
+)
+
+// A held session must be resumable once, in its own room, with the player it was held with
+func TestSessionResume(t *testing.T) {
+	store := NewSessionStore()
+	token := store.Issue(7)
+	player := &objects.Player{Name: "resumer", Radius: 80}
+
+	store.Hold(token, &HeldSession{UserId: 7, RoomName: "arena", Player: player}, time.Minute)
+
+	if _, err := store.Resume(token, "other"); !errors.Is(err, ErrSessionOtherRoom) {
+		t.Errorf("Resuming in another room returned %v instead of %v", err, ErrSessionOtherRoom)
+	}
+
+	session, err := store.Resume(token, "arena")
+
+	if err != nil {
+		t.Fatalf("Resuming a held session failed: %v", err)
+	}
+
+	if session.Player != player || session.UserId != 7 {
+		t.Errorf("Resuming returned %+v instead of the session that was held", session)
+	}
+
+	if _, err := store.Resume(token, "arena"); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("Resuming a session a second time returned %v instead of %v", err, ErrSessionExpired)
+	}
+}
+
+// Sessions must not be resumable with forged tokens, after their window is up, or while the user is still playing
+func TestSessionResumeRefused(t *testing.T) {
+	store := NewSessionStore()
+
+	token := store.Issue(7)
+	store.Hold(token, &HeldSession{UserId: 7, RoomName: "arena"}, time.Minute)
+
+	// A token signed by another run of the server
+	forged := NewSessionStore().Issue(7)
+	store.Hold(forged, &HeldSession{UserId: 7, RoomName: "arena"}, time.Minute)
+
+	for _, bad := range []string{forged, "7.nonce.signature", "garbage", ""} {
+		if _, err := store.Resume(bad, "arena"); !errors.Is(err, ErrSessionInvalid) {
+			t.Errorf("Resuming with token %q returned %v instead of %v", bad, err, ErrSessionInvalid)
+		}
+	}
+
+	store.Join(7)
+
+	if _, err := store.Resume(token, "arena"); !errors.Is(err, ErrSessionInUse) {
+		t.Errorf("Resuming while still playing returned %v instead of %v", err, ErrSessionInUse)
+	}
+
+	store.Leave(7)
+
+	expired := store.Issue(8)
+	store.Hold(expired, &HeldSession{UserId: 8, RoomName: "arena"}, -time.Second)
+
+	if _, err := store.Resume(expired, "arena"); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("Resuming after the window returned %v instead of %v", err, ErrSessionExpired)
+	}
+
+	revoked := &objects.Player{Name: "revoked"}
+	revokedToken := store.Issue(9)
+	store.Hold(revokedToken, &HeldSession{UserId: 9, RoomName: "arena", Player: revoked}, time.Minute)
+	store.Revoke(revoked)
+
+	if _, err := store.Resume(revokedToken, "arena"); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("Resuming a revoked session returned %v instead of %v", err, ErrSessionExpired)
+	}
+
+	if _, err := store.Resume(token, "arena"); err != nil {
+		t.Errorf("Resuming after the user left the game failed: %v", err)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"runtime/debug"
+	"server/internal/server/objects"
+	"server/pkg/packets"
+	"slices"
+	"time"
+)
+
+// How often a room's simulation ticks, matching the rate of the players' own update loops
+const TickInterval = 5 * time.Millisecond
+
+// How far players move each tick, as a fraction of their speed
+const TickDelta float64 = 0.05
+
+// A player a room's simulation moves on every tick
+type Simulated interface {
+	// Move on by one tick, returning the player to pass on to the room's clients, or nil if there's nothing to pass on
+	Step(delta float64, tickTime time.Time) *objects.Player
+}
+
+// Advances every player in a room together on one fixed tick, then passes the players who moved on to the room's
+// clients in one batch, so players all move in step rather than on tickers of their own
+type Simulation struct {
+	// Keyed by the ID of the client each participant belongs to
+	participants *objects.SharedCollection[Simulated]
+	clients *objects.SharedCollection[ClientInterfacer]
+}
+
+// A simulation passing its updates on to the given clients
+func NewSimulation(clients *objects.SharedCollection[ClientInterfacer]) *Simulation {
+	return &Simulation{
+		participants: objects.NewSharedCollection[Simulated](),
+		clients: clients,
+	}
+}
+
+// Step the participant on every tick from now on, under the ID of the client it belongs to
+func (sim *Simulation) Join(id uint64, participant Simulated) {
+	sim.participants.Add(participant, id)
+}
+
+func (sim *Simulation) Leave(id uint64) {
+	sim.participants.Remove(id)
+}
+
+func (sim *Simulation) Len() int {
+	return sim.participants.Len()
+}
+
+// Tick until the context is cancelled, skipping ticks while paused
+func (sim *Simulation) Run(ctx context.Context, rate time.Duration, paused func() bool, onTick func(players int)) {
+	ticker := time.NewTicker(rate)
+	defer ticker.Stop()
+
+	for {
+		select {
+			case <-ctx.Done():
+				return
+			case tickTime := <-ticker.C:
+				if !paused() {
+					onTick(sim.Tick(TickDelta, tickTime))
+				}
+		}
+	}
+}
+
+// Step every participant once, in order of ID, then pass the players they return on to every client in the room as one
+// batch from the server. Each client gets the same batch, with its own player in it. Returns how many players were
+// passed on
+func (sim *Simulation) Tick(delta float64, tickTime time.Time) int {
+	participants := make(map[uint64]Simulated, sim.participants.Len())
+	sim.participants.ForEach(func(id uint64, participant Simulated) {
+		participants[id] = participant
+	})
+
+	players := make(map[uint64]*objects.Player, len(participants))
+
+	for _, id := range slices.Sorted(maps.Keys(participants)) {
+		if player := sim.step(id, participants[id], delta, tickTime); player != nil {
+			players[id] = player
+		}
+	}
+
+	if len(players) == 0 {
+		return 0
+	}
+
+	batch := packets.NewPlayersBatch(players)
+
+	sim.clients.ForEach(func(_ uint64, client ClientInterfacer) {
+		client.ProcessMessage(0, batch)
+	})
+
+	return len(players)
+}
+
+// A participant which panics is dropped and its client closed, rather than taking the whole room's simulation down
+func (sim *Simulation) step(id uint64, participant Simulated, delta float64, tickTime time.Time) (player *objects.Player) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			slog.Error("Simulation step panicked, dropping the participant", "client_id", id, "panic", recovered, "stack", string(debug.Stack()))
+			sim.Leave(id)
+			player = nil
+
+			if client, exists := sim.clients.Get(id); exists {
+				go client.Close(CloseReasonUpdateLoopFailed)
+			}
+		}
+	}()
+
+	return participant.Step(delta, tickTime)
+}
\ No newline at end of file
@@ -0,0 +1,165 @@
+package states
+
+import (
+	"fmt"
+	"math"
+	"server/internal/server"
+	"server/internal/server/objects"
+	"server/internal/server/servertest"
+	"server/pkg/packets"
+	"testing"
+	"time"
+)
+
+// Numbers of players to time a tick with
+var benchmarkPlayerCounts = []int{10, 100, 500}
+
+// Time one tick of moving players and passing each one's update on to the others, with each player moving on their own
+// goroutine and broadcasting through the room, as they do on their own update loops
+func BenchmarkPerPlayerTicks(b *testing.B) {
+	for _, playerCount := range benchmarkPlayerCounts {
+		b.Run(fmt.Sprintf("%d players", playerCount), func(b *testing.B) { benchmarkPerPlayerTicks(b, playerCount) })
+	}
+}
+
+// Time one tick of the room's simulation moving every player in one pass and sending them out in one batch
+func BenchmarkSimulationTicks(b *testing.B) {
+	for _, playerCount := range benchmarkPlayerCounts {
+		b.Run(fmt.Sprintf("%d players", playerCount), func(b *testing.B) { benchmarkSimulationTicks(b, playerCount) })
+	}
+}
+
+// Players spread over a grid and heading in different directions, all in one room of fake clients
+func newBenchmarkGames(playerCount int) ([]*InGame, []*servertest.FakeClient) {
+	const spacing float64 = 100
+
+	config := server.NewServerConfig()
+	config.DriftStrength = 0
+	config.SporeSyncRadius = 0
+
+	games := make([]*InGame, 0, playerCount)
+	clients := make([]*servertest.FakeClient, 0, playerCount)
+	columns := int(math.Ceil(math.Sqrt(float64(playerCount))))
+	var firstClient *servertest.FakeClient
+
+	for i := range playerCount {
+		player := &objects.Player{
+			Name: "bench",
+			X: float64(i % columns) * spacing,
+			Y: float64(i / columns) * spacing,
+			Radius: config.PlayerStartRadius,
+			Direction: float64(i),
+		}
+
+		var game *InGame
+		var client *servertest.FakeClient
+
+		if firstClient == nil {
+			client = servertest.NewFakeClient(1, config)
+			game = newTestGameOn(client, player)
+			firstClient = client
+		} else {
+			game, client = newTestPeer(firstClient, uint64(i + 1), player)
+		}
+
+		game.capabilities = []string{packets.CapabilityPlayersBatch}
+		games = append(games, game)
+		clients = append(clients, client)
+	}
+
+	return games, clients
+}
+
+func clearRecorded(clients []*servertest.FakeClient) {
+	for _, client := range clients {
+		client.ClearRecorded()
+	}
+}
+
+// Each player waits for a tick of their own and hands their update to a goroutine standing in for the room, which
+// passes it on to everyone else
+func benchmarkPerPlayerTicks(b *testing.B, playerCount int) {
+	games, clients := newBenchmarkGames(playerCount)
+	room := clients[0].Room()
+
+	tickChans := make([]chan time.Time, len(games))
+	broadcastChan := make(chan *packets.Packet)
+	tickDone := make(chan struct{})
+
+	for i, game := range games {
+		tickChans[i] = make(chan time.Time, 1)
+
+		go func() {
+			for tickTime := range tickChans[i] {
+				game.movePlayer(server.TickDelta, tickTime)
+				updatePacket := packets.NewPlayer(game.client.Id(), game.player)
+				game.client.SocketSend(updatePacket)
+				broadcastChan <- &packets.Packet{SenderId: game.client.Id(), Msg: updatePacket}
+			}
+		}()
+	}
+
+	go func() {
+		for received := 1; ; received++ {
+			packet, open := <-broadcastChan
+
+			if !open {
+				return
+			}
+
+			room.Clients.ForEach(func(clientId uint64, client server.ClientInterfacer) {
+				if clientId != packet.SenderId {
+					client.ProcessMessage(packet.SenderId, packet.Msg)
+				}
+			})
+
+			if received % len(games) == 0 {
+				tickDone <- struct{}{}
+			}
+		}
+	}()
+
+	b.ReportAllocs()
+	tickTime := time.Now()
+
+	for b.Loop() {
+		tickTime = tickTime.Add(server.TickInterval)
+
+		for _, tickChan := range tickChans {
+			tickChan <- tickTime
+		}
+
+		<-tickDone
+
+		b.StopTimer()
+		clearRecorded(clients)
+		b.StartTimer()
+	}
+
+	for _, tickChan := range tickChans {
+		close(tickChan)
+	}
+
+	close(broadcastChan)
+}
+
+func benchmarkSimulationTicks(b *testing.B, playerCount int) {
+	games, clients := newBenchmarkGames(playerCount)
+	simulation := clients[0].Room().Simulation
+
+	for _, game := range games {
+		simulation.Join(game.client.Id(), game)
+	}
+
+	b.ReportAllocs()
+	tickTime := time.Now()
+
+	for b.Loop() {
+		tickTime = tickTime.Add(server.TickInterval)
+		simulation.Tick(server.TickDelta, tickTime)
+
+		b.StopTimer()
+		clearRecorded(clients)
+		b.StartTimer()
+	}
+}
\ No newline at end of file
@@ -0,0 +1,37 @@
+package states
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Longest chat message passed on, in characters
+const maxChatLength int = 128
+
+// Clean up a chat message so it can't break other clients' rendering. Control characters are removed, any run of
+// whitespace becomes a single space and overly long messages are cut short. Returns an error if nothing is left
+func sanitizeChat(msg string) (string, error) {
+	var builder strings.Builder
+
+	for _, char := range msg {
+		switch {
+			case unicode.IsSpace(char):
+				builder.WriteRune(' ')
+			case unicode.IsPrint(char):
+				builder.WriteRune(char)
+		}
+	}
+
+	msg = strings.Join(strings.Fields(builder.String()), " ")
+
+	if msg == "" {
+		return "", errors.New("empty")
+	}
+
+	if runes := []rune(msg); len(runes) > maxChatLength {
+		msg = strings.TrimSpace(string(runes[:maxChatLength]))
+	}
+
+	return msg, nil
+}
\ No newline at end of file
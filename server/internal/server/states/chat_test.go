@@ -0,0 +1,51 @@
+package states
+
+import (
+	"strings"
+	"testing"
+)
+
+// Control characters must be stripped and whitespace collapsed, without touching printable characters
+func TestSanitizeChat(t *testing.T) {
+	cases := map[string]string{
+		"Hello": "Hello",
+		"  Hello\n\nthere\t ": "Hello there",
+		"Hel\x00lo\x1b[31m": "Hello[31m",
+		"Olá, 世界": "Olá, 世界",
+		"a\r\nb​c": "a bc",
+	}
+
+	for msg, expected := range cases {
+		sanitized, err := sanitizeChat(msg)
+
+		if err != nil {
+			t.Errorf("Sanitizing %q failed: %v", msg, err)
+		} else if sanitized != expected {
+			t.Errorf("Sanitizing %q gave %q instead of %q", msg, sanitized, expected)
+		}
+	}
+}
+
+// Messages with nothing printable in them must be rejected, and over-long ones cut short
+func TestSanitizeChatRejectsAndTruncates(t *testing.T) {
+	for _, msg := range []string{"", "   ", "\n\t", "\x00\x07\x1b"} {
+		if sanitized, err := sanitizeChat(msg); err == nil {
+			t.Errorf("Sanitizing %q gave %q instead of an error", msg, sanitized)
+		}
+	}
+
+	sanitized, err := sanitizeChat(strings.Repeat("é", 500))
+
+	if err != nil {
+		t.Fatalf("Sanitizing an over-long message failed: %v", err)
+	}
+
+	if length := len([]rune(sanitized)); length != maxChatLength {
+		t.Errorf("Sanitizing a 500 character message left %d characters instead of %d", length, maxChatLength)
+	}
+
+	// Cutting a message short mustn't leave it ending in a space
+	if sanitized, _ := sanitizeChat(strings.Repeat("a", maxChatLength - 1) + " b"); strings.HasSuffix(sanitized, " ") {
+		t.Errorf("Sanitizing a message cut short at a space gave %q", sanitized)
+	}
+}
\ No newline at end of file
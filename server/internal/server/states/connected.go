@@ -2,15 +2,17 @@ package states
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"server/internal/server"
 	"server/internal/server/db"
 	"server/internal/server/objects"
 	"server/pkg/packets"
+	"slices"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -19,9 +21,31 @@ import (
 
 type Connected struct {
 	client server.ClientInterfacer
-	logger *log.Logger
+	logger *slog.Logger
 	queries *db.Queries
 	dbCtx context.Context
+
+	// The optional protocol features the client asked for
+	capabilities []string
+}
+
+// The optional protocol features offered to clients
+func (connected *Connected) supportedCapabilities() []string {
+	capabilities := []string{
+		packets.CapabilityFlatSporesBatch,
+		packets.CapabilityRequestWorld,
+	}
+
+	if !connected.client.Config().ChatDisabled {
+		capabilities = append(capabilities, packets.CapabilityChat)
+	}
+
+	// Players are only batched when the room's simulation moves them all together
+	if connected.client.Config().SharedTick {
+		capabilities = append(capabilities, packets.CapabilityPlayersBatch)
+	}
+
+	return capabilities
 }
 
 func (connected *Connected) Name() string {
@@ -30,14 +54,17 @@ func (connected *Connected) Name() string {
 
 func (connected *Connected) SetClient(client server.ClientInterfacer) {
 	connected.client = client
-	loggingPrefix := fmt.Sprintf("Client %d [%s]: ", client.Id(), connected.Name())
-	connected.logger = log.New(log.Writer(), loggingPrefix, log.LstdFlags)
+	connected.logger = client.Logger().With("state", connected.Name())
 	connected.queries = client.DbTransaction().Queries
 	connected.dbCtx = client.DbTransaction().Ctx
 }
 
 func (connected *Connected) OnEnter() {
 	connected.client.SocketSend(packets.NewId(connected.client.Id()))
+
+	config := connected.client.Config()
+	connected.client.SocketSend(packets.NewServerInfo(config.ServerName, config.Motd))
+	connected.client.SocketSend(packets.NewCapabilities(connected.supportedCapabilities()))
 }
 
 func (connected *Connected) HandleMessage(senderId uint64, message packets.Msg) {
@@ -46,6 +73,12 @@ func (connected *Connected) HandleMessage(senderId uint64, message packets.Msg)
 			connected.handleLoginRequest(senderId, message)
 		case *packets.Packet_RegisterRequest:
 			connected.handleRegisterRequest(senderId, message)
+		case *packets.Packet_ResumeRequest:
+			connected.handleResumeRequest(senderId, message)
+		case *packets.Packet_Capabilities:
+			connected.handleCapabilities(senderId, message)
+		case *packets.Packet_SpectateRequest:
+			connected.handleSpectateRequest(senderId, message)
 	}
 }
 
@@ -58,8 +91,20 @@ func (connected *Connected) handleLoginRequest(senderId uint64, message *packets
 		return
 	}
 
+	if connected.client.Config().Maintenance {
+		connected.client.SocketSend(packets.NewDenyResponse("The server is under maintenance, please try again later"))
+		return
+	}
+
 	username := message.LoginRequest.Username
 	password := message.LoginRequest.Password
+
+	// No password longer than bcrypt takes could have been registered, so there's no point hashing one
+	if connected.credentialsTooLong(username, password) || len(password) > maxPasswordLength() {
+		connected.client.SocketSend(packets.NewDenyResponse("Username or password too long"))
+		return
+	}
+
 	genericFailMessage := packets.NewDenyResponse("Incorrect username or password")
 
 	user, err := connected.queries.GetUserByUsername(connected.dbCtx, strings.ToLower(username))
@@ -86,16 +131,140 @@ func (connected *Connected) handleLoginRequest(senderId uint64, message *packets
 		return
 	}
 
-	connected.logger.Printf("User %s logged in successfully!", username)
+	if denial, banned := connected.checkBan(username); banned {
+		connected.client.SocketSend(packets.NewDenyResponse(denial))
+		return
+	}
+
+	if !connected.client.SharedGameObjects().PlayersPerIp.Acquire(connected.client.Ip(), connected.client.Config().MaxPlayersPerIp) {
+		connected.logger.Warn("Too many players from one IP address, refusing login", "ip", connected.client.Ip(), "username", username)
+		connected.client.SocketSend(packets.NewDenyResponse("Too many players are already playing from your network"))
+		return
+	}
+
+	if !connected.claimUserId(user.ID) {
+		connected.client.SharedGameObjects().PlayersPerIp.Release(connected.client.Ip())
+		connected.client.SocketSend(packets.NewDenyResponse("This account is already playing"))
+		return
+	}
+
+	connected.logger.Info("User logged in successfully", "username", username)
 	connected.client.SocketSend(packets.NewOkResponse())
 
+	// The token lets the player pick up where they left off if their connection drops
+	sessionToken := ""
+
+	if connected.client.Config().ResumeWindow > 0 {
+		sessionToken = connected.client.Sessions().Issue(user.ID)
+		connected.client.SocketSend(packets.NewSessionToken(sessionToken))
+	}
+
 	connected.client.SetState(&InGame{
+		userId: user.ID,
+		capabilities: connected.capabilities,
+		sessionToken: sessionToken,
 		player: &objects.Player{
 			Name: username,
 		},
 	})
 }
 
+// Put a player who dropped out back into the game as they were, if they came back soon enough
+func (connected *Connected) handleResumeRequest(senderId uint64, message *packets.Packet_ResumeRequest) {
+	if senderId != connected.client.Id() {
+		return
+	}
+
+	if connected.client.Config().Maintenance {
+		connected.client.SocketSend(packets.NewDenyResponse("The server is under maintenance, please try again later"))
+		return
+	}
+
+	if connected.client.Config().ResumeWindow <= 0 {
+		connected.client.SocketSend(packets.NewDenyResponse("Resuming sessions is disabled on this server"))
+		return
+	}
+
+	token := message.ResumeRequest.Token
+
+	if connected.credentialsTooLong(token) {
+		connected.client.SocketSend(packets.NewDenyResponse("Session token too long"))
+		return
+	}
+
+	if !connected.client.SharedGameObjects().PlayersPerIp.Acquire(connected.client.Ip(), connected.client.Config().MaxPlayersPerIp) {
+		connected.client.SocketSend(packets.NewDenyResponse("Too many players are already playing from your network"))
+		return
+	}
+
+	session, err := connected.client.Sessions().Resume(token, connected.client.Room().Name)
+
+	if err != nil {
+		connected.client.SharedGameObjects().PlayersPerIp.Release(connected.client.Ip())
+		connected.client.SocketSend(packets.NewDenyResponse(fmt.Sprintf("Could not resume session: %v", err)))
+		return
+	}
+
+	if denial, banned := connected.checkBan(session.Player.Name); banned {
+		connected.client.SharedGameObjects().PlayersPerIp.Release(connected.client.Ip())
+		connected.client.SocketSend(packets.NewDenyResponse(denial))
+		return
+	}
+
+	if !connected.claimUserId(session.UserId) {
+		connected.client.SharedGameObjects().PlayersPerIp.Release(connected.client.Ip())
+		connected.client.SocketSend(packets.NewDenyResponse("This account is already playing"))
+		return
+	}
+
+	connected.logger.Info("User resumed their session", "username", session.Player.Name)
+	connected.client.SocketSend(packets.NewOkResponse())
+
+	connected.client.SetState(&InGame{
+		userId: session.UserId,
+		capabilities: connected.capabilities,
+		sessionToken: token,
+		resumed: true,
+		player: session.Player,
+	})
+}
+
+// Watching the game doesn't need an account. Spectators can log in to join the game whenever they like
+func (connected *Connected) handleSpectateRequest(senderId uint64, _ *packets.Packet_SpectateRequest) {
+	if senderId != connected.client.Id() {
+		return
+	}
+
+	if connected.client.Config().Maintenance {
+		connected.client.SocketSend(packets.NewDenyResponse("The server is under maintenance, please try again later"))
+		return
+	}
+
+	connected.client.SocketSend(packets.NewOkResponse())
+	connected.client.SetState(&Spectating{
+		capabilities: connected.capabilities,
+	})
+}
+
+// The client replies to our capabilities with the ones it wants to use. Clients which don't reply get the original
+// protocol
+func (connected *Connected) handleCapabilities(senderId uint64, message *packets.Packet_Capabilities) {
+	if senderId != connected.client.Id() {
+		return
+	}
+
+	supportedCapabilities := connected.supportedCapabilities()
+	connected.capabilities = make([]string, 0, len(supportedCapabilities))
+
+	for _, capability := range message.Capabilities.Capabilities {
+		if slices.Contains(supportedCapabilities, capability) {
+			connected.capabilities = append(connected.capabilities, capability)
+		}
+	}
+
+	connected.logger.Info("Client capabilities", "capabilities", connected.capabilities)
+}
+
 func (connected *Connected) handleRegisterRequest(senderId uint64, message *packets.Packet_RegisterRequest) {
 	if senderId != connected.client.Id() {
 		return
@@ -105,6 +274,11 @@ func (connected *Connected) handleRegisterRequest(senderId uint64, message *pack
 	password := message.RegisterRequest.Password
 	passwordConfirmation := message.RegisterRequest.PasswordConfirmation
 
+	if connected.credentialsTooLong(username, password, passwordConfirmation) {
+		connected.client.SocketSend(packets.NewDenyResponse("Username or password too long"))
+		return
+	}
+
 	err := validateUserName(username)
 
 	if err != nil {
@@ -121,6 +295,16 @@ func (connected *Connected) handleRegisterRequest(senderId uint64, message *pack
 		return
 	}
 
+	// Shed registrations during a signup spike rather than letting them pile up on the database
+	registrationSlots := connected.client.DbTransaction().RegistrationSlots
+
+	if !registrationSlots.TryAcquire() {
+		connected.client.SocketSend(packets.NewDenyResponse("The server is busy, please try again"))
+		return
+	}
+
+	defer registrationSlots.Release()
+
 	if _, err := connected.queries.GetUserByUsername(connected.dbCtx, strings.ToLower(username)); err == nil {
 		connected.client.SocketSend(packets.NewDenyResponse("User already exists"))
 		return
@@ -142,8 +326,6 @@ func (connected *Connected) handleRegisterRequest(senderId uint64, message *pack
 	passwordWithPepper := password + pepper
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(passwordWithPepper), 12)
 
-	connected.logger.Println(pepper)
-
 	if err != nil {
 		connected.client.SocketSend(genericFailMessage)
 		return
@@ -159,10 +341,75 @@ func (connected *Connected) handleRegisterRequest(senderId uint64, message *pack
 		return
 	}
 
-	connected.logger.Printf("User %s registered successfully!", username)
+	connected.logger.Info("User registered successfully", "username", username)
 	connected.client.SocketSend(packets.NewOkResponse())
 }
 
+// Players are known by their account's user ID, so they keep the same ID whenever they play. Only one client can have
+// it at a time, so this fails if the account is already playing. The client is told its new ID if it changed
+func (connected *Connected) claimUserId(userId int64) bool {
+	previousId := connected.client.Id()
+
+	if !connected.client.ClaimId(uint64(userId)) {
+		return false
+	}
+
+	if connected.client.Id() != previousId {
+		connected.logger = connected.client.Logger().With("state", connected.Name())
+		connected.client.SocketSend(packets.NewId(connected.client.Id()))
+	}
+
+	return true
+}
+
+// Whether the user is banned, and the message to turn them away with if so. Users whose ban can't be looked up are
+// turned away too
+func (connected *Connected) checkBan(username string) (string, bool) {
+	ban, err := connected.queries.GetBan(connected.dbCtx, strings.ToLower(username))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false
+	}
+
+	if err != nil {
+		connected.logger.Error("Error checking ban", "username", username, "err", err)
+		return "Could not check your account (internal server error) - please try again later", true
+	}
+
+	connected.logger.Info("Banned user tried to join", "username", username)
+
+	if ban.Reason == "" {
+		return "You are banned from this server", true
+	}
+
+	return "You are banned from this server: " + ban.Reason, true
+}
+
+// Most bytes bcrypt will hash, which passwords share with the pepper added to them
+const bcryptMaxBytes int = 72
+
+// Longer passwords can't be hashed with the pepper on the end. The pepper is loaded from .env when the server starts
+func maxPasswordLength() int {
+	return bcryptMaxBytes - len(os.Getenv("PEPPER"))
+}
+
+// Checked before anything else is done with the credentials, so huge ones can't be used to waste time hashing them
+func (connected *Connected) credentialsTooLong(credentials ...string) bool {
+	maxLength := connected.client.Config().MaxCredentialLength
+
+	if maxLength <= 0 {
+		return false
+	}
+
+	for _, credential := range credentials {
+		if len(credential) > maxLength {
+			return true
+		}
+	}
+
+	return false
+}
+
 func validateUserName(username string) error {
 	if len(username) <= 0 {
 		return errors.New("empty")
@@ -188,5 +435,9 @@ func validatePassword(password string, passwordConfirmation string) error {
 		return errors.New("too short")
 	}
 
+	if len(password) > maxPasswordLength() {
+		return fmt.Errorf("too long, at most %d bytes", maxPasswordLength())
+	}
+
 	return nil
 }
\ No newline at end of file
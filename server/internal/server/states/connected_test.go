@@ -0,0 +1,341 @@
+package states
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"server/internal/server"
+	"server/internal/server/db"
+	"server/internal/server/objects"
+	"server/internal/server/servertest"
+	"server/pkg/packets"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPepper string = "pepper"
+
+// A fake client which has just connected, with a database of its own to log in against. Login reads the pepper from a
+// .env file in the working directory, so the test is moved to an empty directory with one
+func newTestConnected(t *testing.T, configure ...func(config *server.ServerConfig)) (*servertest.FakeClient, *db.Queries) {
+	t.Setenv("PEPPER", testPepper)
+	t.Chdir(t.TempDir())
+
+	if err := os.WriteFile(".env", nil, 0o600); err != nil {
+		t.Fatalf("Couldn't write the .env file: %v", err)
+	}
+
+	config := server.NewServerConfig()
+
+	for _, configureFunc := range configure {
+		configureFunc(config)
+	}
+
+	dbPool, dbTx, err := servertest.NewDatabase(config.MaxConcurrentRegistrations)
+
+	if err != nil {
+		t.Fatalf("Couldn't open a database: %v", err)
+	}
+
+	t.Cleanup(func() { dbPool.Close() })
+	t.Cleanup(dbTx.Matches.Close)
+
+	client := servertest.NewFakeClient(server.FirstConnectionId, config)
+	client.SetDbTransaction(dbTx)
+	client.SetState(&Connected{})
+	client.ClearRecorded()
+	t.Cleanup(func() { client.Close("") })
+
+	return client, dbTx.Queries
+}
+
+// Store an account the way registering does, only with the cheapest hash to keep the tests quick
+func createTestUser(t *testing.T, queries *db.Queries, username string, password string) db.User {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password + testPepper), bcrypt.MinCost)
+
+	if err != nil {
+		t.Fatalf("Couldn't hash the password: %v", err)
+	}
+
+	user, err := queries.CreateUser(t.Context(), db.CreateUserParams{Username: username, Password: string(hash)})
+
+	if err != nil {
+		t.Fatalf("Couldn't create user %s: %v", username, err)
+	}
+
+	return user
+}
+
+func loginRequest(username string, password string) *packets.Packet_LoginRequest {
+	return &packets.Packet_LoginRequest{LoginRequest: &packets.LoginRequestMessage{Username: username, Password: password}}
+}
+
+// Whether the client was sent a packet with the message the getter picks out
+func wasSent[T any](client *servertest.FakeClient, getMessage func(*packets.Packet) *T) bool {
+	for _, packet := range client.Sent() {
+		if getMessage(packet) != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The last packet sent to the client, or nil if it wasn't sent anything
+func lastSent(client *servertest.FakeClient) *packets.Packet {
+	sent := client.Sent()
+
+	if len(sent) == 0 {
+		return nil
+	}
+
+	return sent[len(sent) - 1]
+}
+
+// Logging in with the right password must take the account's ID and put the player in the game under their name
+func TestLogin(t *testing.T) {
+	client, queries := newTestConnected(t)
+	user := createTestUser(t, queries, "tester", "secret")
+
+	client.ProcessMessage(client.Id(), loginRequest("Tester", "secret"))
+
+	if !wasSent(client, (*packets.Packet).GetOkResponse) {
+		t.Fatalf("Logging in with the right password was answered with %v", client.Sent())
+	}
+
+	if client.Id() != uint64(user.ID) {
+		t.Errorf("Logging in left the client with ID %d instead of the account's %d", client.Id(), user.ID)
+	}
+
+	if player, playing := client.SharedGameObjects().Players.Get(client.Id()); !playing || player.Name != "Tester" {
+		t.Errorf("Logging in didn't put the player in the game under their name, got %v", player)
+	}
+}
+
+func TestLoginWrongPassword(t *testing.T) {
+	client, queries := newTestConnected(t)
+	createTestUser(t, queries, "tester", "secret")
+
+	client.ProcessMessage(client.Id(), loginRequest("tester", "guess"))
+
+	if packet := lastSent(client); packet.GetDenyResponse() == nil || len(client.Sent()) != 1 {
+		t.Errorf("Logging in with the wrong password was answered with %v", client.Sent())
+	}
+
+	if client.Id() != server.FirstConnectionId || client.SharedGameObjects().Players.Len() != 0 {
+		t.Error("Logging in with the wrong password put the player in the game")
+	}
+}
+
+// A newly connected client must be told the configured server name and message of the day
+func TestConnectedSendsServerInfo(t *testing.T) {
+	client, _ := newTestConnected(t, func(config *server.ServerConfig) {
+		config.ServerName = "Test server"
+		config.Motd = "Be nice"
+	})
+	// The helper forgets what was sent on connecting, so connect again
+	client.SetState(&Connected{})
+
+	for _, packet := range client.Sent() {
+		if info := packet.GetServerInfo(); info != nil {
+			if info.Name != "Test server" || info.Motd != "Be nice" {
+				t.Errorf("A new client was sent server name %q and MOTD %q", info.Name, info.Motd)
+			}
+
+			return
+		}
+	}
+
+	t.Errorf("A new client was sent %v without the server info", client.Sent())
+}
+
+// During maintenance, logging in must be refused with the reason, while players already in the game carry on playing and
+// chatting
+func TestMaintenance(t *testing.T) {
+	client, queries := newTestConnected(t, func(config *server.ServerConfig) { config.Maintenance = true })
+	createTestUser(t, queries, "tester", "secret")
+	player := &objects.Player{Name: "playing", Radius: 20}
+	game, peer := newTestPeer(client, 2, player)
+
+	client.ProcessMessage(client.Id(), loginRequest("tester", "secret"))
+
+	if reason := lastSent(client).GetDenyResponse().GetReason(); !strings.Contains(reason, "maintenance") {
+		t.Errorf("Logging in during maintenance was answered with %v instead of the maintenance reason", client.Sent())
+	}
+
+	if _, playing := client.SharedGameObjects().Players.Get(client.Id()); playing {
+		t.Error("Logging in during maintenance put the player in the game")
+	}
+
+	sporeId := client.SharedGameObjects().Spores.Add(&objects.Spore{X: 10, Radius: 5})
+	game.HandleMessage(peer.Id(), &packets.Packet_SporeConsumed{SporeConsumed: &packets.SporeConsumedMessage{SporeId: sporeId}})
+	game.HandleMessage(peer.Id(), &packets.Packet_Chat{Chat: &packets.ChatMessage{Msg: "Still here"}})
+
+	if player.SporesEaten != 1 || len(peer.Broadcasts()) != 2 {
+		t.Errorf("A player already in the game during maintenance ate %d spores and broadcast %v", player.SporesEaten, peer.Broadcasts())
+	}
+}
+
+func registerRequest(username string, password string) *packets.Packet_RegisterRequest {
+	return &packets.Packet_RegisterRequest{RegisterRequest: &packets.RegisterRequestMessage{
+		Username: username,
+		Password: password,
+		PasswordConfirmation: password,
+	}}
+}
+
+// A burst of registrations while every registration slot is taken must all be turned away with a busy message rather
+// than waiting on the database, and once a slot frees up registering must get past the limit again
+func TestRegistrationShed(t *testing.T) {
+	const slots int = 2
+	const burst int = 20
+
+	client, _ := newTestConnected(t, func(config *server.ServerConfig) { config.MaxConcurrentRegistrations = slots })
+	registrationSlots := client.DbTransaction().RegistrationSlots
+
+	for range slots {
+		registrationSlots.TryAcquire()
+	}
+
+	var done sync.WaitGroup
+	peers := make([]*servertest.FakeClient, burst)
+
+	for i := range peers {
+		peers[i] = client.NewPeer(server.FirstConnectionId + uint64(i) + 1)
+		peers[i].SetState(&Connected{})
+		peers[i].ClearRecorded()
+
+		done.Go(func() {
+			peers[i].ProcessMessage(peers[i].Id(), registerRequest(fmt.Sprintf("user%d", i), "password"))
+		})
+	}
+
+	done.Wait()
+
+	for _, peer := range peers {
+		if reason := lastSent(peer).GetDenyResponse().GetReason(); !strings.Contains(reason, "busy") {
+			t.Fatalf("A registration with every slot taken was answered with %v instead of being turned away as busy", peer.Sent())
+		}
+	}
+
+	registrationSlots.Release()
+	client.ProcessMessage(client.Id(), registerRequest("latecomer", "password"))
+
+	if reason := lastSent(client).GetDenyResponse().GetReason(); strings.Contains(reason, "busy") {
+		t.Error("A registration after a slot freed up was still turned away as busy")
+	}
+}
+
+// With a cap of two players per IP, a third player logging in from the same IP must be refused, while a player from
+// another IP gets in
+func TestPlayersPerIpCap(t *testing.T) {
+	client, queries := newTestConnected(t, func(config *server.ServerConfig) { config.MaxPlayersPerIp = 2 })
+	logins := []struct {
+		username string
+		ip string
+		allowed bool
+	}{
+		{"first", "10.0.0.1", true},
+		{"second", "10.0.0.1", true},
+		{"third", "10.0.0.1", false},
+		{"elsewhere", "10.0.0.2", true},
+	}
+
+	for i, login := range logins {
+		createTestUser(t, queries, login.username, "secret")
+		peer := client.NewPeer(server.FirstConnectionId + uint64(i) + 1)
+		peer.SetIp(login.ip)
+		peer.SetState(&Connected{})
+		peer.ClearRecorded()
+
+		peer.ProcessMessage(peer.Id(), loginRequest(login.username, "secret"))
+
+		if allowed := wasSent(peer, (*packets.Packet).GetOkResponse); allowed != login.allowed {
+			t.Errorf("Logging in as %s from %s was answered with %v", login.username, login.ip, peer.Sent())
+		}
+	}
+}
+
+// Passwords which won't fit into bcrypt with the pepper on the end, and credentials past the configured length, must be
+// turned away before the registration gets anywhere near hashing them. Every registration slot is taken, so a password
+// which got past the checks would be turned away as busy instead
+func TestOversizedPasswordRejected(t *testing.T) {
+	client, queries := newTestConnected(t, func(config *server.ServerConfig) {
+		config.MaxConcurrentRegistrations = 1
+		config.MaxCredentialLength = 256
+	})
+	client.DbTransaction().RegistrationSlots.TryAcquire()
+	longest := 72 - len(testPepper)
+
+	registrations := []struct {
+		password string
+		reason string
+	}{
+		{strings.Repeat("a", longest + 1), "Invalid password: too long"},
+		{strings.Repeat("a", 1000), "Username or password too long"},
+		{strings.Repeat("a", longest), "busy"},
+	}
+
+	for _, registration := range registrations {
+		client.ClearRecorded()
+		client.ProcessMessage(client.Id(), registerRequest("newcomer", registration.password))
+
+		if reason := lastSent(client).GetDenyResponse().GetReason(); !strings.Contains(reason, registration.reason) {
+			t.Errorf("Registering with a %d byte password was answered with %q instead of %q", len(registration.password), reason, registration.reason)
+		}
+	}
+
+	if _, err := queries.GetUserByUsername(t.Context(), "newcomer"); err == nil {
+		t.Error("A user was registered with an oversized password")
+	}
+
+	client.ClearRecorded()
+	client.ProcessMessage(client.Id(), loginRequest("newcomer", strings.Repeat("a", longest + 1)))
+
+	if reason := lastSent(client).GetDenyResponse().GetReason(); reason != "Username or password too long" {
+		t.Errorf("Logging in with a password too long for bcrypt was answered with %q", reason)
+	}
+}
+
+// A player resuming their session must come back near where they dropped out when that spot is clear, and somewhere
+// clear of the player who has since moved in when it isn't, keeping their size either way
+func TestResumeNearLastPosition(t *testing.T) {
+	const lastX float64 = 1200
+	const lastY float64 = -800
+	const radius float64 = 40
+	const squatterRadius float64 = 100
+
+	for _, blocked := range []bool{false, true} {
+		t.Run(fmt.Sprintf("blocked %t", blocked), func(t *testing.T) {
+			client, _ := newTestConnected(t, func(config *server.ServerConfig) { config.ResumeWindow = time.Minute })
+			t.Cleanup(func() { client.Close("") })
+			player := &objects.Player{Name: "returning", X: lastX, Y: lastY, Radius: radius}
+
+			if blocked {
+				client.SharedGameObjects().Players.Add(&objects.Player{Name: "squatter", X: lastX, Y: lastY, Radius: squatterRadius}, 99)
+			}
+
+			token := client.Sessions().Issue(7)
+			client.Sessions().Hold(token, &server.HeldSession{UserId: 7, RoomName: client.Room().Name, Player: player}, time.Minute)
+			client.ProcessMessage(client.Id(), packets.NewResumeRequest(token))
+
+			if resumed, playing := client.SharedGameObjects().Players.Get(client.Id()); !playing || resumed != player || player.Radius != radius {
+				t.Fatalf("Resuming the session left %v in the game instead of the player with radius %f, after sending %v", resumed, radius, client.Sent())
+			}
+
+			distance := math.Hypot(player.X - lastX, player.Y - lastY)
+
+			if !blocked && distance > radius * math.Sqrt2 {
+				t.Errorf("With the spot clear, the player resumed %f away from where they dropped out", distance)
+			}
+
+			if blocked && distance < radius + squatterRadius {
+				t.Errorf("With the spot taken, the player resumed %f away from where they dropped out, overlapping the player there", distance)
+			}
+		})
+	}
+}
\ No newline at end of file
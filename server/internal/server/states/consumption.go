@@ -0,0 +1,87 @@
+package states
+
+import (
+	"fmt"
+	"server/internal/server"
+	"server/internal/server/objects"
+)
+
+// How far past touching a player and an object can be and still count as touching, to allow for latency
+const consumeBuffer float64 = 10
+
+// Work out whether the consumer can consume the target player under the given settings, and the consumer's new radius
+// if so. This only does the math, leaving the players and the game untouched
+func resolveConsumption(consumer *objects.Player, target *objects.Player, config *server.ServerConfig) (float64, bool, error) {
+	// A player with no size, e.g. one half set up, isn't there to be consumed
+	if !(target.Radius > 0) {
+		return 0, false, fmt.Errorf("other player has an invalid radius (%f)", target.Radius)
+	}
+
+	if !canConsume(radiusToMass(consumer.Radius), radiusToMass(target.Radius), config.ConsumeRatio) {
+		return 0, false, fmt.Errorf("player not massive enough to consume the other player (our radius: %f, other radius: %f)", consumer.Radius, target.Radius)
+	}
+
+	var err error
+
+	if config.ConsumeMode == server.ConsumeModeEngulf {
+		err = validateEngulfsObject(consumer, target.X, target.Y, config.EngulfFraction)
+	} else {
+		err = validateCloseToObject(consumer, target.X, target.Y, target.Radius, consumeBuffer)
+	}
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	return nextRadius(consumer.Radius, target.Radius), true, nil
+}
+
+// Whether a player with the consumer mass is massive enough to consume one with the target mass. They need to be more
+// than the ratio times as massive, so at exactly the ratio they can't
+func canConsume(consumerMass float64, targetMass float64, ratio float64) bool {
+	return targetMass > 0 && consumerMass > targetMass * ratio
+}
+
+// The radius of a player after consuming something of the given radius, which adds its mass to theirs
+func nextRadius(radius float64, consumedRadius float64) float64 {
+	return massToRadius(radiusToMass(radius) + radiusToMass(max(consumedRadius, 0)))
+}
+
+// Work out whether the consumer can consume the spore, and the consumer's new radius if so. Any player can consume a
+// spore they're touching, gaining the mass its type makes it worth
+func resolveSporeConsumption(consumer *objects.Player, spore *objects.Spore) (float64, bool, error) {
+	if err := validateCloseToObject(consumer, spore.X, spore.Y, spore.Radius, consumeBuffer); err != nil {
+		return 0, false, err
+	}
+
+	return massToRadius(radiusToMass(consumer.Radius) + max(spore.Mass(), 0)), true, nil
+}
+
+func validateCloseToObject(player *objects.Player, objX, objY, objRadius, buffer float64) error {
+	realDX := player.X - objX
+	realDY := player.Y - objY
+	realDistSq := realDX * realDX + realDY * realDY
+	thresholdDist := player.Radius + buffer + objRadius
+	thresholdDistSq := thresholdDist * thresholdDist
+
+	if realDistSq > thresholdDistSq {
+		return fmt.Errorf("Player is too far from the object (distSq: %f, thresholdSq: %f)", realDistSq, thresholdDistSq)
+	}
+
+	return nil
+}
+
+// Check the object's center is inside the player, at least the given fraction of the player's radius past its edge
+func validateEngulfsObject(player *objects.Player, objX, objY, fraction float64) error {
+	realDX := player.X - objX
+	realDY := player.Y - objY
+	realDistSq := realDX * realDX + realDY * realDY
+	thresholdDist := player.Radius * (1 - fraction)
+	thresholdDistSq := thresholdDist * thresholdDist
+
+	if realDistSq > thresholdDistSq {
+		return fmt.Errorf("Player has not engulfed the object (distSq: %f, thresholdSq: %f)", realDistSq, thresholdDistSq)
+	}
+
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,174 @@
+package states
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"server/internal/server"
+	"server/internal/server/objects"
+	"strings"
+	"testing"
+)
+
+// Being exactly the consume ratio times as massive isn't enough, and nothing can be consumed with no mass
+func TestConsumeRatioBoundary(t *testing.T) {
+	const ratio float64 = 1.5
+
+	if canConsume(150, 100, ratio) {
+		t.Error("A player exactly 1.5 times as massive could consume the other")
+	}
+
+	if !canConsume(math.Nextafter(150, math.Inf(1)), 100, ratio) {
+		t.Error("A player just over 1.5 times as massive couldn't consume the other")
+	}
+
+	if canConsume(100, 0, ratio) || canConsume(100, -10, ratio) {
+		t.Error("A player could consume another with no mass")
+	}
+
+	if grown := nextRadius(20, 20); math.Abs(grown - 20 * math.Sqrt2) > 1e-9 {
+		t.Errorf("Consuming a player of the same size gave radius %f", grown)
+	}
+}
+
+// A player touching a smaller one without covering its center can consume it on contact, but not when it has to be
+// engulfed
+func TestConsumeMode(t *testing.T) {
+	consumer := &objects.Player{Radius: 50}
+	// Overlapping the consumer by half its radius, with its center well outside
+	target := &objects.Player{X: 60, Radius: 20}
+
+	for _, mode := range []struct {
+		name string
+		fraction float64
+		consumed bool
+	}{
+		{server.ConsumeModeContact, 0, true},
+		{server.ConsumeModeEngulf, 0, false},
+		{server.ConsumeModeEngulf, 0.5, false},
+	} {
+		config := server.NewServerConfig()
+		config.ConsumeMode = mode.name
+		config.EngulfFraction = mode.fraction
+
+		if _, consumed, err := resolveConsumption(consumer, target, config); consumed != mode.consumed {
+			t.Errorf("In %s mode with fraction %f, consuming a touching but not engulfed player gave %v instead of %v (%v)", mode.name, mode.fraction, consumed, mode.consumed, err)
+		}
+	}
+
+	engulfed := &objects.Player{X: 20, Radius: 20}
+
+	for _, fraction := range []float64{0, 0.5} {
+		config := server.NewServerConfig()
+		config.ConsumeMode = server.ConsumeModeEngulf
+		config.EngulfFraction = fraction
+
+		if _, consumed, err := resolveConsumption(consumer, engulfed, config); !consumed {
+			t.Errorf("In engulf mode with fraction %f, a player with its center well inside wasn't consumed: %v", fraction, err)
+		}
+	}
+}
+
+// Raising the consume ratio through the admin endpoint must change whether a consumption goes through from then on, and
+// only for requests carrying the admin token
+func TestConfigPatchChangesConsumption(t *testing.T) {
+	config := server.NewServerConfig()
+	config.AdminToken = "secret"
+	config.ConsumeRatio = 1.5
+	hub := server.NewHub(config)
+	handler := hub.AdminOnly(hub.HandleConfig)
+
+	// Twice as massive as the target
+	consumer := &objects.Player{Radius: 20 * math.Sqrt2}
+	target := &objects.Player{X: 20, Radius: 20}
+
+	if _, consumed, err := resolveConsumption(consumer, target, hub.Config()); !consumed {
+		t.Fatalf("A player twice as massive couldn't consume another at ratio 1.5: %v", err)
+	}
+
+	patch := func(token string) int {
+		request := httptest.NewRequest(http.MethodPatch, "/admin/config", strings.NewReader(`{"consume_ratio": 3}`))
+		request.Header.Set("Authorization", "Bearer " + token)
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+
+		return recorder.Code
+	}
+
+	if status := patch("guess"); status != http.StatusUnauthorized || hub.Config().ConsumeRatio != 1.5 {
+		t.Errorf("Patching the settings without the admin token was answered with %d and left a consume ratio of %f", status, hub.Config().ConsumeRatio)
+	}
+
+	if status := patch("secret"); status != http.StatusOK {
+		t.Fatalf("Patching the consume ratio was answered with %d", status)
+	}
+
+	if _, consumed, _ := resolveConsumption(consumer, target, hub.Config()); consumed {
+		t.Error("A player twice as massive could still consume another after raising the consume ratio to 3")
+	}
+}
+
+// resolveConsumption over the mass ratios, distances and radii at the edges of its rules. The consume ratio is 4, so a
+// player with twice the radius is exactly at it
+func TestResolveConsumption(t *testing.T) {
+	// Just further than the given distance
+	past := func(dist float64) float64 { return math.Nextafter(dist, math.Inf(1)) }
+	// The furthest apart a radius 30 consumer and radius 10 target can be and still touch, counting the buffer
+	const contactDist float64 = 30 + consumeBuffer + 10
+
+	cases := []struct {
+		name string
+		mode string
+		engulfFraction float64
+		consumerRadius float64
+		targetX float64
+		targetRadius float64
+		consumed bool
+	}{
+		{"much smaller and touching", server.ConsumeModeContact, 0, 30, 20, 10, true},
+		{"exactly at the ratio", server.ConsumeModeContact, 0, 20, 0, 10, false},
+		{"just under the ratio", server.ConsumeModeContact, 0, 20, 0, 10 * (1 - 1e-9), true},
+		{"the same size", server.ConsumeModeContact, 0, 30, 0, 30, false},
+		{"bigger", server.ConsumeModeContact, 0, 30, 0, 40, false},
+		{"at the edge of contact", server.ConsumeModeContact, 0, 30, contactDist, 10, true},
+		{"just past contact", server.ConsumeModeContact, 0, 30, past(contactDist), 10, false},
+		{"far away", server.ConsumeModeContact, 0, 30, 1000, 10, false},
+		{"with no radius", server.ConsumeModeContact, 0, 30, 0, 0, false},
+		{"with a negative radius", server.ConsumeModeContact, 0, 30, 0, -10, false},
+		{"with a NaN radius", server.ConsumeModeContact, 0, 30, 0, math.NaN(), false},
+		{"by a consumer with no radius", server.ConsumeModeContact, 0, 0, 0, 10, false},
+		{"center at the consumer's edge", server.ConsumeModeEngulf, 0, 30, 30, 10, true},
+		{"center just outside the consumer", server.ConsumeModeEngulf, 0, 30, past(30), 10, false},
+		{"center at the engulf fraction", server.ConsumeModeEngulf, 0.5, 30, 15, 10, true},
+		{"center just short of the engulf fraction", server.ConsumeModeEngulf, 0.5, 30, past(15), 10, false},
+		{"engulfed but too big", server.ConsumeModeEngulf, 0, 30, 0, 30, false},
+	}
+
+	for _, testCase := range cases {
+		config := server.NewServerConfig()
+		config.ConsumeRatio = 4
+		config.ConsumeMode = testCase.mode
+		config.EngulfFraction = testCase.engulfFraction
+		consumer := &objects.Player{Radius: testCase.consumerRadius}
+		target := &objects.Player{X: testCase.targetX, Radius: testCase.targetRadius}
+
+		newRadius, consumed, err := resolveConsumption(consumer, target, config)
+
+		if consumed != testCase.consumed {
+			t.Errorf("Consuming a player %s in %s mode gave %v instead of %v (%v)", testCase.name, testCase.mode, consumed, testCase.consumed, err)
+			continue
+		}
+
+		if consumed != (err == nil) {
+			t.Errorf("Consuming a player %s gave %v with error %v", testCase.name, consumed, err)
+		}
+
+		if expected := nextRadius(testCase.consumerRadius, testCase.targetRadius); consumed && math.Abs(newRadius - expected) > 1e-9 {
+			t.Errorf("Consuming a player %s gave radius %f instead of %f", testCase.name, newRadius, expected)
+		}
+
+		if consumer.Radius != testCase.consumerRadius || target.Radius != testCase.targetRadius && !math.IsNaN(testCase.targetRadius) {
+			t.Errorf("Consuming a player %s changed the players' radii", testCase.name)
+		}
+	}
+}
\ No newline at end of file
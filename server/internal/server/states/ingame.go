@@ -3,19 +3,94 @@ package states
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
+	"runtime/debug"
 	"server/internal/server"
+	"server/internal/server/db"
 	"server/internal/server/objects"
 	"server/pkg/packets"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Reasons a life in the game can end, recorded in the match history
+const (
+	matchEndLeft = "left"
+	matchEndConsumed = "consumed"
+)
+
+// A direction change from the client and when it arrived
+type bufferedDirection struct {
+	direction float64
+	receivedAt time.Time
+}
+
+// What was last sent about a player, to tell whether they've changed enough since to be worth sending again
+type playerSnapshot struct {
+	x float64
+	y float64
+	radius float64
+	direction float64
+	speed float64
+	sentAt time.Time
+}
+
+// The server is the only authority on where players are. Clients only send the direction they want to go in, and
+// positions are never read from them but worked out in movePlayer. Whatever directions are sent, a player's own movement
+// each tick is never more than their speed times the tick's length, and their position is never NaN or infinite
 type InGame struct {
 	client server.ClientInterfacer
+	room *server.Room
 	player *objects.Player
-	logger *log.Logger
+	logger *slog.Logger
 	cancelPlayerUpdateLoop context.CancelFunc
+
+	// The ID of the user account playing, for the match history
+	userId int64
+
+	// The optional protocol features the client asked for
+	capabilities []string
+	worldSent atomic.Bool
+
+	// Held on to when the player drops out, so they can resume with it. Resumed players come back as they were
+	sessionToken string
+	resumed bool
+
+	// Kept across respawns, so dying doesn't reset the chat rate limit
+	chatLimiter *server.TokenBucket
+
+	// Fraction of the usual starting mass to start with, if not all of it
+	startMassScale float64
+	joinedAt time.Time
+	peakMass float64
+	endReason string
+
+	lastDirectionChange time.Time
+
+	// Disconnects the client if it goes quiet for too long, so it doesn't hold on to its place in the game forever
+	idleTimer *time.Timer
+	lastPlayerConsumed time.Time
+
+	// Direction changes waiting for the next tick when inputs are buffered, and when the last tick was
+	bufferedDirections []bufferedDirection
+	bufferedDirectionsMux sync.Mutex
+	lastTickTime time.Time
+
+	// When the player's periodic work is next due, when the room's simulation moves them on rather than their own loop
+	nextZoneDamage time.Time
+	nextSporeSync time.Time
+	nextAfkDecay time.Time
+	nextMassDecay time.Time
+
+	// The player as they were last sent to everyone
+	lastSent playerSnapshot
+
+	// The spores and other players the client has been told about, so it's only told about the removal of those
+	knownSpores *objects.SharedCollection[struct{}]
+	knownPlayers *objects.SharedCollection[struct{}]
 }
 
 func (game *InGame) Name() string {
@@ -24,30 +99,92 @@ func (game *InGame) Name() string {
 
 func (game *InGame) SetClient(client server.ClientInterfacer) {
 	game.client = client
-	loggingPrefix := fmt.Sprintf("Client %d [%s]: ", client.Id(), game.Name())
-	game.logger = log.New(log.Writer(), loggingPrefix, log.LstdFlags)
+	game.room = client.Room()
+	game.logger = client.Logger().With("state", game.Name())
 }
 
 func (game *InGame) OnEnter() {
-	game.logger.Printf("Adding player %s to the shared collection", game.player.Name)
-	go game.client.SharedGameObjects().Players.Add(game.player, game.client.Id())
+	// Whatever put us in this state may not have checked the name, so don't let a bad one reach other clients
+	if err := validateUserName(game.player.Name); err != nil {
+		defaultName := fmt.Sprintf("Player%d", game.client.Id())
+		game.logger.Warn("Invalid player name, using a default one instead", "name", game.player.Name, "err", err, "default_name", defaultName)
+		game.player.Name = defaultName
+	}
+
+	players := game.room.SharedGameObjects.Players
+	hazards := game.room.SharedGameObjects.Hazards
+
+	// Set the initial properties of the player. Resumed players keep their size and come back near where they were
+	free := true
+
+	if game.resumed {
+		game.player.X, game.player.Y, free = objects.SpawnCoordsAround(game.client.Config().WorldBound, game.player.X, game.player.Y, game.player.Radius, game.player.Radius, players, nil, hazards)
+	} else {
+		game.player.Radius = game.client.Config().PlayerStartRadius
+
+		if game.startMassScale > 0 {
+			game.player.Radius = massToRadius(radiusToMass(game.player.Radius) * game.startMassScale)
+		}
+
+		game.player.X, game.player.Y, free = objects.SpawnCoords(game.client.Config().WorldBound, game.player.Radius, players, nil, hazards)
+	}
+
+	// Players always get to join, so in a packed world they go in the least crowded spot found
+	if !free {
+		game.logger.Warn("No free spot for player, spawning them overlapping another player", "name", game.player.Name)
+	}
+
+	game.player.Speed = radiusToSpeed(game.client.Config(), game.player.Radius)
+
+	// The client took the account's user ID as it logged in, so the player is always added under the same ID
+	game.logger.Info("Adding player to the shared collection", "name", game.player.Name)
+	game.client.RunAsync(func() { players.Add(game.player, game.client.Id()) })
+	game.client.Sessions().Join(game.userId)
+
+	game.joinedAt = time.Now()
+	game.lastDirectionChange = game.joinedAt
+	game.peakMass = radiusToMass(game.player.Radius)
+	if config := game.client.Config(); game.chatLimiter == nil && config.ChatRateLimit > 0 {
+		game.chatLimiter = server.NewTokenBucket(config.ChatRateLimit, config.ChatRatePeriod)
+	}
 
-	// Set the initial properties of the player
-	game.player.X, game.player.Y = objects.SpawnCoords(game.player.Radius, game.client.SharedGameObjects().Players, nil)
-	game.player.Speed = 15.0
-	game.player.Radius = 20
+	game.knownSpores = objects.NewSharedCollection[struct{}]()
+	game.knownPlayers = objects.NewSharedCollection[struct{}]()
+
+	if idleTimeout := game.client.Config().IdleTimeout; idleTimeout > 0 {
+		game.idleTimer = time.AfterFunc(idleTimeout, func() {
+			game.logger.Info("Nothing received for too long, disconnecting", "idle_timeout", idleTimeout)
+			game.client.Close(server.CloseReasonIdle)
+		})
+	}
 
 	// Send the player's initial state to the client
 	game.client.SocketSend(packets.NewPlayer(game.client.Id(), game.player))
 
-	// Send the spores to the client in the background
-	go game.sendInitialSpores(80, 25 * time.Millisecond)
+	config := game.client.Config()
+	zone := game.room.SharedGameObjects.Zone
+	game.client.SocketSend(packets.NewGameMode(config.GameMode, zone.Radius() > 0, config.ConsumeRatio, config.ConsumeMode))
+
+	if zone.Radius() > 0 {
+		game.client.SocketSend(packets.NewZone(zone))
+	}
+
+	// Clients which ask for the world get it once they're ready to show it
+	if !game.hasCapability(packets.CapabilityRequestWorld) {
+		game.sendWorld()
+	}
 }
 
 func (game *InGame) HandleMessage(senderId uint64, message packets.Msg) {
+	if senderId == game.client.Id() && game.idleTimer != nil {
+		game.idleTimer.Reset(game.client.Config().IdleTimeout)
+	}
+
 	switch message := message.(type) {
 		case *packets.Packet_Player:
 			game.handlePlayer(senderId, message)
+		case *packets.Packet_PlayersBatch:
+			game.handlePlayersBatch(senderId, message)
 		case *packets.Packet_PlayerDirection:
 			game.handlePlayerDirection(senderId, message)
 		case *packets.Packet_Chat:
@@ -58,102 +195,486 @@ func (game *InGame) HandleMessage(senderId uint64, message packets.Msg) {
 			game.handlePlayerConsumed(senderId, message)
 		case *packets.Packet_Spore:
 			game.handleSpore(senderId, message)
+		case *packets.Packet_SporeRemoved:
+			game.handleSporeRemoved(senderId, message)
+		case *packets.Packet_Zone:
+			game.handleZone(senderId, message)
+		case *packets.Packet_Hazard:
+			game.handleHazard(senderId, message)
+		case *packets.Packet_HazardRemoved:
+			game.handleHazardRemoved(senderId, message)
+		case *packets.Packet_RequestWorld:
+			game.handleRequestWorld(senderId, message)
+		case *packets.Packet_Stats:
+			game.handleStats(senderId, message)
+		case *packets.Packet_ServerStats:
+			game.handleServerStats(senderId, message)
+		case *packets.Packet_Leaderboard:
+			game.handleLeaderboard(senderId, message)
+		case *packets.Packet_PlayerLeft:
+			game.handlePlayerLeft(senderId, message)
 	}
 }
 
 func (game *InGame) OnExit() {
+	if game.idleTimer != nil {
+		game.idleTimer.Stop()
+	}
+
 	if game.cancelPlayerUpdateLoop != nil {
 		game.cancelPlayerUpdateLoop()
 	}
 
-	game.client.SharedGameObjects().Players.Remove(game.client.Id())
+	game.room.SharedGameObjects.Players.Remove(game.client.Id())
+	game.client.Sessions().Leave(game.userId)
+
+	// Let the other players know to stop showing the player, unless they've already seen them consumed. This runs while
+	// the client is closing, and possibly on the room's own goroutine, so the broadcast mustn't hold it up
+	if game.endReason != matchEndConsumed {
+		leftPacket := packets.NewPlayerLeft(game.client.Id())
+		game.client.RunAsync(func() { game.client.Broadcast(leftPacket) })
+	}
+
+	// Players who drop out rather than get consumed can come back for a while
+	if window := game.client.Config().ResumeWindow; game.endReason == "" && game.sessionToken != "" && window > 0 {
+		game.client.Sessions().Hold(game.sessionToken, &server.HeldSession{
+			UserId: game.userId,
+			RoomName: game.room.Name,
+			Player: game.player,
+		}, window)
+	}
+
+	// A consumed player respawns straight into a new life, which keeps their place in the per-IP player count
+	if game.endReason != matchEndConsumed {
+		game.room.SharedGameObjects.PlayersPerIp.Release(game.client.Ip())
+	}
+
+	game.recordMatch()
+}
+
+// Save the stats of the life which just ended to the match history
+func (game *InGame) recordMatch() {
+	endReason := game.endReason
+
+	if endReason == "" {
+		endReason = matchEndLeft
+	}
+
+	// This can run on the room's goroutine as the player is consumed, so the match is saved in the background
+	game.client.DbTransaction().Matches.Write(db.CreateMatchParams{
+		UserID: game.userId,
+		PeakMass: game.peakMass,
+		DurationMs: time.Since(game.joinedAt).Milliseconds(),
+		Kills: int64(game.player.PlayersEaten),
+		EndReason: endReason,
+	})
+}
+
+// How much of the usual starting mass to respawn with after being consumed by a player who grew to the given radius.
+// The smaller our share of their mass was, the less we get back, so feeding bigger players isn't rewarded
+func (game *InGame) respawnMassScale(consumerRadius float64) float64 {
+	config := game.client.Config()
+
+	if config.MinRespawnMass >= 1 {
+		return 1
+	}
+
+	victimMass := radiusToMass(game.player.Radius)
+	consumerMass := radiusToMass(consumerRadius) - victimMass
+
+	if consumerMass <= 0 {
+		return 1
+	}
+
+	// Being as big as the consume ratio allows is as close as a fight gets
+	closeness := min(victimMass / consumerMass * config.ConsumeRatio, 1)
+
+	return config.MinRespawnMass + (1 - config.MinRespawnMass) * closeness
 }
 
 func (game *InGame) sendInitialSpores(batchSize int, delay time.Duration) {
+	batches := batchSpores(game.room.SharedGameObjects.Spores, batchSize, func(spore *objects.Spore) bool {
+		return game.withinSyncRadius(spore.X, spore.Y)
+	})
+
+	for i, batch := range batches {
+		if i > 0 {
+			time.Sleep(delay)
+		}
+
+		for sporeId := range batch {
+			game.knownSpores.Add(struct{}{}, sporeId)
+		}
+
+		game.client.SocketSend(game.newSporesBatch(batch))
+	}
+}
+
+// Whether a spore at the given position is close enough to the player to be sent to the client. Without a sync radius,
+// every spore is
+func (game *InGame) withinSyncRadius(x float64, y float64) bool {
+	syncRadius := game.client.Config().SporeSyncRadius
+
+	return syncRadius <= 0 || math.Hypot(x - game.player.X, y - game.player.Y) <= syncRadius
+}
+
+// Whether another player's update is close enough to the player to be sent to the client. Without a view range, every
+// update is
+func (game *InGame) withinPlayerViewRange(other *packets.PlayerMessage) bool {
+	viewRange := game.client.Config().PlayerViewRange
+
+	return viewRange <= 0 || math.Hypot(other.X - game.player.X, other.Y - game.player.Y) - other.Radius <= viewRange
+}
+
+// Whether the given player is close enough for this client to be told about what they consume. Without a view range,
+// or if the player can't be found, they are
+func (game *InGame) withinViewRange(playerId uint64) bool {
+	viewRange := game.client.Config().ConsumptionViewRange
+
+	if viewRange <= 0 {
+		return true
+	}
+
+	other, found := game.room.SharedGameObjects.Players.Get(playerId)
+
+	return !found || math.Hypot(other.X - game.player.X, other.Y - game.player.Y) <= viewRange
+}
+
+// Send the spores the player has come close enough to since the world was sent, when only nearby spores are sent
+func (game *InGame) streamNearbySpores() {
+	if !game.worldSent.Load() {
+		return
+	}
+
+	nearbySpores := make(map[uint64]*objects.Spore)
+
+	game.room.SharedGameObjects.Spores.Range(func(sporeId uint64, spore *objects.Spore) {
+		if _, known := game.knownSpores.Get(sporeId); !known && game.withinSyncRadius(spore.X, spore.Y) {
+			nearbySpores[sporeId] = spore
+		}
+	})
+
+	if len(nearbySpores) == 0 {
+		return
+	}
+
+	for sporeId := range nearbySpores {
+		game.knownSpores.Add(struct{}{}, sporeId)
+	}
+
+	game.client.SocketSend(game.newSporesBatch(nearbySpores))
+}
+
+func (game *InGame) hasCapability(capability string) bool {
+	return slices.Contains(game.capabilities, capability)
+}
+
+// Send the spores to the client in the background, once per life
+func (game *InGame) sendWorld() {
+	if !game.worldSent.CompareAndSwap(false, true) {
+		return
+	}
+
+	sendHazards(game.client, game.room.SharedGameObjects.Hazards)
+	go game.sendInitialSpores(80, 25 * time.Millisecond)
+}
+
+func (game *InGame) newSporesBatch(spores map[uint64]*objects.Spore) packets.Msg {
+	return newSporesBatch(game.capabilities, spores)
+}
+
+// Split the spores to send into batches first, so the collection isn't held up while waiting between batches
+func batchSpores(spores *objects.SharedCollection[*objects.Spore], batchSize int, include func(*objects.Spore) bool) []map[uint64]*objects.Spore {
+	batches := make([]map[uint64]*objects.Spore, 0)
 	sporesBatch := make(map[uint64]*objects.Spore, batchSize)
 
-	game.client.SharedGameObjects().Spores.ForEach(func(sporeId uint64, spore *objects.Spore) {
+	spores.Range(func(sporeId uint64, spore *objects.Spore) {
+		if !include(spore) {
+			return
+		}
+
 		sporesBatch[sporeId] = spore
 
 		if len(sporesBatch) >= batchSize {
-			game.client.SocketSend(packets.NewSporesBatch(sporesBatch))
+			batches = append(batches, sporesBatch)
 			sporesBatch = make(map[uint64]*objects.Spore, batchSize)
-			time.Sleep(delay)
 		}
 	})
 
-	// Send any remaining spores
+	// Send any remaining spores too
 	if len(sporesBatch) > 0 {
-		game.client.SocketSend(packets.NewSporesBatch((sporesBatch)))
+		batches = append(batches, sporesBatch)
+	}
+
+	return batches
+}
+
+// Hazards are few, so they're all sent at once, wherever they are
+func sendHazards(client server.ClientInterfacer, hazards *objects.SharedCollection[*objects.Hazard]) {
+	hazards.ForEach(func(hazardId uint64, hazard *objects.Hazard) {
+		client.SocketSend(packets.NewHazard(hazardId, hazard))
+	})
+}
+
+// A batch of spores in the format the client asked for
+func newSporesBatch(capabilities []string, spores map[uint64]*objects.Spore) packets.Msg {
+	if slices.Contains(capabilities, packets.CapabilityFlatSporesBatch) {
+		return packets.NewFlatSporesBatch(spores)
 	}
+
+	return packets.NewSporesBatch(spores)
 }
 
 func (game *InGame) handlePlayer(senderId uint64, message *packets.Packet_Player) {
+	// Our own client has no say over where its player is, so its player messages are dropped. Only the updates other
+	// players' states broadcast after moving them are passed on
 	if senderId == game.client.Id() {
-		game.logger.Println("Received player message from our own client, ignoring")
+		game.logger.Debug("Received player message from our own client, dropping")
 		return
 	}
 
+	otherId := message.Player.Id
+
+	// Players out of view aren't sent, and ones going out of view are taken off the client's screen rather than left
+	// frozen where they were last seen
+	if !game.withinPlayerViewRange(message.Player) {
+		if game.forgetPlayer(otherId) {
+			game.client.SocketSendAs(packets.NewPlayerLeft(otherId), senderId)
+		}
+
+		return
+	}
+
+	game.knownPlayers.Add(struct{}{}, otherId)
 	game.client.SocketSendAs(message, senderId)
 }
 
+// The room's simulation sends every player it moved each tick in one batch. The client is sent its own player and the
+// others in view, the same way as players sent one at a time
+func (game *InGame) handlePlayersBatch(senderId uint64, message *packets.Packet_PlayersBatch) {
+	if senderId == game.client.Id() {
+		game.logger.Debug("Received players batch from our own client, dropping")
+		return
+	}
+
+	players := make([]*packets.PlayerMessage, 0, len(message.PlayersBatch.Players))
+
+	for _, player := range message.PlayersBatch.Players {
+		if player.Id != game.client.Id() && !game.withinPlayerViewRange(player) {
+			if game.forgetPlayer(player.Id) {
+				game.client.SocketSendAs(packets.NewPlayerLeft(player.Id), player.Id)
+			}
+
+			continue
+		}
+
+		if player.Id != game.client.Id() {
+			game.knownPlayers.Add(struct{}{}, player.Id)
+		}
+
+		players = append(players, player)
+	}
+
+	sendPlayers(game.client, game.capabilities, senderId, players)
+}
+
+// Send players as one batch to clients which take them that way, and one at a time as if from each player otherwise
+func sendPlayers(client server.ClientInterfacer, capabilities []string, senderId uint64, players []*packets.PlayerMessage) {
+	if len(players) == 0 {
+		return
+	}
+
+	if slices.Contains(capabilities, packets.CapabilityPlayersBatch) {
+		client.SocketSendAs(packets.NewPlayersBatchOf(players), senderId)
+		return
+	}
+
+	for _, player := range players {
+		client.SocketSendAs(&packets.Packet_Player{Player: player}, player.Id)
+	}
+}
+
 func (game *InGame) handleSpore(senderId uint64, message *packets.Packet_Spore) {
+	// Spores too far away are sent once the player gets close enough
+	if !game.withinSyncRadius(message.Spore.X, message.Spore.Y) {
+		return
+	}
+
+	game.knownSpores.Add(struct{}{}, message.Spore.Id)
 	game.client.SocketSendAs(message, senderId)
 }
 
-func (game *InGame) handleChat(senderId uint64, message *packets.Packet_Chat) {
+func (game *InGame) handleSporeRemoved(senderId uint64, message *packets.Packet_SporeRemoved) {
+	if game.forgetSpore(message.SporeRemoved.SporeId) {
+		game.client.SocketSendAs(message, senderId)
+	}
+}
+
+// Forget a removed spore, returning whether the client knew about it and so needs telling it's gone
+func (game *InGame) forgetSpore(sporeId uint64) bool {
+	_, known := game.knownSpores.Get(sporeId)
+	game.knownSpores.Remove(sporeId)
+
+	return known
+}
+
+func (game *InGame) forgetPlayer(playerId uint64) bool {
+	_, known := game.knownPlayers.Get(playerId)
+	game.knownPlayers.Remove(playerId)
+
+	return known
+}
+
+func (game *InGame) handleZone(senderId uint64, message *packets.Packet_Zone) {
+	game.client.SocketSendAs(message, senderId)
+}
+
+func (game *InGame) handleHazard(senderId uint64, message *packets.Packet_Hazard) {
+	game.client.SocketSendAs(message, senderId)
+}
+
+func (game *InGame) handleHazardRemoved(senderId uint64, message *packets.Packet_HazardRemoved) {
+	game.client.SocketSendAs(message, senderId)
+}
+
+// Clients with the request world capability ask for the world once they're ready for it
+func (game *InGame) handleRequestWorld(senderId uint64, _ *packets.Packet_RequestWorld) {
+	if senderId == game.client.Id() && game.hasCapability(packets.CapabilityRequestWorld) {
+		game.sendWorld()
+	}
+}
+
+// The client asks for the stats of its current life by sending an empty stats message
+func (game *InGame) handleStats(senderId uint64, _ *packets.Packet_Stats) {
 	if senderId == game.client.Id() {
-		game.client.Broadcast(message)
-	} else {
+		game.client.SocketSend(packets.NewStats(game.player, time.Since(game.joinedAt)))
+	}
+}
+
+func (game *InGame) handleServerStats(senderId uint64, message *packets.Packet_ServerStats) {
+	game.client.SocketSendAs(message, senderId)
+}
+
+func (game *InGame) handleLeaderboard(senderId uint64, message *packets.Packet_Leaderboard) {
+	game.client.SocketSendAs(message, senderId)
+}
+
+func (game *InGame) handlePlayerLeft(senderId uint64, message *packets.Packet_PlayerLeft) {
+	if game.forgetPlayer(message.PlayerLeft.PlayerId) {
 		game.client.SocketSendAs(message, senderId)
 	}
 }
 
-func (game *InGame) handleSporeConsumed(senderId uint64, message *packets.Packet_SporeConsumed) {
+func (game *InGame) handleChat(senderId uint64, message *packets.Packet_Chat) {
+	if game.client.Config().ChatDisabled {
+		if senderId == game.client.Id() {
+			game.client.SocketSendAs(packets.NewChat("Chat is disabled on this server"), 0)
+		}
+
+		return
+	}
+
 	if senderId != game.client.Id() {
 		game.client.SocketSendAs(message, senderId)
 		return
 	}
 
+	msg, err := sanitizeChat(message.Chat.Msg)
+
+	if err != nil {
+		game.logger.Info("Rejected chat message", "err", err)
+		return
+	}
+
+	if game.chatLimiter != nil && !game.chatLimiter.Allow() {
+		game.client.SocketSendAs(packets.NewChat("You're sending messages too quickly, slow down"), 0)
+		return
+	}
+
+	message.Chat.Msg = msg
+	game.client.Broadcast(message)
+}
+
+func (game *InGame) handleSporeConsumed(senderId uint64, message *packets.Packet_SporeConsumed) {
+	if senderId != game.client.Id() {
+		if !game.forgetSpore(message.SporeConsumed.SporeId) {
+			return
+		}
+
+		// Far away clients only need to know the spore is gone
+		if game.withinViewRange(senderId) {
+			game.client.SocketSendAs(message, senderId)
+		} else {
+			game.client.SocketSendAs(packets.NewSporeRemoved(message.SporeConsumed.SporeId), senderId)
+		}
+
+		return
+	}
+
 	// If the spore was supposely consumed by our player
 	errorMessage := "Could not verify spore consumption: "
 
+	if game.client.Config().Paused {
+		game.rejectConsumption(errorMessage + "the game is paused")
+		return
+	}
+
 	// Check if spore exists
 	sporeId := message.SporeConsumed.SporeId
 	spore, err := game.getSpore(sporeId)
 
 	if err != nil {
-		game.logger.Println(errorMessage + err.Error())
+		game.rejectConsumption(errorMessage + err.Error())
 		return
 	}
 
-	// Check if the spore is close enough to be consumed
-	err = game.validatePlayerCloseToObject(spore.X, spore.Y, spore.Radius, 10)
+	newRadius, ok, err := resolveSporeConsumption(game.player, spore)
 
-	if err != nil {
-		game.logger.Println(errorMessage + err.Error())
+	if !ok {
+		game.rejectConsumption(errorMessage + err.Error())
+		return
+	}
+
+	newRadius = game.capEarlyGrowth(newRadius)
+
+	// The spore consumption is valid, so take the spore and grow the player. Only whoever actually takes it gets its
+	// mass, so the same spore can't be claimed twice
+	if _, taken := game.room.SharedGameObjects.Spores.GetAndRemove(sporeId); !taken {
+		game.rejectConsumption(errorMessage + "the spore was already consumed")
 		return
 	}
 
-	// The spore consumption is valid, so grow the player and remove the spore
-	sporeMass := radiusToMass(spore.Radius)
-	newRadius := game.nextRadius(sporeMass)
 	game.player.Radius = newRadius
+	game.peakMass = max(game.peakMass, radiusToMass(newRadius))
+	game.player.SporesEaten++
 
-	go game.client.SharedGameObjects().Spores.Remove(senderId)
+	game.forgetSpore(sporeId)
 
 	message.SporeConsumed.NewRadius = newRadius
+	game.recordEvent("consumed", fmt.Sprintf("Spore %d, new radius %f", sporeId, newRadius))
 
 	game.client.Broadcast(message)
 }
 
 func (game *InGame) handlePlayerConsumed(senderId uint64, message *packets.Packet_PlayerConsumed) {
 	if senderId != game.client.Id() {
-		game.client.SocketSendAs(message, senderId)
+		victimId := message.PlayerConsumed.PlayerId
+
+		// Far away clients see the victim respawn from its next update instead
+		if victimId == game.client.Id() || (game.forgetPlayer(victimId) && game.withinViewRange(senderId)) {
+			game.client.SocketSendAs(message, senderId)
+		}
 
-		if message.PlayerConsumed.PlayerId == game.client.Id() {
-			game.logger.Println("Player was consumed, respawning")
+		if victimId == game.client.Id() {
+			game.logger.Info("Player was consumed, respawning")
+			game.client.SocketSend(packets.NewStats(game.player, time.Since(game.joinedAt)))
+			game.endReason = matchEndConsumed
 			game.client.SetState(&InGame{
+				userId: game.userId,
+				capabilities: game.capabilities,
+				sessionToken: game.sessionToken,
+				chatLimiter: game.chatLimiter,
+				startMassScale: game.respawnMassScale(message.PlayerConsumed.NewRadius),
 				player: &objects.Player{
 					Name: game.player.Name,
 				},
@@ -165,87 +686,499 @@ func (game *InGame) handlePlayerConsumed(senderId uint64, message *packets.Packe
 
 	errorMessage := "Could not verify player consumption: "
 
+	if game.client.Config().Paused {
+		game.rejectConsumption(errorMessage + "the game is paused")
+		return
+	}
+
+	if cooldown := game.client.Config().PlayerConsumeCooldown; time.Since(game.lastPlayerConsumed) < cooldown {
+		game.rejectConsumption(errorMessage + fmt.Sprintf("player consumed another player too recently (cooldown: %v)", cooldown))
+		return
+	}
+
+	// Keeps players from respawning next to someone smaller and eating them straight away
+	if grace := game.client.Config().SpawnConsumeGrace; time.Since(game.joinedAt) < grace {
+		game.rejectConsumption(errorMessage + fmt.Sprintf("player spawned too recently (grace: %v)", grace))
+		return
+	}
+
 	otherId := message.PlayerConsumed.PlayerId	
 	other, err := game.getOtherPlayer(otherId)
 
 	if err != nil {
-		game.logger.Println(errorMessage, err.Error())
+		game.rejectConsumption(errorMessage + err.Error())
 		return
 	}
 
-	ourMass := radiusToMass(game.player.Radius)
-	otherMass := radiusToMass(other.Radius)
-	
-	if ourMass <= otherMass*1.5 {
-		game.logger.Printf(errorMessage + "player not massive enough to consume the other player (our radius: %f, other radius: %f)", game.player.Radius, other.Radius)
+	newRadius, ok, err := resolveConsumption(game.player, other, game.client.Config())
+
+	if !ok {
+		game.rejectConsumption(errorMessage + err.Error())
 		return
 	}
 
-	err = game.validatePlayerCloseToObject(other.X, other.Y, other.Radius, 10)
-
-	if err != nil {
-		game.logger.Println(errorMessage, err.Error())
+	// Take the other player out of the game before growing, so if two players consume the same one only the first gets
+	// their mass
+	if _, taken := game.room.SharedGameObjects.Players.GetAndRemove(otherId); !taken {
+		game.rejectConsumption(errorMessage + "the player was already consumed")
 		return
 	}
 
-	newRadius := game.nextRadius(otherMass)
 	game.player.Radius = newRadius
-	
-	go game.client.SharedGameObjects().Players.Remove(otherId)
+	game.peakMass = max(game.peakMass, radiusToMass(newRadius))
+	game.player.PlayersEaten++
+	game.lastPlayerConsumed = time.Now()
+
+	game.forgetPlayer(otherId)
 
 	message.PlayerConsumed.NewRadius = newRadius
+	message.PlayerConsumed.VictimRadius = other.Radius
+	game.recordEvent("consumed", fmt.Sprintf("Player %d, new radius %f", otherId, newRadius))
 
 	game.client.Broadcast(message)
 }
 
 func (game *InGame) handlePlayerDirection(senderId uint64, message *packets.Packet_PlayerDirection) {
 	if senderId == game.client.Id() {
-		game.player.Direction = message.PlayerDirection.Direction
+		direction := message.PlayerDirection.Direction
+
+		// Any finite angle in radians will do, but NaN or infinity would poison the player's position
+		if math.IsNaN(direction) || math.IsInf(direction, 0) {
+			game.logger.Warn("Received invalid direction, ignoring", "direction", direction)
+			return
+		}
+
+		if direction != game.player.Direction {
+			game.lastDirectionChange = time.Now()
+		}
+
+		game.recordEvent("input", fmt.Sprintf("Direction %f", direction))
+
+		if game.client.Config().BufferInputs {
+			game.bufferedDirectionsMux.Lock()
+			game.bufferedDirections = append(game.bufferedDirections, bufferedDirection{direction: direction, receivedAt: time.Now()})
+			game.bufferedDirectionsMux.Unlock()
+		} else {
+			game.player.Direction = direction
+		}
+
+		// If this is the first time receiving a direction from the client, start moving the player, either with the rest
+		// of the room or on their own update loop
+		if game.cancelPlayerUpdateLoop == nil && game.client.Config().SharedTick {
+			simulation := game.room.Simulation
+			playerId := game.client.Id()
+
+			game.cancelPlayerUpdateLoop = func() { simulation.Leave(playerId) }
 
-		// If this is the first time receiving a direction from the client, start the player update loop
-		if game.cancelPlayerUpdateLoop == nil {
-			ctx, cancel := context.WithCancel(context.Background())
+			simulation.Join(playerId, game)
+		} else if game.cancelPlayerUpdateLoop == nil {
+			ctx, cancel := context.WithCancel(game.client.Context())
 
 			game.cancelPlayerUpdateLoop = cancel
 
-			go game.updatePlayerLoop(ctx)
+			go game.superviseUpdatePlayerLoop(ctx)
 		}
 	}
 }
 
-func (game *InGame) updatePlayerLoop(ctx context.Context) {
-	const delta float64 = 0.05
+// Run the player update loop, restarting it if it panics so the player doesn't just freeze. If it keeps panicking,
+// close the client instead
+func (game *InGame) superviseUpdatePlayerLoop(ctx context.Context) {
+	const maxRestarts int = 3
+
+	for restarts := 0; game.runUpdatePlayerLoop(ctx); restarts++ {
+		if restarts >= maxRestarts {
+			game.logger.Error("Player update loop keeps panicking, giving up")
+			game.client.Close(server.CloseReasonUpdateLoopFailed)
+			return
+		}
 
-	ticker := time.NewTicker(time.Duration(delta * 100) * time.Millisecond)
+		game.logger.Warn("Restarting player update loop", "restart", restarts + 1, "max_restarts", maxRestarts)
+	}
+}
+
+// Returns whether the loop panicked rather than stopping normally
+func (game *InGame) runUpdatePlayerLoop(ctx context.Context) (panicked bool) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			game.logger.Error("Player update loop panicked", "panic", recovered, "stack", string(debug.Stack()))
+			panicked = true
+		}
+	}()
 
+	game.updatePlayerLoop(ctx)
+
+	return false
+}
+
+func (game *InGame) updatePlayerLoop(ctx context.Context) {
+	ticker := time.NewTicker(server.TickInterval)
 	defer ticker.Stop()
 
+	// Stays nil when the zone is disabled, so it never fires
+	var zoneDamageChan <-chan time.Time
+
+	if game.room.SharedGameObjects.Zone.Radius() > 0 {
+		zoneDamageTicker := time.NewTicker(game.client.Config().ZoneDamageInterval)
+		defer zoneDamageTicker.Stop()
+		zoneDamageChan = zoneDamageTicker.C
+	}
+
+	var sporeSyncChan <-chan time.Time
+
+	if game.client.Config().SporeSyncRadius > 0 {
+		sporeSyncTicker := time.NewTicker(500 * time.Millisecond)
+		defer sporeSyncTicker.Stop()
+		sporeSyncChan = sporeSyncTicker.C
+	}
+
+	var afkDecayChan <-chan time.Time
+
+	if game.client.Config().AfkThreshold > 0 {
+		afkDecayTicker := time.NewTicker(time.Second)
+		defer afkDecayTicker.Stop()
+		afkDecayChan = afkDecayTicker.C
+	}
+
+	var massDecayChan <-chan time.Time
+
+	if game.client.Config().MassDecay > 0 {
+		massDecayTicker := time.NewTicker(game.client.Config().MassDecayInterval)
+		defer massDecayTicker.Stop()
+		massDecayChan = massDecayTicker.C
+	}
+
 	for {
+		// Keep draining the tickers while paused, so nothing builds up to be applied all at once on resume
+		paused := game.client.Config().Paused
+
 		select {
-			case <- ticker.C:
-				game.syncPlayer(delta)
+			case tickTime := <- ticker.C:
+				if !paused {
+					game.syncPlayer(server.TickDelta, tickTime)
+				}
+			case <- zoneDamageChan:
+				if !paused {
+					game.applyZoneDamage()
+				}
+			case <- afkDecayChan:
+				if !paused {
+					game.applyAfkDecay()
+				}
+			case <- massDecayChan:
+				if !paused {
+					game.applyMassDecay()
+				}
+			case <- sporeSyncChan:
+				game.streamNearbySpores()
 			case <- ctx.Done():
 				return
 		}
 	}
 }
 
-func (game *InGame) syncPlayer(delta float64) {
-	newX := game.player.X + game.player.Speed * math.Cos(game.player.Direction) * delta
-	newY := game.player.Y + game.player.Speed * math.Sin(game.player.Direction) * delta
+// Move the player on by one tick of the room's simulation, doing the periodic work their own update loop would do on
+// tickers. The simulation sends the player to everyone, their own client included, in its batch for the tick
+func (game *InGame) Step(delta float64, tickTime time.Time) *objects.Player {
+	config := game.client.Config()
 
-	game.player.X = newX
-	game.player.Y = newY
+	if game.room.SharedGameObjects.Zone.Radius() > 0 && isDue(&game.nextZoneDamage, config.ZoneDamageInterval, tickTime) {
+		game.applyZoneDamage()
+	}
+
+	if config.SporeSyncRadius > 0 && isDue(&game.nextSporeSync, 500 * time.Millisecond, tickTime) {
+		game.streamNearbySpores()
+	}
+
+	if config.AfkThreshold > 0 && isDue(&game.nextAfkDecay, time.Second, tickTime) {
+		game.applyAfkDecay()
+	}
+
+	if config.MassDecay > 0 && isDue(&game.nextMassDecay, config.MassDecayInterval, tickTime) {
+		game.applyMassDecay()
+	}
+
+	game.movePlayer(delta, tickTime)
+
+	if !game.shouldSendPlayer(tickTime) {
+		return nil
+	}
+
+	return game.player
+}
+
+// Whether periodic work scheduled for the given time is due by now, scheduling the next round if so. Like a ticker,
+// the first round is due one interval after the first check
+func isDue(next *time.Time, interval time.Duration, now time.Time) bool {
+	if next.IsZero() {
+		*next = now.Add(interval)
+		return false
+	}
+
+	if now.Before(*next) {
+		return false
+	}
+
+	*next = now.Add(interval)
 
+	return true
+}
+
+// Shrink the player if they are outside of the safe zone. The change is sent out with the next player update
+func (game *InGame) applyZoneDamage() {
+	if game.room.SharedGameObjects.Zone.Contains(game.player.X, game.player.Y) {
+		return
+	}
+
+	game.loseMass(game.client.Config().ZoneDamage)
+}
+
+// Shrink the player if they haven't changed direction in a while
+func (game *InGame) applyAfkDecay() {
+	if time.Since(game.lastDirectionChange) < game.client.Config().AfkThreshold {
+		return
+	}
+
+	game.loseMass(game.client.Config().AfkDecay)
+}
+
+// Shrink big players a little, down to the decay floor. The next tick sends everyone the new size
+func (game *InGame) applyMassDecay() {
+	config := game.client.Config()
+	floorRadius := max(config.MassDecayMinRadius, config.PlayerStartRadius)
+
+	if game.player.Radius <= floorRadius {
+		return
+	}
+
+	newMass := radiusToMass(game.player.Radius) * (1 - config.MassDecay)
+	game.player.Radius = max(massToRadius(newMass), floorRadius)
+}
+
+// Shrink the player by the given fraction of their mass, without going below a minimum radius
+func (game *InGame) loseMass(fraction float64) {
+	const minRadius float64 = 10
+
+	newMass := radiusToMass(game.player.Radius) * (1 - fraction)
+	game.player.Radius = max(massToRadius(newMass), minRadius)
+}
+
+// How far the player moves this tick. Each direction change buffered since the last tick is followed for the part of
+// the tick after it arrived, so quick changes between ticks aren't lost
+func (game *InGame) tickMovement(delta float64, tickTime time.Time) (float64, float64) {
+	game.bufferedDirectionsMux.Lock()
+	directions := game.bufferedDirections
+	game.bufferedDirections = nil
+	game.bufferedDirectionsMux.Unlock()
+
+	tickStart := game.lastTickTime
+	tickLength := float64(tickTime.Sub(tickStart))
+	game.lastTickTime = tickTime
+
+	// Without a previous tick to measure from, the latest direction gets the whole tick
+	if tickStart.IsZero() || tickLength <= 0 {
+		for _, buffered := range directions {
+			game.player.Direction = buffered.direction
+		}
+
+		return game.player.Speed * math.Cos(game.player.Direction) * delta, game.player.Speed * math.Sin(game.player.Direction) * delta
+	}
+
+	var dx, dy float64
+	segmentStart := tickStart
+
+	// Move in the current direction for the part of the tick up to the given time
+	moveUntil := func(until time.Time) {
+		if until.After(segmentStart) {
+			fraction := min(float64(until.Sub(segmentStart)) / tickLength, 1)
+			dx += game.player.Speed * math.Cos(game.player.Direction) * delta * fraction
+			dy += game.player.Speed * math.Sin(game.player.Direction) * delta * fraction
+			segmentStart = until
+		}
+	}
+
+	for _, buffered := range directions {
+		moveUntil(buffered.receivedAt)
+		game.player.Direction = buffered.direction
+	}
+
+	moveUntil(tickTime)
+
+	return dx, dy
+}
+
+// Set the player's speed for their current size, sped up for a while after they consume another player
+func (game *InGame) updateSpeed(now time.Time) {
+	config := game.client.Config()
+	game.player.Speed = radiusToSpeed(game.client.Config(), game.player.Radius)
+
+	if config.KillSpeedBoost <= 0 || game.lastPlayerConsumed.IsZero() {
+		return
+	}
+
+	if remaining := config.KillSpeedBoostDuration - now.Sub(game.lastPlayerConsumed); remaining > 0 {
+		game.player.Speed *= 1 + config.KillSpeedBoost * float64(remaining) / float64(config.KillSpeedBoostDuration)
+	}
+}
+
+// Move the player on by one tick and send everyone where they are now
+func (game *InGame) syncPlayer(delta float64, tickTime time.Time) {
+	game.movePlayer(delta, tickTime)
+
+	if !game.shouldSendPlayer(tickTime) {
+		return
+	}
 	updatePacket := packets.NewPlayer(game.client.Id(), game.player)
 
 	game.client.Broadcast(updatePacket)
 
-	go game.client.SocketSend(updatePacket)
+	game.client.RunAsync(func() { game.client.SocketSend(updatePacket) })
+}
+
+func (game *InGame) movePlayer(delta float64, tickTime time.Time) {
+	game.updateSpeed(tickTime)
+	dx, dy := game.tickMovement(delta, tickTime)
+
+	// Directions are checked as they arrive, so this only catches bugs in working out the movement, but a bad position
+	// would be sent to everyone and break every distance check involving the player
+	if limitedDx, limitedDy, limited := limitDisplacement(dx, dy, game.player.Speed * delta); limited {
+		game.logger.Error("Player moved too far in one tick, limiting the movement", "dx", dx, "dy", dy, "speed", game.player.Speed, "delta", delta)
+		dx, dy = limitedDx, limitedDy
+	}
+
+	newX := game.player.X + dx
+	newY := game.player.Y + dy
+
+	// Pull the player toward the center of the world, without overshooting it
+	if drift := game.client.Config().DriftStrength; drift > 0 {
+		distToCenter := math.Hypot(newX, newY)
+
+		if distToCenter > 0 {
+			pull := min(drift * delta, distToCenter)
+			newX -= newX / distToCenter * pull
+			newY -= newY / distToCenter * pull
+		}
+	}
+
+	// Stop at the edge of the world on each axis separately, so players slide along the walls
+	worldBound := game.client.Config().WorldBound
+	newX = objects.ClampToWorld(newX, game.player.Radius, worldBound)
+	newY = objects.ClampToWorld(newY, game.player.Radius, worldBound)
+
+	game.player.DistanceTraveled += math.Hypot(newX - game.player.X, newY - game.player.Y)
+	game.player.X = newX
+	game.player.Y = newY
+	game.player.TickTime = tickTime.UnixMilli()
+	game.room.SharedGameObjects.Players.Reindex(game.client.Id())
+	game.hitHazards()
+}
+
+// Pop any hazards the player has run into, if they're big enough for hazards to hurt them, and shrink them for each.
+// The new size goes out with the player's next update
+func (game *InGame) hitHazards() {
+	config := game.client.Config()
+
+	if config.MaxHazards <= 0 || game.player.Radius <= config.HazardMinPlayerRadius {
+		return
+	}
+
+	hazards := game.room.SharedGameObjects.Hazards
+
+	for _, hazardId := range hazards.QueryRadiusIds(game.player.X, game.player.Y, game.player.Radius) {
+		hazard, found := hazards.Get(hazardId)
+
+		if !found || validateCloseToObject(game.player, hazard.X, hazard.Y, hazard.Radius, 0) != nil {
+			continue
+		}
+
+		// Only whoever takes the hazard is hurt by it, so it can't pop on two players at once
+		if _, taken := hazards.GetAndRemove(hazardId); !taken {
+			continue
+		}
+
+		game.loseMass(config.HazardPenalty)
+		game.recordEvent("hazard", fmt.Sprintf("Hazard %d, new radius %f", hazardId, game.player.Radius))
+
+		removedPacket := packets.NewHazardRemoved(hazardId, game.client.Id())
+		game.client.SocketSend(removedPacket)
+		game.client.RunAsync(func() { game.client.Broadcast(removedPacket) })
+	}
+}
+
+// Whether the player has moved or changed since they were last sent, or has gone unsent for long enough to send them
+// anyway. If so, they're counted as sent now
+func (game *InGame) shouldSendPlayer(now time.Time) bool {
+	// Closer than this to where the player was last sent counts as not having moved
+	const minDistance float64 = 0.01
+
+	player := game.player
+	last := game.lastSent
+	keepalive := game.client.Config().PlayerKeepaliveInterval
+
+	changed := math.Hypot(player.X - last.x, player.Y - last.y) >= minDistance ||
+		player.Radius != last.radius ||
+		player.Direction != last.direction ||
+		player.Speed != last.speed
+
+	if !changed && keepalive > 0 && !last.sentAt.IsZero() && now.Sub(last.sentAt) < keepalive {
+		return false
+	}
+
+	game.lastSent = playerSnapshot{
+		x: player.X,
+		y: player.Y,
+		radius: player.Radius,
+		direction: player.Direction,
+		speed: player.Speed,
+		sentAt: now,
+	}
+
+	return true
+}
+
+// Shorten a movement to at most the given distance, allowing for rounding, or cancel it if it isn't a finite number.
+// Returns whether the movement had to be changed
+func limitDisplacement(dx float64, dy float64, maxDistance float64) (float64, float64, bool) {
+	const tolerance float64 = 1e-9
+
+	distance := math.Hypot(dx, dy)
+
+	if math.IsNaN(distance) || math.IsInf(distance, 0) {
+		return 0, 0, true
+	}
+
+	if distance <= maxDistance * (1 + tolerance) + tolerance {
+		return dx, dy, false
+	}
+
+	scale := max(maxDistance, 0) / distance
+
+	return dx * scale, dy * scale, true
+}
+
+// Until enough players have joined, keep the first ones from growing past a limit by sweeping up the spores unopposed
+func (game *InGame) capEarlyGrowth(newRadius float64) float64 {
+	config := game.client.Config()
+
+	if game.room.SharedGameObjects.Players.Len() >= config.FairStartPlayers {
+		return newRadius
+	}
+
+	return max(min(newRadius, config.FairStartMaxRadius), game.player.Radius)
+}
+
+// Log a consumption the server couldn't verify, keeping it in the event log too
+func (game *InGame) rejectConsumption(reason string) {
+	game.logger.Warn("Rejected consumption", "reason", reason)
+	game.recordEvent("rejected", reason)
+}
+
+func (game *InGame) recordEvent(kind string, detail string) {
+	game.client.EventLog().Record(kind, detail, game.player.X, game.player.Y)
 }
 
 func (game *InGame) getSpore(sporeId uint64) (*objects.Spore, error) {
-	spore, exists := game.client.SharedGameObjects().Spores.Get(sporeId)
+	spore, exists := game.room.SharedGameObjects.Spores.Get(sporeId)
 
 	if !exists {
 		return nil, fmt.Errorf("Spore with ID %d does not exist", sporeId)
@@ -255,7 +1188,7 @@ func (game *InGame) getSpore(sporeId uint64) (*objects.Spore, error) {
 }
 
 func (game *InGame) getOtherPlayer(playerId uint64) (*objects.Player, error) {
-	player, exists := game.client.SharedGameObjects().Players.Get(playerId)
+	player, exists := game.room.SharedGameObjects.Players.Get(playerId)
 
 	if !exists {
 		return nil, fmt.Errorf("Player with ID %d does not exist", playerId)
@@ -264,31 +1197,19 @@ func (game *InGame) getOtherPlayer(playerId uint64) (*objects.Player, error) {
 	return player, nil
 }
 
-func (game *InGame) validatePlayerCloseToObject(objX, objY, objRadius, buffer float64) error {
-	realDX := game.player.X - objX
-	realDY := game.player.Y - objY
-	realDistSq := realDX * realDX + realDY * realDY
-	thresholdDist := game.player.Radius + buffer + objRadius
-	thresholdDistSq := thresholdDist * thresholdDist
-
-	if realDistSq > thresholdDistSq {
-		return fmt.Errorf("Player is too far from the object (distSq: %f, thresholdSq: %f)", realDistSq, thresholdDistSq)
-	}
-
-	return nil
-}
-
 func radiusToMass(radius float64) float64 {
-	return math.Pi * radius * radius
+	return objects.RadiusToMass(radius)
 }
 
 func massToRadius(mass float64) float64 {
-	return math.Sqrt(mass / math.Pi)
+	return objects.MassToRadius(mass)
 }
 
-func (game *InGame) nextRadius(massDiff float64) float64 {
-	oldMass := radiusToMass(game.player.Radius)
-	newMass := oldMass + massDiff
+// Players slow down as they grow, moving at half their starting speed once four times as big
+func radiusToSpeed(config *server.ServerConfig, radius float64) float64 {
+	if radius <= 0 {
+		return config.PlayerSpeed
+	}
 
-	return massToRadius(newMass)
+	return min(max(config.PlayerSpeed * math.Sqrt(config.PlayerStartRadius / radius), config.MinPlayerSpeed), config.PlayerSpeed)
 }
\ No newline at end of file
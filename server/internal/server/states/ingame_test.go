@@ -0,0 +1,1545 @@
+package states
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"math"
+	"server/internal/server"
+	"server/internal/server/db"
+	"server/internal/server/objects"
+	"server/internal/server/servertest"
+	"server/pkg/packets"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Put a player in the game on a fake client without going through OnEnter, which needs a database and starts the
+// player's update loop
+func newTestGame(player *objects.Player, configure ...func(config *server.ServerConfig)) (*InGame, *servertest.FakeClient) {
+	config := server.NewServerConfig()
+
+	for _, configureFunc := range configure {
+		configureFunc(config)
+	}
+
+	client := servertest.NewFakeClient(1, config)
+
+	return newTestGameOn(client, player), client
+}
+
+// Put another player in the same room as the given client's, on a fake client of their own
+func newTestPeer(client *servertest.FakeClient, id uint64, player *objects.Player) (*InGame, *servertest.FakeClient) {
+	peer := client.NewPeer(id)
+
+	return newTestGameOn(peer, player), peer
+}
+
+func newTestGameOn(client *servertest.FakeClient, player *objects.Player) *InGame {
+	game := &InGame{
+		player: player,
+		knownSpores: objects.NewSharedCollection[struct{}](),
+		knownPlayers: objects.NewSharedCollection[struct{}](),
+	}
+
+	client.SetStateWithoutEntering(game)
+	client.SharedGameObjects().Players.Add(player, client.Id())
+
+	return game
+}
+
+func TestSporeConsumed(t *testing.T) {
+	player := &objects.Player{Name: "test", Radius: 20}
+	game, client := newTestGame(player)
+	spore := &objects.Spore{X: 10, Radius: 5}
+	sporeId := client.SharedGameObjects().Spores.Add(spore)
+
+	game.HandleMessage(client.Id(), &packets.Packet_SporeConsumed{SporeConsumed: &packets.SporeConsumedMessage{SporeId: sporeId}})
+
+	expectedRadius := massToRadius(radiusToMass(20) + radiusToMass(spore.Radius))
+
+	if math.Abs(player.Radius - expectedRadius) > 1e-9 {
+		t.Errorf("Consuming a spore gave radius %f instead of %f", player.Radius, expectedRadius)
+	}
+
+	if player.SporesEaten != 1 {
+		t.Errorf("Consuming a spore counted %d spores eaten", player.SporesEaten)
+	}
+
+	broadcasts := client.Broadcasts()
+
+	if len(broadcasts) != 1 || broadcasts[0].GetSporeConsumed().GetSporeId() != sporeId {
+		t.Errorf("Consuming a spore broadcast %v instead of the consumption", broadcasts)
+	}
+}
+
+// Sending the same consumption again must not grant the spore's mass a second time
+func TestSporeConsumedTwice(t *testing.T) {
+	player := &objects.Player{Name: "test", Radius: 20}
+	game, client := newTestGame(player)
+	sporeId := client.SharedGameObjects().Spores.Add(&objects.Spore{X: 10, Radius: 5})
+
+	game.HandleMessage(client.Id(), &packets.Packet_SporeConsumed{SporeConsumed: &packets.SporeConsumedMessage{SporeId: sporeId}})
+	radiusAfterFirst := player.Radius
+	game.HandleMessage(client.Id(), &packets.Packet_SporeConsumed{SporeConsumed: &packets.SporeConsumedMessage{SporeId: sporeId}})
+
+	if _, found := client.SharedGameObjects().Spores.Get(sporeId); found {
+		t.Fatal("Consuming a spore left it in the world")
+	}
+
+	if player.Radius != radiusAfterFirst || player.SporesEaten != 1 || len(client.Broadcasts()) != 1 {
+		t.Fatalf("Consuming a spore twice grew the player to %f, %d spores eaten", player.Radius, player.SporesEaten)
+	}
+}
+
+// Consuming a spore must remove that spore and no other. The spore placed first shares its ID with the client, which
+// is the one that would go if the client's ID were mistaken for the spore's
+func TestOnlyConsumedSporeRemoved(t *testing.T) {
+	player := &objects.Player{Name: "test", Radius: 20}
+	game, client := newTestGame(player)
+	spores := client.SharedGameObjects().Spores
+	bystanderId := spores.Add(&objects.Spore{X: 500, Radius: 5})
+	sporeId := spores.Add(&objects.Spore{X: 10, Radius: 5})
+
+	if bystanderId != client.Id() {
+		t.Fatalf("Expected the first spore to get ID %d like the client, got %d", client.Id(), bystanderId)
+	}
+
+	game.HandleMessage(client.Id(), &packets.Packet_SporeConsumed{SporeConsumed: &packets.SporeConsumedMessage{SporeId: sporeId}})
+
+	if _, found := spores.Get(sporeId); found {
+		t.Fatalf("Consumed spore %d is still in the world", sporeId)
+	}
+
+	if _, found := spores.Get(bystanderId); !found || spores.Len() != 1 {
+		t.Fatalf("Consuming spore %d removed other spores too", sporeId)
+	}
+}
+
+func TestFarSporeRejected(t *testing.T) {
+	player := &objects.Player{Name: "test", Radius: 20}
+	game, client := newTestGame(player)
+	sporeId := client.SharedGameObjects().Spores.Add(&objects.Spore{X: 1000, Radius: 5})
+
+	game.HandleMessage(client.Id(), &packets.Packet_SporeConsumed{SporeConsumed: &packets.SporeConsumedMessage{SporeId: sporeId}})
+
+	if player.Radius != 20 || len(client.Broadcasts()) > 0 {
+		t.Fatalf("Consuming a spore out of reach grew the player to %f and broadcast %v", player.Radius, client.Broadcasts())
+	}
+}
+
+// A player out of view range must not be sent another's movements, and one moving out of view must be removed
+func TestPlayerViewRange(t *testing.T) {
+	player := &objects.Player{Name: "test", Radius: 20}
+	game, client := newTestGame(player, func(config *server.ServerConfig) { config.PlayerViewRange = 1000 })
+	const otherId uint64 = 2
+
+	game.HandleMessage(otherId, packets.NewPlayer(otherId, &objects.Player{Name: "far", X: 2500, Radius: 20}))
+
+	if sent := client.Sent(); len(sent) > 0 {
+		t.Errorf("A player out of view range was sent %v", sent)
+	}
+
+	game.HandleMessage(otherId, packets.NewPlayer(otherId, &objects.Player{Name: "near", X: 500, Radius: 20}))
+
+	if sent := client.Sent(); len(sent) != 1 || sent[0].GetPlayer() == nil {
+		t.Errorf("A player in view range was sent %v instead of their movement", sent)
+	}
+
+	client.ClearRecorded()
+	game.HandleMessage(otherId, packets.NewPlayer(otherId, &objects.Player{Name: "near", X: 2500, Radius: 20}))
+
+	if sent := client.Sent(); len(sent) != 1 || sent[0].GetPlayerLeft().GetPlayerId() != otherId {
+		t.Errorf("A player moving out of view range was sent %v instead of them leaving", sent)
+	}
+}
+
+// With a cooldown, a player consuming two others in quick succession only gets the first, but can still consume spores
+func TestPlayerConsumeCooldown(t *testing.T) {
+	player := &objects.Player{Name: "test", Radius: 40}
+	game, client := newTestGame(player, func(config *server.ServerConfig) { config.PlayerConsumeCooldown = time.Second })
+	players := client.SharedGameObjects().Players
+	players.Add(&objects.Player{Name: "first", X: 10, Radius: 10}, 2)
+	players.Add(&objects.Player{Name: "second", X: -10, Radius: 10}, 3)
+
+	for _, victimId := range []uint64{2, 3} {
+		game.HandleMessage(client.Id(), &packets.Packet_PlayerConsumed{PlayerConsumed: &packets.PlayerConsumedMessage{PlayerId: victimId}})
+	}
+
+	expectedRadius := nextRadius(40, 10)
+
+	if player.PlayersEaten != 1 || math.Abs(player.Radius - expectedRadius) > 1e-9 {
+		t.Fatalf("Consuming two players within the cooldown ate %d and grew to %f", player.PlayersEaten, player.Radius)
+	}
+
+	if _, found := players.Get(3); !found {
+		t.Fatal("The player consumed within the cooldown was taken out of the game")
+	}
+
+	sporeId := client.SharedGameObjects().Spores.Add(&objects.Spore{Y: 10, Radius: 5})
+	game.HandleMessage(client.Id(), &packets.Packet_SporeConsumed{SporeConsumed: &packets.SporeConsumedMessage{SporeId: sporeId}})
+
+	if player.SporesEaten != 1 {
+		t.Error("A spore couldn't be consumed within the cooldown for consuming players")
+	}
+}
+
+// Logs from a state must carry the client and state they came from
+func TestLogFields(t *testing.T) {
+	var output bytes.Buffer
+
+	client := servertest.NewFakeClient(1, server.NewServerConfig())
+	client.SetLogHandler(slog.NewJSONHandler(&output, nil))
+	game := &InGame{player: &objects.Player{Name: "test", Radius: 20}}
+	game.SetClient(client)
+
+	game.HandleMessage(client.Id(), &packets.Packet_PlayerDirection{PlayerDirection: &packets.PlayerDirectionMessage{Direction: math.NaN()}})
+
+	var record struct {
+		ClientId uint64 `json:"client_id"`
+		State string `json:"state"`
+	}
+
+	if err := json.Unmarshal(output.Bytes(), &record); err != nil {
+		t.Fatalf("Logged %q, which isn't a single JSON record: %v", output.String(), err)
+	}
+
+	if record.ClientId != client.Id() || record.State != game.Name() {
+		t.Fatalf("Logged %q without the client ID %d and state %s", output.String(), client.Id(), game.Name())
+	}
+}
+
+// Whatever directions a client sends, and however many between ticks, its player must never move further in a tick
+// than its speed allows, or end up somewhere that isn't a real number
+func TestMovementSpeedLimit(t *testing.T) {
+	const delta float64 = 0.05
+	const tickLength time.Duration = 50 * time.Millisecond
+
+	directions := []float64{0, 1, -1, math.Pi, 1e300, -1e300, math.MaxFloat64, math.SmallestNonzeroFloat64, math.NaN(), math.Inf(1), math.Inf(-1)}
+
+	for _, bufferInputs := range []bool{false, true} {
+		player := &objects.Player{Name: "test", Radius: 20}
+		game, client := newTestGame(player, func(config *server.ServerConfig) {
+			config.BufferInputs = bufferInputs
+			config.DriftStrength = 0
+		})
+
+		// Keep the update loop from starting, so the ticks here are the only movement
+		game.cancelPlayerUpdateLoop = func() {}
+		tickTime := time.Now()
+
+		for i := range directions {
+			for _, direction := range directions[:i + 1] {
+				game.HandleMessage(client.Id(), &packets.Packet_PlayerDirection{PlayerDirection: &packets.PlayerDirectionMessage{Direction: direction}})
+			}
+
+			x, y := player.X, player.Y
+			tickTime = tickTime.Add(tickLength)
+			game.syncPlayer(delta, tickTime)
+			moved := math.Hypot(player.X - x, player.Y - y)
+
+			if math.IsNaN(moved) || math.IsInf(moved, 0) {
+				t.Errorf("Direction %f moved the player to (%f, %f) with buffered inputs %t", directions[i], player.X, player.Y, bufferInputs)
+				break
+			}
+
+			if moved > player.Speed * delta + 1e-9 {
+				t.Errorf("Direction %f moved the player %f in a tick at speed %f with buffered inputs %t", directions[i], moved, player.Speed, bufferInputs)
+			}
+		}
+	}
+
+	if dx, dy, _ := limitDisplacement(30, 40, 10); math.Abs(math.Hypot(dx, dy) - 10) > 1e-9 || math.Abs(dy / dx - 4.0 / 3) > 1e-9 {
+		t.Errorf("Limiting a movement of (30, 40) to 10 gave (%f, %f)", dx, dy)
+	}
+
+	if dx, dy, limited := limitDisplacement(math.NaN(), 1, 10); !limited || dx != 0 || dy != 0 {
+		t.Errorf("Limiting a movement of (NaN, 1) gave (%f, %f)", dx, dy)
+	}
+}
+
+// Does nothing but panic when stepped
+type panickingParticipant struct{}
+
+func (panickingParticipant) Step(float64, time.Time) *objects.Player {
+	panic("stepped a participant which always panics")
+}
+
+// Players must join the room's simulation on their first direction and leave it with their update loop. Each tick must
+// move every player once and send every client both players without broadcasting, in a single batch to clients which
+// take them that way, and carry on past a participant which panics
+func TestSimulation(t *testing.T) {
+	const panickingId uint64 = 3
+
+	player := &objects.Player{Name: "test", Radius: 20, Speed: 100}
+	game, client := newTestGame(player, func(config *server.ServerConfig) {
+		config.SharedTick = true
+		config.DriftStrength = 0
+	})
+	otherPlayer := &objects.Player{Name: "other", X: 100, Radius: 20, Speed: 100}
+	otherGame, otherClient := newTestPeer(client, 2, otherPlayer)
+	simulation := client.Room().Simulation
+	game.capabilities = []string{packets.CapabilityPlayersBatch}
+
+	game.HandleMessage(client.Id(), &packets.Packet_PlayerDirection{PlayerDirection: &packets.PlayerDirectionMessage{Direction: 0}})
+	otherGame.HandleMessage(otherClient.Id(), &packets.Packet_PlayerDirection{PlayerDirection: &packets.PlayerDirectionMessage{Direction: math.Pi}})
+	simulation.Join(panickingId, panickingParticipant{})
+
+	if simulation.Len() != 3 {
+		t.Errorf("%d participants joined the simulation instead of 3", simulation.Len())
+	}
+
+	if moved := simulation.Tick(server.TickDelta, time.Now()); moved != 2 {
+		t.Errorf("A tick passed on %d players instead of 2", moved)
+	}
+
+	if simulation.Len() != 2 {
+		t.Errorf("A participant which panicked was left in the simulation, leaving %d", simulation.Len())
+	}
+
+	if player.X <= 0 || otherPlayer.X >= 100 {
+		t.Errorf("A tick moved the players to %f and %f", player.X, otherPlayer.X)
+	}
+
+	if sent := client.Sent(); len(sent) != 1 || len(sent[0].GetPlayersBatch().GetPlayers()) != 2 {
+		t.Errorf("A client taking players in batches was sent %v instead of one batch of both players", sent)
+	}
+
+	for _, testClient := range []*servertest.FakeClient{client, otherClient} {
+		updatedIds := make(map[uint64]bool)
+
+		for _, packet := range testClient.Sent() {
+			if update := packet.GetPlayer(); update != nil {
+				updatedIds[update.Id] = true
+			}
+
+			for _, update := range packet.GetPlayersBatch().GetPlayers() {
+				updatedIds[update.Id] = true
+			}
+		}
+
+		if !updatedIds[client.Id()] || !updatedIds[otherClient.Id()] {
+			t.Errorf("Client %d was sent updates for %v instead of both players", testClient.Id(), updatedIds)
+		}
+
+		if broadcasts := testClient.Broadcasts(); len(broadcasts) > 0 {
+			t.Errorf("Client %d broadcast %v instead of leaving its update to the simulation", testClient.Id(), broadcasts)
+		}
+	}
+
+	game.cancelPlayerUpdateLoop()
+	x := player.X
+	simulation.Tick(server.TickDelta, time.Now())
+
+	if simulation.Len() != 1 || player.X != x {
+		t.Errorf("A player which left the simulation moved from %f to %f, leaving %d participants", x, player.X, simulation.Len())
+	}
+}
+
+// A player who can't move because they're up against the edge of the world must only be sent once until the keepalive
+// interval has passed, on their own update loop and in the room's simulation alike
+func TestUnchangedPlayerNotSent(t *testing.T) {
+	const keepalive time.Duration = time.Second
+
+	player := &objects.Player{Name: "test", Radius: 20}
+	game, client := newTestGame(player, func(config *server.ServerConfig) {
+		config.DriftStrength = 0
+		config.PlayerKeepaliveInterval = keepalive
+	})
+	player.X = client.Config().WorldBound - player.Radius
+	tickTime := time.Now()
+
+	for tick := range 10 {
+		tickTime = tickTime.Add(server.TickInterval)
+		game.syncPlayer(server.TickDelta, tickTime)
+
+		if broadcasts := len(client.Broadcasts()); tick > 0 && broadcasts != 1 {
+			t.Errorf("A player pushing against the edge of the world was broadcast %d times in %d ticks", broadcasts, tick + 1)
+			break
+		}
+	}
+
+	if player := game.Step(server.TickDelta, tickTime.Add(server.TickInterval)); player != nil {
+		t.Error("A player pushing against the edge of the world was passed on by the simulation")
+	}
+
+	game.syncPlayer(server.TickDelta, tickTime.Add(keepalive))
+
+	if broadcasts := len(client.Broadcasts()); broadcasts != 2 {
+		t.Errorf("A player who hadn't changed for the keepalive interval was broadcast %d times rather than again", broadcasts)
+	}
+}
+
+// Each type of spore must grow the player by its own share of mass, with plain spores worth their usual mass
+func TestSporeTypeMass(t *testing.T) {
+	for sporeType := objects.SporeTypePlain; sporeType < objects.SporeTypeCount; sporeType++ {
+		player := &objects.Player{Name: "test", Radius: 20}
+		game, client := newTestGame(player)
+		spore := &objects.Spore{X: 10, Radius: 5, Type: sporeType}
+		sporeId := client.SharedGameObjects().Spores.Add(spore)
+
+		game.HandleMessage(client.Id(), &packets.Packet_SporeConsumed{SporeConsumed: &packets.SporeConsumedMessage{SporeId: sporeId}})
+
+		gained := radiusToMass(player.Radius) - radiusToMass(20)
+		expected := radiusToMass(spore.Radius) * sporeType.MassMultiplier()
+
+		if math.Abs(gained - expected) > 1e-6 {
+			t.Errorf("Consuming a spore of type %d gained %f mass instead of %f", sporeType, gained, expected)
+		}
+	}
+}
+
+// A player too small for hazards must pass over one unharmed and leave it be, while a big one must pop it, lose the
+// penalty share of their mass and be told it's gone
+func TestHazards(t *testing.T) {
+	const penalty float64 = 0.3
+
+	for _, radius := range []float64{30, 100} {
+		player := &objects.Player{Name: "test", Radius: radius}
+		game, client := newTestGame(player, func(config *server.ServerConfig) {
+			config.MaxHazards = 1
+			config.HazardMinPlayerRadius = 60
+			config.HazardPenalty = penalty
+		})
+		hazards := client.SharedGameObjects().Hazards
+		hazardId := hazards.Add(&objects.Hazard{X: 20, Radius: 40})
+
+		game.hitHazards()
+
+		_, hazardLeft := hazards.Get(hazardId)
+		removed := false
+
+		for _, packet := range client.Sent() {
+			removed = removed || packet.GetHazardRemoved().GetHazardId() == hazardId
+		}
+
+		if radius <= 60 {
+			if player.Radius != radius || !hazardLeft || removed {
+				t.Errorf("A player of radius %f too small for hazards became radius %f touching one, which was removed: %t", radius, player.Radius, !hazardLeft)
+			}
+
+			continue
+		}
+
+		expectedRadius := massToRadius(radiusToMass(radius) * (1 - penalty))
+
+		if math.Abs(player.Radius - expectedRadius) > 1e-9 || hazardLeft || !removed {
+			t.Errorf("A player of radius %f became radius %f instead of %f popping a hazard, which was removed: %t and sent as removed: %t", radius, player.Radius, expectedRadius, !hazardLeft, removed)
+		}
+	}
+}
+
+// A player heading straight past the center must be pulled in toward it with drift, and keep their distance without it
+func TestDrift(t *testing.T) {
+	const ticks int = 20
+	const startX float64 = 1000
+
+	for _, drift := range []float64{0, 50} {
+		player := &objects.Player{Name: "test", X: startX, Radius: 20, Direction: math.Pi / 2}
+		game, _ := newTestGame(player, func(config *server.ServerConfig) { config.DriftStrength = drift })
+		tickTime := time.Now()
+		lastX := player.X
+
+		for range ticks {
+			tickTime = tickTime.Add(server.TickInterval)
+			game.movePlayer(server.TickDelta, tickTime)
+
+			if drift > 0 && player.X >= lastX {
+				t.Fatalf("A player drifting at %f moved from %f to %f rather than toward the center", drift, lastX, player.X)
+			}
+
+			lastX = player.X
+		}
+
+		if drift == 0 && math.Abs(player.X - startX) > 1e-6 {
+			t.Errorf("A player with no drift moved from %f to %f toward the center", startX, player.X)
+		}
+
+		if drift > 0 && startX - player.X < drift * server.TickDelta * float64(ticks) / 2 {
+			t.Errorf("A player drifting at %f only moved from %f to %f in %d ticks", drift, startX, player.X, ticks)
+		}
+	}
+}
+
+// Over several rounds of zone damage, a player outside the zone must keep shrinking while one inside keeps their size
+func TestZoneDamage(t *testing.T) {
+	const zoneRadius float64 = 500
+	const rounds int = 3
+
+	config := server.NewServerConfig()
+	config.DriftStrength = 0
+	config.AfkThreshold = 0
+	config.MassDecay = 0
+	config.ZoneDamage = 0.1
+	config.ZoneDamageInterval = time.Second
+
+	client := servertest.NewFakeClient(1, config)
+	client.SharedGameObjects().Zone.SetRadius(zoneRadius)
+	inside := &objects.Player{Name: "inside", Radius: 30}
+	outside := &objects.Player{Name: "outside", X: 2 * zoneRadius, Radius: 30}
+	insideGame := newTestGameOn(client, inside)
+	outsideGame, _ := newTestPeer(client, 2, outside)
+
+	// Head upward, which keeps each player on their side of the zone's edge
+	inside.Direction = math.Pi / 2
+	outside.Direction = math.Pi / 2
+	lastRadius := outside.Radius
+
+	// The first round of damage is due an interval after the first step
+	tickTime := time.Now()
+	insideGame.Step(server.TickDelta, tickTime)
+	outsideGame.Step(server.TickDelta, tickTime)
+
+	for range rounds {
+		for range int(config.ZoneDamageInterval / server.TickInterval) {
+			tickTime = tickTime.Add(server.TickInterval)
+			insideGame.Step(server.TickDelta, tickTime)
+			outsideGame.Step(server.TickDelta, tickTime)
+		}
+
+		if outside.Radius >= lastRadius {
+			t.Errorf("A player outside the zone went from radius %f to %f over a round of zone damage", lastRadius, outside.Radius)
+		}
+
+		lastRadius = outside.Radius
+	}
+
+	if inside.Radius != 30 {
+		t.Errorf("A player inside the zone went from radius 30 to %f", inside.Radius)
+	}
+}
+
+// Leaving the game must save the life to the match history with how long it lasted and the biggest the player got,
+// not their size when they left
+func TestMatchRecorded(t *testing.T) {
+	const lifetime time.Duration = 3 * time.Second
+
+	player := &objects.Player{Name: "test", Radius: 20}
+	game, client := newTestGame(player)
+	dbPool, dbTx, err := servertest.NewDatabase(0)
+
+	if err != nil {
+		t.Fatalf("Couldn't open a database: %v", err)
+	}
+
+	defer dbPool.Close()
+
+	client.SetDbTransaction(dbTx)
+	user := createTestUser(t, dbTx.Queries, "tester", "secret")
+	game.userId = user.ID
+	game.joinedAt = time.Now().Add(-lifetime)
+	game.peakMass = radiusToMass(player.Radius)
+
+	sporeId := client.SharedGameObjects().Spores.Add(&objects.Spore{X: 10, Radius: 10})
+	game.HandleMessage(client.Id(), &packets.Packet_SporeConsumed{SporeConsumed: &packets.SporeConsumedMessage{SporeId: sporeId}})
+	peakMass := radiusToMass(player.Radius)
+	game.loseMass(0.5)
+
+	client.SetState(nil)
+	dbTx.Matches.Close()
+
+	matches, err := dbTx.Queries.GetRecentMatches(t.Context(), db.GetRecentMatchesParams{UserID: user.ID, Limit: 10})
+
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("Leaving the game saved matches %v with error %v instead of one match", matches, err)
+	}
+
+	match := matches[0]
+
+	if math.Abs(match.PeakMass - peakMass) > 1e-9 {
+		t.Errorf("The match was saved with peak mass %f instead of %f", match.PeakMass, peakMass)
+	}
+
+	if elapsed := time.Duration(match.DurationMs) * time.Millisecond; elapsed < lifetime || elapsed > lifetime + time.Second {
+		t.Errorf("A life of %s was saved as lasting %s", lifetime, elapsed)
+	}
+
+	if match.EndReason != matchEndLeft {
+		t.Errorf("Leaving the game was saved with end reason %q", match.EndReason)
+	}
+}
+
+// A player who hasn't changed direction for the AFK threshold must lose mass over the following seconds, while one
+// who keeps steering keeps their size
+func TestAfkDecay(t *testing.T) {
+	const threshold time.Duration = time.Minute
+	const seconds int = 3
+
+	config := server.NewServerConfig()
+	config.DriftStrength = 0
+	config.MassDecay = 0
+	config.AfkThreshold = threshold
+	config.AfkDecay = 0.1
+
+	client := servertest.NewFakeClient(1, config)
+	active := &objects.Player{Name: "active", Radius: 30}
+	afk := &objects.Player{Name: "afk", X: 500, Radius: 30}
+	activeGame := newTestGameOn(client, active)
+	afkGame, _ := newTestPeer(client, 2, afk)
+	afkGame.lastDirectionChange = time.Now().Add(-2 * threshold)
+
+	// Keep the update loop from starting, so the steps here are the only movement
+	activeGame.cancelPlayerUpdateLoop = func() {}
+	tickTime := time.Now()
+
+	for second := range seconds + 1 {
+		activeGame.HandleMessage(client.Id(), &packets.Packet_PlayerDirection{PlayerDirection: &packets.PlayerDirectionMessage{Direction: float64(second)}})
+
+		for range int(time.Second / server.TickInterval) {
+			tickTime = tickTime.Add(server.TickInterval)
+			activeGame.Step(server.TickDelta, tickTime)
+			afkGame.Step(server.TickDelta, tickTime)
+		}
+	}
+
+	if active.Radius != 30 {
+		t.Errorf("A player who kept steering went from radius 30 to %f", active.Radius)
+	}
+
+	expectedRadius := massToRadius(radiusToMass(30) * math.Pow(1 - config.AfkDecay, float64(seconds)))
+
+	if math.Abs(afk.Radius - expectedRadius) > 1e-9 {
+		t.Errorf("An AFK player went from radius 30 to %f in %d seconds instead of %f", afk.Radius, seconds, expectedRadius)
+	}
+}
+
+// Entering the game with a name that wouldn't pass registration must swap it for a default one, both in what the
+// client is told and in what everyone else is sent
+func TestInvalidNameReplaced(t *testing.T) {
+	for _, name := range []string{"", " padded ", strings.Repeat("a", 21), "valid"} {
+		client := servertest.NewFakeClient(7, server.NewServerConfig())
+		game := &InGame{player: &objects.Player{Name: name}}
+		client.SetState(game)
+		t.Cleanup(func() { client.Close("") })
+
+		expected := name
+
+		if validateUserName(name) != nil {
+			expected = "Player7"
+		}
+
+		if sent := client.Sent()[0].GetPlayer().GetName(); sent != expected {
+			t.Errorf("Entering with name %q sent the client name %q instead of %q", name, sent, expected)
+		}
+
+		game.syncPlayer(0, time.Now())
+		broadcasts := client.Broadcasts()
+
+		if len(broadcasts) != 1 || broadcasts[0].GetPlayer().GetName() != expected {
+			t.Errorf("Entering with name %q broadcast %v instead of name %q", name, broadcasts, expected)
+		}
+	}
+}
+
+// Consuming spores and players must be counted towards the player's stats, which the client gets back on asking
+func TestStats(t *testing.T) {
+	player := &objects.Player{Name: "test", Radius: 40}
+	game, client := newTestGame(player)
+	game.joinedAt = time.Now().Add(-time.Minute)
+	sharedObjects := client.SharedGameObjects()
+
+	for _, x := range []float64{10, -10} {
+		sporeId := sharedObjects.Spores.Add(&objects.Spore{X: x, Radius: 5})
+		game.HandleMessage(client.Id(), &packets.Packet_SporeConsumed{SporeConsumed: &packets.SporeConsumedMessage{SporeId: sporeId}})
+	}
+
+	sharedObjects.Players.Add(&objects.Player{Name: "victim", Y: 10, Radius: 10}, 2)
+	game.HandleMessage(client.Id(), &packets.Packet_PlayerConsumed{PlayerConsumed: &packets.PlayerConsumedMessage{PlayerId: 2}})
+
+	if player.SporesEaten != 2 || player.PlayersEaten != 1 {
+		t.Fatalf("Consuming two spores and a player counted %d spores and %d players eaten", player.SporesEaten, player.PlayersEaten)
+	}
+
+	game.HandleMessage(client.Id(), &packets.Packet_Stats{Stats: &packets.StatsMessage{}})
+	stats := lastSent(client).GetStats()
+
+	if stats.GetSporesEaten() != 2 || stats.GetPlayersEaten() != 1 || stats.GetTimeAlive() < time.Minute.Seconds() {
+		t.Errorf("Asking for stats after a minute of eating two spores and a player was answered with %v", stats)
+	}
+}
+
+// Every player moved in the same tick of the room's simulation must be sent with that tick's timestamp
+func TestSimulationTickTime(t *testing.T) {
+	player := &objects.Player{Name: "test", Radius: 20, Speed: 100}
+	game, client := newTestGame(player, func(config *server.ServerConfig) { config.SharedTick = true })
+	otherGame, otherClient := newTestPeer(client, 2, &objects.Player{Name: "other", X: 100, Radius: 20, Speed: 100})
+	game.capabilities = []string{packets.CapabilityPlayersBatch}
+
+	game.HandleMessage(client.Id(), &packets.Packet_PlayerDirection{PlayerDirection: &packets.PlayerDirectionMessage{Direction: 0}})
+	otherGame.HandleMessage(otherClient.Id(), &packets.Packet_PlayerDirection{PlayerDirection: &packets.PlayerDirectionMessage{Direction: math.Pi}})
+
+	// An hour off the clock, so only a timestamp taken from the tick itself can match
+	tickTime := time.Now().Add(time.Hour)
+	client.Room().Simulation.Tick(server.TickDelta, tickTime)
+	players := lastSent(client).GetPlayersBatch().GetPlayers()
+
+	if len(players) != 2 {
+		t.Fatalf("A tick of two players sent %v", players)
+	}
+
+	for _, update := range players {
+		if update.TickTime != tickTime.UnixMilli() {
+			t.Errorf("Player %d was sent with tick time %d instead of the tick's %d", update.Id, update.TickTime, tickTime.UnixMilli())
+		}
+	}
+}
+
+// While paused, the room's simulation must leave players where they are and consumptions must be refused. Resuming
+// must get players moving again
+func TestPause(t *testing.T) {
+	player := &objects.Player{Name: "test", Radius: 20, Speed: 100}
+	game, client := newTestGame(player, func(config *server.ServerConfig) {
+		config.SharedTick = true
+		config.DriftStrength = 0
+		config.Paused = true
+	})
+	game.HandleMessage(client.Id(), &packets.Packet_PlayerDirection{PlayerDirection: &packets.PlayerDirectionMessage{Direction: 0}})
+
+	sporeId := client.SharedGameObjects().Spores.Add(&objects.Spore{Y: 10, Radius: 5})
+	game.HandleMessage(client.Id(), &packets.Packet_SporeConsumed{SporeConsumed: &packets.SporeConsumedMessage{SporeId: sporeId}})
+
+	if player.SporesEaten != 0 {
+		t.Error("A spore was consumed while paused")
+	}
+
+	var paused atomic.Bool
+	paused.Store(true)
+	ticked := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(t.Context())
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		client.Room().Simulation.Run(ctx, time.Millisecond, paused.Load, func(int) {
+			select {
+				case ticked <- struct{}{}:
+				default:
+			}
+		})
+	}()
+
+	// Plenty of would-be ticks go by while paused
+	time.Sleep(50 * time.Millisecond)
+
+	select {
+		case <-ticked:
+			t.Error("The simulation ticked while paused")
+		default:
+	}
+
+	paused.Store(false)
+
+	select {
+		case <-ticked:
+		case <-time.After(5 * time.Second):
+			t.Fatal("The simulation didn't tick after resuming")
+	}
+
+	cancel()
+	<-stopped
+
+	if player.X <= 0 {
+		t.Errorf("Resuming left the player at %f instead of moving them on", player.X)
+	}
+}
+
+// A player joining must be told the room's game mode along with the rules the client needs to show it
+func TestGameModeSent(t *testing.T) {
+	config := server.NewServerConfig()
+	config.GameMode = "battle-royale"
+	config.ConsumeRatio = 1.25
+	config.ConsumeMode = server.ConsumeModeEngulf
+	client := servertest.NewFakeClient(1, config)
+	client.SharedGameObjects().Zone.SetRadius(1000)
+	client.SetState(&InGame{player: &objects.Player{Name: "test"}})
+	t.Cleanup(func() { client.Close("") })
+
+	for _, packet := range client.Sent() {
+		if mode := packet.GetGameMode(); mode != nil {
+			if mode.Name != "battle-royale" || !mode.ZoneEnabled || mode.ConsumeRatio != 1.25 || mode.ConsumeMode != server.ConsumeModeEngulf {
+				t.Errorf("A player joining was sent game mode %v", mode)
+			}
+
+			return
+		}
+	}
+
+	t.Errorf("A player joining was sent %v without the game mode", client.Sent())
+}
+
+// A rejected consumption must be kept in the client's event log with the reason and where the player was
+func TestRejectedConsumptionRecorded(t *testing.T) {
+	player := &objects.Player{Name: "test", X: 30, Y: 40, Radius: 20}
+	game, client := newTestGame(player, func(config *server.ServerConfig) { config.EventLogSize = 8 })
+	sporeId := client.SharedGameObjects().Spores.Add(&objects.Spore{X: 1000, Radius: 5})
+
+	game.HandleMessage(client.Id(), &packets.Packet_SporeConsumed{SporeConsumed: &packets.SporeConsumedMessage{SporeId: sporeId}})
+
+	events := client.EventLog().Events()
+
+	if len(events) != 1 {
+		t.Fatalf("Rejecting a consumption recorded events %v", events)
+	}
+
+	if event := events[0]; event.Kind != "rejected" || !strings.Contains(event.Detail, "too far") || event.X != 30 || event.Y != 40 {
+		t.Errorf("Rejecting a consumption of a far away spore recorded %+v", event)
+	}
+}
+
+// Respawning after being consumed by a player far bigger must start with less mass than after a close fight, and the
+// player must come back with that share of the starting mass
+func TestRespawnMass(t *testing.T) {
+	const minRespawnMass float64 = 0.25
+	const ratio float64 = 1.5
+
+	player := &objects.Player{Name: "test", Radius: 20}
+	game, _ := newTestGame(player, func(config *server.ServerConfig) {
+		config.MinRespawnMass = minRespawnMass
+		config.ConsumeRatio = ratio
+	})
+	victimMass := radiusToMass(player.Radius)
+	// The radius the consumer grew to, having been the given multiple of the victim's mass
+	grownRadius := func(multiple float64) float64 { return massToRadius(victimMass * (multiple + 1)) }
+
+	if scale := game.respawnMassScale(grownRadius(ratio)); math.Abs(scale - 1) > 1e-9 {
+		t.Errorf("Losing a close fight gave a respawn mass scale of %f instead of 1", scale)
+	}
+
+	whaleScale := game.respawnMassScale(grownRadius(10))
+
+	if expected := minRespawnMass + (1 - minRespawnMass) * ratio / 10; math.Abs(whaleScale - expected) > 1e-9 {
+		t.Errorf("Being fed to a player 10 times as massive gave a respawn mass scale of %f instead of %f", whaleScale, expected)
+	}
+
+	if scale := game.respawnMassScale(grownRadius(1e9)); scale < minRespawnMass || scale > minRespawnMass + 1e-6 {
+		t.Errorf("Being fed to a huge player gave a respawn mass scale of %f instead of about the minimum %f", scale, minRespawnMass)
+	}
+
+	client := servertest.NewFakeClient(2, server.NewServerConfig())
+	client.SetState(&InGame{player: &objects.Player{Name: "respawned"}, startMassScale: whaleScale})
+	t.Cleanup(func() { client.Close("") })
+	respawned, _ := client.SharedGameObjects().Players.Get(client.Id())
+	startMass := radiusToMass(client.Config().PlayerStartRadius)
+
+	if mass := radiusToMass(respawned.Radius); math.Abs(mass - startMass * whaleScale) > 1e-6 {
+		t.Errorf("Respawning with a mass scale of %f gave mass %f instead of %f", whaleScale, mass, startMass * whaleScale)
+	}
+}
+
+// The spores sent to the client so far
+func sentSpores(client *servertest.FakeClient) int {
+	spores := 0
+
+	for _, packet := range client.Sent() {
+		spores += len(packet.GetSporesBatch().GetSpores())
+	}
+
+	return spores
+}
+
+// A client which asks for the world when it's ready must not be sent any spores until it does, and then all of them
+func TestWorldWaitsForRequest(t *testing.T) {
+	const spores int = 100
+
+	client := servertest.NewFakeClient(1, server.NewServerConfig())
+
+	for i := range spores {
+		client.SharedGameObjects().Spores.Add(&objects.Spore{X: float64(i), Radius: 5})
+	}
+
+	game := &InGame{player: &objects.Player{Name: "test"}, capabilities: []string{packets.CapabilityRequestWorld}}
+	client.SetState(game)
+	t.Cleanup(func() { client.Close("") })
+
+	// Give any spores sent in the background time to arrive
+	time.Sleep(100 * time.Millisecond)
+
+	if sent := sentSpores(client); sent > 0 {
+		t.Fatalf("%d spores were sent before the client asked for the world", sent)
+	}
+
+	game.HandleMessage(client.Id(), &packets.Packet_RequestWorld{RequestWorld: &packets.RequestWorldMessage{}})
+
+	for deadline := time.Now().Add(5 * time.Second); sentSpores(client) < spores && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if sent := sentSpores(client); sent != spores {
+		t.Errorf("Asking for the world sent %d spores instead of %d", sent, spores)
+	}
+}
+
+// Removals of spores and players must only be sent to the clients which were told about them in the first place
+func TestRemovalsOnlyToKnowingClients(t *testing.T) {
+	near, nearClient := newTestGame(&objects.Player{Name: "near", Radius: 20}, func(config *server.ServerConfig) {
+		config.SporeSyncRadius = 500
+		config.PlayerViewRange = 500
+	})
+	far, farClient := newTestPeer(nearClient, 2, &objects.Player{Name: "far", X: 2000, Radius: 20})
+	games := map[*InGame]*servertest.FakeClient{near: nearClient, far: farClient}
+
+	sporePacket := packets.NewSpore(7, &objects.Spore{X: 100, Radius: 5})
+	playerPacket := packets.NewPlayer(3, &objects.Player{Name: "other", Y: 100, Radius: 20})
+
+	for game := range games {
+		game.HandleMessage(0, sporePacket)
+		game.HandleMessage(3, playerPacket)
+	}
+
+	for game, client := range games {
+		client.ClearRecorded()
+		game.HandleMessage(0, packets.NewSporeRemoved(7))
+		game.HandleMessage(3, packets.NewPlayerLeft(3))
+	}
+
+	if sent := nearClient.Sent(); len(sent) != 2 || sent[0].GetSporeRemoved() == nil || sent[1].GetPlayerLeft() == nil {
+		t.Errorf("The client which knew about the spore and player was sent %v instead of both removals", sent)
+	}
+
+	if sent := farClient.Sent(); len(sent) > 0 {
+		t.Errorf("The client which never heard of the spore or player was sent %v", sent)
+	}
+}
+
+// With chat disabled, a chat message must not be broadcast, its sender must be told chat is off, and messages from
+// others mustn't be passed on either
+func TestChatDisabled(t *testing.T) {
+	game, client := newTestGame(&objects.Player{Name: "test", Radius: 20}, func(config *server.ServerConfig) { config.ChatDisabled = true })
+
+	game.HandleMessage(client.Id(), &packets.Packet_Chat{Chat: &packets.ChatMessage{Msg: "Hello"}})
+
+	if broadcasts := client.Broadcasts(); len(broadcasts) > 0 {
+		t.Errorf("A chat message was broadcast with chat disabled: %v", broadcasts)
+	}
+
+	if notice := lastSent(client); len(client.Sent()) != 1 || !strings.Contains(notice.GetChat().GetMsg(), "disabled") || notice.SenderId != 0 {
+		t.Errorf("The sender of a chat message with chat disabled was sent %v instead of a notice from the server", client.Sent())
+	}
+
+	client.ClearRecorded()
+	game.HandleMessage(2, &packets.Packet_Chat{Chat: &packets.ChatMessage{Msg: "Hello"}})
+
+	if sent := client.Sent(); len(sent) > 0 {
+		t.Errorf("Another player's chat message was passed on with chat disabled: %v", sent)
+	}
+}
+
+// The consumption broadcast, and what peers are sent of it, must carry the victim's radius from before they were
+// consumed alongside the consumer's new radius, so clients can animate the victim shrinking away
+func TestConsumptionCarriesVictimRadius(t *testing.T) {
+	const victimRadius float64 = 10
+
+	player := &objects.Player{Name: "test", Radius: 40}
+	game, client := newTestGame(player)
+	peer, peerClient := newTestPeer(client, 3, &objects.Player{Name: "peer", X: 100, Radius: 20})
+	victim := &objects.Player{Name: "victim", Y: 10, Radius: victimRadius}
+	client.SharedGameObjects().Players.Add(victim, 2)
+	peer.HandleMessage(2, packets.NewPlayer(2, victim))
+	peerClient.ClearRecorded()
+
+	game.HandleMessage(client.Id(), &packets.Packet_PlayerConsumed{PlayerConsumed: &packets.PlayerConsumedMessage{PlayerId: 2}})
+	broadcasts := client.Broadcasts()
+
+	if len(broadcasts) != 1 || broadcasts[0].GetPlayerConsumed() == nil {
+		t.Fatalf("Consuming a player broadcast %v instead of the consumption", broadcasts)
+	}
+
+	consumed := broadcasts[0].GetPlayerConsumed()
+
+	if consumed.GetVictimRadius() != victimRadius || consumed.GetNewRadius() != player.Radius || player.Radius <= 40 {
+		t.Errorf("The consumption broadcast carried victim radius %f and new radius %f instead of %f and %f", consumed.GetVictimRadius(), consumed.GetNewRadius(), victimRadius, player.Radius)
+	}
+
+	peer.HandleMessage(client.Id(), broadcasts[0].Msg)
+
+	if sent := lastSent(peerClient).GetPlayerConsumed(); sent.GetVictimRadius() != victimRadius || sent.GetNewRadius() != player.Radius {
+		t.Errorf("A peer was sent %v instead of the consumption with the victim's radius", sent)
+	}
+}
+
+// Panics the given number of times it's asked to broadcast, as a bug in the update loop would, then broadcasts as usual
+type panickingClient struct {
+	*servertest.FakeClient
+	panicsLeft atomic.Int64
+}
+
+func (client *panickingClient) Broadcast(message packets.Msg) {
+	if client.panicsLeft.Add(-1) >= 0 {
+		panic("broadcast failed")
+	}
+
+	client.FakeClient.Broadcast(message)
+}
+
+// A player whose update loop panics on the given client, with the logs of the state going to the given buffer
+func newPanickingGame(panics int64, logs *bytes.Buffer) (*InGame, *panickingClient) {
+	client := &panickingClient{FakeClient: servertest.NewFakeClient(1, server.NewServerConfig())}
+	client.panicsLeft.Store(panics)
+	client.SetLogHandler(slog.NewTextHandler(logs, nil))
+	game := newTestGameOn(client.FakeClient, &objects.Player{Name: "test", Radius: 20, Direction: 1})
+	game.SetClient(client)
+
+	return game, client
+}
+
+// The player update loop must log a panic and carry on ticking, leaving the client open
+func TestUpdateLoopRecovers(t *testing.T) {
+	var logs bytes.Buffer
+	game, client := newPanickingGame(2, &logs)
+	ctx, cancel := context.WithCancel(t.Context())
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		game.superviseUpdatePlayerLoop(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for len(client.Broadcasts()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(server.TickInterval)
+	}
+
+	cancel()
+	<-stopped
+
+	if ticks := len(client.Broadcasts()); ticks < 3 {
+		t.Errorf("The update loop only sent %d updates after panicking twice", ticks)
+	}
+
+	if panicsLogged := strings.Count(logs.String(), "Player update loop panicked"); panicsLogged != 2 {
+		t.Errorf("Logged %d of the update loop's 2 panics:\n%s", panicsLogged, logs.String())
+	}
+
+	if reason := client.CloseReason(); reason != "" {
+		t.Errorf("The client was closed with %q after the update loop recovered", reason)
+	}
+}
+
+// An update loop which keeps panicking must be given up on and the client closed
+func TestUpdateLoopGivesUp(t *testing.T) {
+	var logs bytes.Buffer
+	game, client := newPanickingGame(math.MaxInt64, &logs)
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		game.superviseUpdatePlayerLoop(t.Context())
+	}()
+
+	select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Fatal("The update loop kept restarting however often it panicked")
+	}
+
+	if reason := client.CloseReason(); reason != server.CloseReasonUpdateLoopFailed {
+		t.Errorf("The client was closed with %q instead of %q", reason, server.CloseReasonUpdateLoopFailed)
+	}
+
+	if !strings.Contains(logs.String(), "giving up") {
+		t.Errorf("Giving up on the update loop wasn't logged:\n%s", logs.String())
+	}
+}
+
+// With buffered inputs, two direction changes between ticks must each be followed for the part of the tick after they
+// arrived, where without buffering only the last one counts
+func TestBufferedInputs(t *testing.T) {
+	const delta float64 = 0.05
+	const gap time.Duration = 20 * time.Millisecond
+
+	for _, bufferInputs := range []bool{false, true} {
+		player := &objects.Player{Name: "test", Radius: 20, Direction: math.Pi}
+		game, client := newTestGame(player, func(config *server.ServerConfig) {
+			config.BufferInputs = bufferInputs
+			config.DriftStrength = 0
+		})
+
+		// Keep the update loop from starting, so the ticks here are the only movement
+		game.cancelPlayerUpdateLoop = func() {}
+		game.lastTickTime = time.Now()
+
+		// East for about the first half of the tick, then north
+		game.HandleMessage(client.Id(), &packets.Packet_PlayerDirection{PlayerDirection: &packets.PlayerDirectionMessage{Direction: 0}})
+		time.Sleep(gap)
+		game.HandleMessage(client.Id(), &packets.Packet_PlayerDirection{PlayerDirection: &packets.PlayerDirectionMessage{Direction: math.Pi / 2}})
+		time.Sleep(gap)
+		game.syncPlayer(delta, time.Now())
+
+		step := player.Speed * delta
+
+		if bufferInputs && (player.X < 0.2 * step || player.Y < 0.2 * step) {
+			t.Errorf("Heading east then north within a tick with buffered inputs moved the player to (%f, %f) in a step of %f", player.X, player.Y, step)
+		}
+
+		if !bufferInputs && (math.Abs(player.X) > 1e-9 || math.Abs(player.Y - step) > 1e-9) {
+			t.Errorf("Heading east then north within a tick without buffered inputs moved the player to (%f, %f) instead of (0, %f)", player.X, player.Y, step)
+		}
+
+		if player.Direction != math.Pi / 2 {
+			t.Errorf("The player was left heading %f instead of in the last direction sent with buffered inputs %t", player.Direction, bufferInputs)
+		}
+	}
+}
+
+// The IDs of the spores sent to the client in batches so far
+func sentSporeIds(client *servertest.FakeClient) map[uint64]bool {
+	ids := make(map[uint64]bool)
+
+	for _, packet := range client.Sent() {
+		for _, spore := range packet.GetSporesBatch().GetSpores() {
+			ids[spore.GetId()] = true
+		}
+	}
+
+	return ids
+}
+
+// With a spore sync radius, joining must only send the spores within it, and the far ones must follow once the player
+// gets close to them
+func TestSporeSyncRadius(t *testing.T) {
+	const syncRadius float64 = 500
+
+	player := &objects.Player{Name: "test", Radius: 20}
+	game, client := newTestGame(player, func(config *server.ServerConfig) { config.SporeSyncRadius = syncRadius })
+	spores := client.SharedGameObjects().Spores
+	near := map[uint64]bool{}
+	far := map[uint64]bool{}
+
+	for i := range 50 {
+		near[spores.Add(&objects.Spore{X: float64(i) * 9, Y: 100, Radius: 5})] = true
+		far[spores.Add(&objects.Spore{X: 3000 + float64(i) * 9, Y: 100, Radius: 5})] = true
+	}
+
+	game.worldSent.Store(true)
+	game.sendInitialSpores(80, 0)
+
+	if sent := sentSporeIds(client); !maps.Equal(sent, near) {
+		t.Errorf("Joining sent %d spores, %d of them far away, instead of the %d within the sync radius", len(sent), len(sent) - len(near), len(near))
+	}
+
+	client.ClearRecorded()
+	player.X = 3200
+	game.streamNearbySpores()
+
+	if sent := sentSporeIds(client); !maps.Equal(sent, far) {
+		t.Errorf("Getting close to the far spores sent %d spores instead of the %d now within the sync radius", len(sent), len(far))
+	}
+}
+
+// Right after consuming another player, a player must move faster by the boost, easing back to their usual speed over
+// the boost's duration and moving at exactly that speed once it's over
+func TestKillSpeedBoost(t *testing.T) {
+	const delta float64 = 0.05
+	const boost float64 = 0.5
+	const duration time.Duration = 2 * time.Second
+
+	player := &objects.Player{Name: "test", Radius: 40}
+	game, client := newTestGame(player, func(config *server.ServerConfig) {
+		config.KillSpeedBoost = boost
+		config.KillSpeedBoostDuration = duration
+		config.DriftStrength = 0
+	})
+	client.SharedGameObjects().Players.Add(&objects.Player{Name: "victim", Y: 10, Radius: 10}, 2)
+
+	game.HandleMessage(client.Id(), &packets.Packet_PlayerConsumed{PlayerConsumed: &packets.PlayerConsumedMessage{PlayerId: 2}})
+
+	if game.lastPlayerConsumed.IsZero() {
+		t.Fatalf("The consumption wasn't accepted: %v", client.Sent())
+	}
+
+	baseline := radiusToSpeed(client.Config(), player.Radius) * delta
+	ticks := []struct {
+		after time.Duration
+		expected float64
+	}{
+		{0, baseline * (1 + boost)},
+		{duration / 2, baseline * (1 + boost / 2)},
+		{duration, baseline},
+		{2 * duration, baseline},
+	}
+
+	for _, tick := range ticks {
+		x := player.X
+		game.movePlayer(delta, game.lastPlayerConsumed.Add(tick.after))
+
+		if moved := player.X - x; math.Abs(moved - tick.expected) > 1e-9 {
+			t.Errorf("%v after the kill the player moved %f in a tick instead of %f", tick.after, moved, tick.expected)
+		}
+	}
+}
+
+// A lone early player must stop growing from spores at the fair start radius, and grow past it again once enough
+// players have joined
+func TestFairStart(t *testing.T) {
+	const maxRadius float64 = 40
+
+	player := &objects.Player{Name: "test", Radius: 38}
+	game, client := newTestGame(player, func(config *server.ServerConfig) {
+		config.FairStartPlayers = 3
+		config.FairStartMaxRadius = maxRadius
+	})
+	sharedObjects := client.SharedGameObjects()
+
+	eatSpore := func() {
+		sporeId := sharedObjects.Spores.Add(&objects.Spore{X: 10, Radius: 10})
+		game.HandleMessage(client.Id(), &packets.Packet_SporeConsumed{SporeConsumed: &packets.SporeConsumedMessage{SporeId: sporeId}})
+
+		if _, left := sharedObjects.Spores.Get(sporeId); left {
+			t.Fatalf("The spore wasn't consumed: %v", client.Sent())
+		}
+	}
+
+	for range 5 {
+		eatSpore()
+	}
+
+	if player.Radius != maxRadius {
+		t.Fatalf("A lone player grew to radius %f from spores instead of stopping at %f", player.Radius, maxRadius)
+	}
+
+	sharedObjects.Players.Add(&objects.Player{Name: "second", X: 1000, Radius: 20}, 2)
+	eatSpore()
+
+	if player.Radius != maxRadius {
+		t.Errorf("With 2 of 3 players in, a player grew to radius %f from spores instead of stopping at %f", player.Radius, maxRadius)
+	}
+
+	sharedObjects.Players.Add(&objects.Player{Name: "third", X: -1000, Radius: 20}, 3)
+	eatSpore()
+
+	if player.Radius <= maxRadius {
+		t.Errorf("Once enough players were in, eating a spore left the player at radius %f", player.Radius)
+	}
+}
+
+// With a consumption view range, a client near a consumption must be told about it while a distant one isn't, only
+// hearing that the spore is gone and nothing of the player until they respawn
+func TestConsumptionViewRange(t *testing.T) {
+	consumer, consumerClient := newTestGame(&objects.Player{Name: "consumer", Radius: 40}, func(config *server.ServerConfig) {
+		config.ConsumptionViewRange = 1000
+	})
+	near, nearClient := newTestPeer(consumerClient, 2, &objects.Player{Name: "near", X: 500, Radius: 20})
+	far, farClient := newTestPeer(consumerClient, 3, &objects.Player{Name: "far", X: 5000, Radius: 20})
+	sharedObjects := consumerClient.SharedGameObjects()
+
+	spore := &objects.Spore{X: 10, Radius: 5}
+	sporeId := sharedObjects.Spores.Add(spore)
+	victim := &objects.Player{Name: "victim", Y: 10, Radius: 10}
+	sharedObjects.Players.Add(victim, 4)
+
+	for _, game := range []*InGame{consumer, near, far} {
+		game.HandleMessage(0, packets.NewSpore(sporeId, spore))
+		game.HandleMessage(4, packets.NewPlayer(4, victim))
+	}
+
+	consumerClient.ClearRecorded()
+	nearClient.ClearRecorded()
+	farClient.ClearRecorded()
+
+	consumer.HandleMessage(consumerClient.Id(), &packets.Packet_SporeConsumed{SporeConsumed: &packets.SporeConsumedMessage{SporeId: sporeId}})
+	consumer.HandleMessage(consumerClient.Id(), &packets.Packet_PlayerConsumed{PlayerConsumed: &packets.PlayerConsumedMessage{PlayerId: 4}})
+	broadcasts := consumerClient.Broadcasts()
+
+	if len(broadcasts) != 2 {
+		t.Fatalf("Consuming a spore and a player broadcast %v", broadcasts)
+	}
+
+	for _, broadcast := range broadcasts {
+		near.HandleMessage(consumerClient.Id(), broadcast.Msg)
+		far.HandleMessage(consumerClient.Id(), broadcast.Msg)
+	}
+
+	if sent := nearClient.Sent(); len(sent) != 2 || sent[0].GetSporeConsumed() == nil || sent[1].GetPlayerConsumed() == nil {
+		t.Errorf("A client near the consumptions was sent %v instead of both of them", sent)
+	}
+
+	if sent := farClient.Sent(); len(sent) != 1 || sent[0].GetSporeRemoved().GetSporeId() != sporeId {
+		t.Errorf("A distant client was sent %v instead of only the spore's removal", sent)
+	}
+}
+
+// A player who has only just spawned must have their attempts to consume another player turned down until the spawn
+// grace has passed
+func TestSpawnConsumeGrace(t *testing.T) {
+	const grace time.Duration = time.Minute
+
+	player := &objects.Player{Name: "test", Radius: 40}
+	game, client := newTestGame(player, func(config *server.ServerConfig) {
+		config.SpawnConsumeGrace = grace
+		config.EventLogSize = 8
+	})
+	players := client.SharedGameObjects().Players
+	players.Add(&objects.Player{Name: "victim", Y: 10, Radius: 10}, 2)
+	game.joinedAt = time.Now()
+
+	game.HandleMessage(client.Id(), &packets.Packet_PlayerConsumed{PlayerConsumed: &packets.PlayerConsumedMessage{PlayerId: 2}})
+
+	if _, alive := players.Get(2); !alive || player.Radius != 40 || len(client.Broadcasts()) > 0 {
+		t.Fatal("A player who had only just spawned consumed another player")
+	}
+
+	if events := client.EventLog().Events(); len(events) != 1 || !strings.Contains(events[0].Detail, "spawned too recently") {
+		t.Errorf("Turning down a consumption within the spawn grace recorded %v", events)
+	}
+
+	game.joinedAt = time.Now().Add(-grace)
+	game.HandleMessage(client.Id(), &packets.Packet_PlayerConsumed{PlayerConsumed: &packets.PlayerConsumedMessage{PlayerId: 2}})
+
+	if _, alive := players.Get(2); alive || player.Radius <= 40 {
+		t.Error("A player whose spawn grace had passed couldn't consume another player")
+	}
+}
+
+// A player heading diagonally into a wall must stop at it with their edge on the wall, sliding along it on the other
+// axis, and be sent where they were stopped. Spawning must stay within the world bound too
+func TestWorldBound(t *testing.T) {
+	const worldBound float64 = 200
+	const delta float64 = 1
+
+	player := &objects.Player{Name: "test", X: 150, Y: -100, Radius: 20, Direction: math.Pi / 4}
+	game, client := newTestGame(player, func(config *server.ServerConfig) {
+		config.WorldBound = worldBound
+		config.DriftStrength = 0
+	})
+	tickTime := time.Now()
+	wallX := worldBound - player.Radius
+
+	for range 20 {
+		y := player.Y
+		tickTime = tickTime.Add(server.TickInterval)
+		game.syncPlayer(delta, tickTime)
+
+		if player.X > wallX {
+			t.Fatalf("The player went through the wall to x %f", player.X)
+		}
+
+		if player.Y <= y && player.Y < wallX {
+			t.Fatalf("The player stopped sliding along the wall at y %f", player.Y)
+		}
+	}
+
+	if player.X != wallX {
+		t.Errorf("The player stopped at x %f instead of against the wall at %f", player.X, wallX)
+	}
+
+	broadcasts := client.Broadcasts()
+
+	if sent := broadcasts[len(broadcasts) - 1].GetPlayer(); sent.GetX() != player.X || sent.GetY() != player.Y {
+		t.Errorf("The player was sent at (%f, %f) instead of where the wall stopped them at (%f, %f)", sent.GetX(), sent.GetY(), player.X, player.Y)
+	}
+
+	for range 100 {
+		if x, y, _ := objects.SpawnCoords(worldBound, player.Radius, client.SharedGameObjects().Players, nil, nil); math.Abs(x) > wallX || math.Abs(y) > wallX {
+			t.Fatalf("A player of radius %f spawned at (%f, %f), past the world bound of %f", player.Radius, x, y, worldBound)
+		}
+	}
+}
+
+// A client trying to move its own player by sending a position must be ignored, with nothing passed on, and directions
+// which aren't finite numbers must be turned away, leaving the player heading where they were
+func TestServerAuthoritativeMovement(t *testing.T) {
+	player := &objects.Player{Name: "test", X: 10, Y: 20, Radius: 20, Direction: 1}
+	game, client := newTestGame(player)
+	game.cancelPlayerUpdateLoop = func() {}
+
+	game.HandleMessage(client.Id(), packets.NewPlayer(client.Id(), &objects.Player{Name: "test", X: 2000, Y: -2000, Radius: 500}))
+
+	if player.X != 10 || player.Y != 20 || player.Radius != 20 {
+		t.Errorf("A client's own player message moved its player to (%f, %f) with radius %f", player.X, player.Y, player.Radius)
+	}
+
+	if sent, broadcasts := client.Sent(), client.Broadcasts(); len(sent) > 0 || len(broadcasts) > 0 {
+		t.Errorf("A client's own player message was passed on, sending %v and broadcasting %v", sent, broadcasts)
+	}
+
+	for _, direction := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		game.HandleMessage(client.Id(), &packets.Packet_PlayerDirection{PlayerDirection: &packets.PlayerDirectionMessage{Direction: direction}})
+
+		if player.Direction != 1 {
+			t.Errorf("Direction %f was taken, leaving the player heading %f", direction, player.Direction)
+		}
+	}
+
+	game.HandleMessage(client.Id(), &packets.Packet_PlayerDirection{PlayerDirection: &packets.PlayerDirectionMessage{Direction: -2.5}})
+
+	if player.Direction != -2.5 {
+		t.Errorf("A finite direction of -2.5 left the player heading %f", player.Direction)
+	}
+}
+
+// Players must slow down as they grow, from the full speed at the starting size to half of it at four times the size,
+// never going below the minimum speed or above the full speed
+func TestRadiusToSpeed(t *testing.T) {
+	config := server.NewServerConfig()
+	config.PlayerStartRadius = 20
+	config.PlayerSpeed = 16
+	config.MinPlayerSpeed = 5
+
+	speeds := []struct {
+		radius float64
+		expected float64
+	}{
+		{0, 16},
+		{-5, 16},
+		{10, 16},
+		{20, 16},
+		{80, 8},
+		{320, 5},
+		{1e6, 5},
+	}
+
+	for _, speed := range speeds {
+		if actual := radiusToSpeed(config, speed.radius); actual != speed.expected {
+			t.Errorf("A player of radius %f moves at %f instead of %f", speed.radius, actual, speed.expected)
+		}
+	}
+
+	for radius := 1.0; radius < 1000; radius *= 1.1 {
+		if radiusToSpeed(config, radius * 1.1) > radiusToSpeed(config, radius) {
+			t.Fatalf("A player of radius %f moves faster than one of radius %f", radius * 1.1, radius)
+		}
+	}
+}
+
+// The speed a player moves at each tick, and is sent with, must follow their size as it changes
+func TestSpeedFollowsSize(t *testing.T) {
+	player := &objects.Player{Name: "test", Radius: 40}
+	game, client := newTestGame(player, func(config *server.ServerConfig) { config.DriftStrength = 0 })
+	tickTime := time.Now()
+
+	game.syncPlayer(server.TickDelta, tickTime)
+	before := player.Speed
+
+	sporeId := client.SharedGameObjects().Spores.Add(&objects.Spore{X: 10, Radius: 30})
+	game.HandleMessage(client.Id(), &packets.Packet_SporeConsumed{SporeConsumed: &packets.SporeConsumedMessage{SporeId: sporeId}})
+	game.syncPlayer(server.TickDelta, tickTime.Add(server.TickInterval))
+
+	if expected := radiusToSpeed(client.Config(), player.Radius); player.Speed != expected || player.Speed >= before {
+		t.Errorf("After growing to radius %f the player moved at %f instead of %f, down from %f", player.Radius, player.Speed, expected, before)
+	}
+
+	broadcasts := client.Broadcasts()
+
+	if sent := broadcasts[len(broadcasts) - 1].GetPlayer(); sent.GetSpeed() != player.Speed {
+		t.Errorf("The player was sent with speed %f instead of %f", sent.GetSpeed(), player.Speed)
+	}
+}
+
+// Big players must lose the decay fraction of their mass every decay interval, however often the movement ticks come,
+// stopping at the decay floor, while players at the starting size don't decay at all
+func TestMassDecay(t *testing.T) {
+	const decay float64 = 0.1
+	const interval time.Duration = 5 * time.Second
+	const floorRadius float64 = 50
+
+	configure := func(config *server.ServerConfig) {
+		config.MassDecay = decay
+		config.MassDecayInterval = interval
+		config.MassDecayMinRadius = floorRadius
+		config.DriftStrength = 0
+	}
+
+	big := &objects.Player{Name: "big", Radius: 100}
+	bigGame, bigClient := newTestGame(big, configure)
+	small := &objects.Player{Name: "small", Radius: 20}
+	smallGame, _ := newTestPeer(bigClient, 2, small)
+	tickTime := time.Now()
+
+	// The first round is due one interval after the first tick
+	for _, game := range []*InGame{bigGame, smallGame} {
+		game.Step(server.TickDelta, tickTime)
+	}
+
+	for elapsed := server.TickInterval; elapsed < interval; elapsed += server.TickInterval {
+		bigGame.Step(server.TickDelta, tickTime.Add(elapsed))
+	}
+
+	if big.Radius != 100 {
+		t.Fatalf("A big player decayed to radius %f before the decay interval was up", big.Radius)
+	}
+
+	for _, game := range []*InGame{bigGame, smallGame} {
+		game.Step(server.TickDelta, tickTime.Add(interval))
+	}
+
+	if expected := massToRadius(radiusToMass(100) * (1 - decay)); math.Abs(big.Radius - expected) > 1e-9 {
+		t.Errorf("A player of radius 100 decayed to %f instead of %f", big.Radius, expected)
+	}
+
+	if small.Radius != 20 {
+		t.Errorf("A player at the starting size decayed to %f", small.Radius)
+	}
+
+	for range 100 {
+		bigGame.applyMassDecay()
+	}
+
+	if big.Radius != floorRadius {
+		t.Errorf("A big player decayed to %f instead of stopping at the floor of %f", big.Radius, floorRadius)
+	}
+}
+
+// Chat messages sent faster than the rate limit mustn't be broadcast, with the sender told to slow down instead, and
+// blank or over-long messages must be rejected or cut short before they count
+func TestChatRateLimit(t *testing.T) {
+	game, client := newTestGame(&objects.Player{Name: "test", Radius: 20})
+	game.chatLimiter = server.NewTokenBucket(3, time.Hour)
+
+	game.HandleMessage(client.Id(), &packets.Packet_Chat{Chat: &packets.ChatMessage{Msg: "   "}})
+	game.HandleMessage(client.Id(), &packets.Packet_Chat{Chat: &packets.ChatMessage{Msg: strings.Repeat("a", 200)}})
+
+	for range 5 {
+		game.HandleMessage(client.Id(), &packets.Packet_Chat{Chat: &packets.ChatMessage{Msg: "Hello"}})
+	}
+
+	broadcasts := client.Broadcasts()
+
+	if len(broadcasts) != 3 {
+		t.Fatalf("%d chat messages were broadcast instead of the 3 the rate limit allows", len(broadcasts))
+	}
+
+	if msg := broadcasts[0].GetChat().GetMsg(); len([]rune(msg)) != maxChatLength {
+		t.Errorf("A 200 character chat message was broadcast with %d characters instead of %d", len([]rune(msg)), maxChatLength)
+	}
+
+	if sent := client.Sent(); len(sent) != 3 || !strings.Contains(sent[0].GetChat().GetMsg(), "slow down") {
+		t.Errorf("The sender of chat messages over the rate limit was sent %v instead of a notice for each", sent)
+	}
+}
+
+// A player who sends nothing for the idle timeout must be disconnected, with anything they send, but not what others
+// send, putting it off
+func TestIdleTimeout(t *testing.T) {
+	const idleTimeout = 100 * time.Millisecond
+
+	config := server.NewServerConfig()
+	config.IdleTimeout = idleTimeout
+	client := servertest.NewFakeClient(1, config)
+	client.SetState(&InGame{player: &objects.Player{Name: "test"}})
+	t.Cleanup(func() { client.Close("") })
+
+	for range 6 {
+		time.Sleep(idleTimeout / 3)
+		client.ProcessMessage(client.Id(), &packets.Packet_Chat{Chat: &packets.ChatMessage{Msg: "Still here"}})
+		client.ProcessMessage(2, &packets.Packet_Chat{Chat: &packets.ChatMessage{Msg: "Hello"}})
+	}
+
+	if reason := client.CloseReason(); reason != "" {
+		t.Fatalf("A player who kept sending messages was disconnected because: %s", reason)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); client.CloseReason() == "" && time.Now().Before(deadline); {
+		client.ProcessMessage(2, &packets.Packet_Chat{Chat: &packets.ChatMessage{Msg: "Hello"}})
+		time.Sleep(idleTimeout / 10)
+	}
+
+	if reason := client.CloseReason(); reason != server.CloseReasonIdle {
+		t.Errorf("A silent player was disconnected because %q instead of %q", reason, server.CloseReasonIdle)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,132 @@
+package states
+
+import (
+	"log/slog"
+	"server/internal/server"
+	"server/internal/server/objects"
+	"server/pkg/packets"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// Watching the game without playing. Spectators have no player, so they take up no spot in the world, but they're
+// sent everything needed to show it. They can log in at any time to join the game
+type Spectating struct {
+	client server.ClientInterfacer
+	room *server.Room
+	logger *slog.Logger
+
+	// Handles logging in, registering and resuming, which take the client into the game
+	account *Connected
+
+	// The optional protocol features the client asked for
+	capabilities []string
+	worldSent atomic.Bool
+}
+
+func (spectating *Spectating) Name() string {
+	return "Spectating"
+}
+
+func (spectating *Spectating) SetClient(client server.ClientInterfacer) {
+	spectating.client = client
+	spectating.room = client.Room()
+	spectating.logger = client.Logger().With("state", spectating.Name())
+	spectating.account = &Connected{
+		client: client,
+		logger: spectating.logger,
+		queries: client.DbTransaction().Queries,
+		dbCtx: client.DbTransaction().Ctx,
+		capabilities: spectating.capabilities,
+	}
+}
+
+func (spectating *Spectating) OnEnter() {
+	spectating.logger.Info("Started spectating")
+
+	config := spectating.client.Config()
+	zone := spectating.room.SharedGameObjects.Zone
+	spectating.client.SocketSend(packets.NewGameMode(config.GameMode, zone.Radius() > 0, config.ConsumeRatio, config.ConsumeMode))
+
+	if zone.Radius() > 0 {
+		spectating.client.SocketSend(packets.NewZone(zone))
+	}
+
+	// Clients which ask for the world get it once they're ready to show it
+	if !slices.Contains(spectating.capabilities, packets.CapabilityRequestWorld) {
+		spectating.sendWorld()
+	}
+}
+
+func (spectating *Spectating) HandleMessage(senderId uint64, message packets.Msg) {
+	// Spectators have nothing to move or consume with, so only their requests are listened to
+	if senderId == spectating.client.Id() {
+		switch message := message.(type) {
+			case *packets.Packet_LoginRequest:
+				spectating.account.handleLoginRequest(senderId, message)
+			case *packets.Packet_RegisterRequest:
+				spectating.account.handleRegisterRequest(senderId, message)
+			case *packets.Packet_ResumeRequest:
+				spectating.account.handleResumeRequest(senderId, message)
+			case *packets.Packet_RequestWorld:
+				spectating.handleRequestWorld(senderId, message)
+		}
+
+		return
+	}
+
+	switch message := message.(type) {
+		case *packets.Packet_Chat:
+			if !spectating.client.Config().ChatDisabled {
+				spectating.client.SocketSendAs(message, senderId)
+			}
+		case *packets.Packet_Player,
+			*packets.Packet_Spore,
+			*packets.Packet_SporeConsumed,
+			*packets.Packet_SporeRemoved,
+			*packets.Packet_PlayerConsumed,
+			*packets.Packet_PlayerLeft,
+			*packets.Packet_Zone,
+			*packets.Packet_Hazard,
+			*packets.Packet_HazardRemoved,
+			*packets.Packet_ServerStats,
+			*packets.Packet_Leaderboard:
+			spectating.client.SocketSendAs(message, senderId)
+		case *packets.Packet_PlayersBatch:
+			sendPlayers(spectating.client, spectating.capabilities, senderId, message.PlayersBatch.Players)
+	}
+}
+
+func (spectating *Spectating) OnExit() {
+	spectating.logger.Info("Stopped spectating")
+}
+
+// Clients with the request world capability ask for the world once they're ready for it
+func (spectating *Spectating) handleRequestWorld(_ uint64, _ *packets.Packet_RequestWorld) {
+	if slices.Contains(spectating.capabilities, packets.CapabilityRequestWorld) {
+		spectating.sendWorld()
+	}
+}
+
+// Send every spore to the client in the background, once
+func (spectating *Spectating) sendWorld() {
+	if !spectating.worldSent.CompareAndSwap(false, true) {
+		return
+	}
+
+	sendHazards(spectating.client, spectating.room.SharedGameObjects.Hazards)
+	go spectating.sendInitialSpores(80, 25 * time.Millisecond)
+}
+
+func (spectating *Spectating) sendInitialSpores(batchSize int, delay time.Duration) {
+	batches := batchSpores(spectating.room.SharedGameObjects.Spores, batchSize, func(_ *objects.Spore) bool { return true })
+
+	for i, batch := range batches {
+		if i > 0 {
+			time.Sleep(delay)
+		}
+
+		spectating.client.SocketSend(newSporesBatch(spectating.capabilities, batch))
+	}
+}
\ No newline at end of file
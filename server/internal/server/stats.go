@@ -0,0 +1,104 @@
+package server
+
+import (
+	"net/http"
+	"server/internal/server/objects"
+	"time"
+)
+
+// How long the stats are reused for before being worked out again, so polling them often costs little
+const statsCacheDuration = time.Second
+
+// The server's population, as shown to anyone asking without having to connect to the game
+type ServerStats struct {
+	Players int `json:"players"`
+	Spores int `json:"spores"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+	TopPlayerRadius float64 `json:"top_player_radius"`
+}
+
+// The rooms being played in right now
+func (hub *Hub) Rooms() []*Room {
+	hub.roomsMux.Lock()
+	defer hub.roomsMux.Unlock()
+
+	rooms := make([]*Room, 0, len(hub.rooms))
+
+	for _, room := range hub.rooms {
+		rooms = append(rooms, room)
+	}
+
+	return rooms
+}
+
+// Players in the game across all rooms
+func (hub *Hub) PlayerCount() int {
+	count := 0
+
+	for _, room := range hub.Rooms() {
+		count += room.SharedGameObjects.Players.Len()
+	}
+
+	return count
+}
+
+// Spores in the world across all rooms
+func (hub *Hub) SporeCount() int {
+	count := 0
+
+	for _, room := range hub.Rooms() {
+		count += room.SharedGameObjects.Spores.Len()
+	}
+
+	return count
+}
+
+// The radius of the biggest player in any room, or 0 if nobody is playing
+func (hub *Hub) TopPlayerRadius() float64 {
+	topRadius := 0.0
+
+	for _, room := range hub.Rooms() {
+		room.SharedGameObjects.Players.ForEach(func(_ uint64, player *objects.Player) {
+			topRadius = max(topRadius, player.Radius)
+		})
+	}
+
+	return topRadius
+}
+
+func (hub *Hub) Uptime() time.Duration {
+	return time.Since(hub.startTime)
+}
+
+// The stats as they are now, or as they were up to a second ago
+func (hub *Hub) Stats() ServerStats {
+	hub.statsMux.Lock()
+	defer hub.statsMux.Unlock()
+
+	if hub.statsCachedAt.IsZero() || time.Since(hub.statsCachedAt) >= statsCacheDuration {
+		hub.cachedStats = ServerStats{
+			Players: hub.PlayerCount(),
+			Spores: hub.SporeCount(),
+			UptimeSeconds: hub.Uptime().Seconds(),
+			TopPlayerRadius: hub.TopPlayerRadius(),
+		}
+		hub.statsCachedAt = time.Now()
+	}
+
+	return hub.cachedStats
+}
+
+// Anyone can see the stats, including dashboards on other sites fetching them from a browser
+func (hub *Hub) HandleStats(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Access-Control-Allow-Origin", "*")
+	writer.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+
+	switch request.Method {
+		case http.MethodGet:
+			writeJson(writer, hub.Stats())
+		case http.MethodOptions:
+			writer.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(writer, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
\ No newline at end of file
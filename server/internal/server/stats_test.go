@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"server/internal/server/objects"
+	"testing"
+	"time"
+)
+
+// The stats must add up the players and spores in every room, find the biggest player, allow any site to fetch them,
+// and be reused for a while rather than worked out on every request
+func TestStatsHandler(t *testing.T) {
+	config := NewServerConfig()
+	hub := &Hub{rooms: make(map[string]*Room), startTime: time.Now().Add(-time.Minute)}
+	hub.config.Store(config)
+
+	for _, name := range []string{DefaultRoomName, "other"} {
+		hub.rooms[name] = &Room{Name: name, SharedGameObjects: NewSharedGameObjects(config.MaxSpores, NewKeyCounter())}
+	}
+
+	hub.rooms[DefaultRoomName].SharedGameObjects.Players.Add(&objects.Player{Radius: 20})
+	hub.rooms[DefaultRoomName].SharedGameObjects.Players.Add(&objects.Player{X: 500, Radius: 75.5})
+	hub.rooms["other"].SharedGameObjects.Players.Add(&objects.Player{Radius: 30})
+
+	for range 4 {
+		hub.rooms["other"].SharedGameObjects.Spores.Add(&objects.Spore{Radius: 5})
+	}
+
+	recorder := httptest.NewRecorder()
+	hub.HandleStats(recorder, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+	if origin := recorder.Header().Get("Access-Control-Allow-Origin"); recorder.Code != http.StatusOK || origin != "*" {
+		t.Errorf("Getting the stats gave status %d and allowed origin %q", recorder.Code, origin)
+	}
+
+	stats := ServerStats{}
+
+	if err := json.Unmarshal(recorder.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Couldn't decode the stats %q: %v", recorder.Body.String(), err)
+	}
+
+	if stats.Players != 3 || stats.Spores != 4 || stats.TopPlayerRadius != 75.5 || stats.UptimeSeconds < 60 {
+		t.Errorf("Got stats %+v instead of 3 players, 4 spores, a top radius of 75.5 and a minute's uptime", stats)
+	}
+
+	hub.rooms["other"].SharedGameObjects.Players.Add(&objects.Player{Radius: 10})
+
+	if cached := hub.Stats(); cached.Players != stats.Players {
+		t.Errorf("Stats asked for again straight away counted %d players instead of reusing %d", cached.Players, stats.Players)
+	}
+}
\ No newline at end of file
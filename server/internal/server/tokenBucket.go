@@ -0,0 +1,43 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// Allows bursts of up to a capacity of events, refilling at a steady rate so the limit resets over time
+type TokenBucket struct {
+	capacity   float64
+	refillRate float64
+	tokens     float64
+	lastRefill time.Time
+	mux        sync.Mutex
+}
+
+// Allow the given number of events per period, all at once if they come in a burst
+func NewTokenBucket(capacity int, period time.Duration) *TokenBucket {
+	return &TokenBucket{
+		capacity: float64(capacity),
+		refillRate: float64(capacity) / period.Seconds(),
+		tokens: float64(capacity),
+		lastRefill: time.Now(),
+	}
+}
+
+// Take a token for an event, returning whether there was one left
+func (bucket *TokenBucket) Allow() bool {
+	bucket.mux.Lock()
+	defer bucket.mux.Unlock()
+
+	now := time.Now()
+	bucket.tokens = min(bucket.tokens + now.Sub(bucket.lastRefill).Seconds() * bucket.refillRate, bucket.capacity)
+	bucket.lastRefill = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+
+	bucket.tokens--
+
+	return true
+}
\ No newline at end of file
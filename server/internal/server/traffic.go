@@ -0,0 +1,62 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// Counts the bytes sent, in total and for each type of packet. A nil traffic counter counts nothing
+type TrafficCounter struct {
+	since  time.Time
+	bytes  uint64
+	byType map[string]uint64
+	mux    sync.Mutex
+}
+
+// What a traffic counter has counted so far
+type TrafficSnapshot struct {
+	Bytes          uint64            `json:"bytes"`
+	BytesPerSecond float64           `json:"bytes_per_second"`
+	ByType         map[string]uint64 `json:"by_type"`
+}
+
+func NewTrafficCounter() *TrafficCounter {
+	return &TrafficCounter{
+		since: time.Now(),
+		byType: make(map[string]uint64),
+	}
+}
+
+func (counter *TrafficCounter) Add(packetType string, bytes int) {
+	if counter == nil {
+		return
+	}
+
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	counter.bytes += uint64(bytes)
+	counter.byType[packetType] += uint64(bytes)
+}
+
+// The counts so far, with the rate averaged over the counter's lifetime
+func (counter *TrafficCounter) Snapshot() TrafficSnapshot {
+	if counter == nil {
+		return TrafficSnapshot{ByType: map[string]uint64{}}
+	}
+
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	byType := make(map[string]uint64, len(counter.byType))
+
+	for packetType, bytes := range counter.byType {
+		byType[packetType] = bytes
+	}
+
+	return TrafficSnapshot{
+		Bytes: counter.bytes,
+		BytesPerSecond: float64(counter.bytes) / time.Since(counter.since).Seconds(),
+		ByType: byType,
+	}
+}
\ No newline at end of file
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// Allows up to a limit of events per key in each fixed window of time. A limit of 0 allows everything
+type WindowLimiter struct {
+	limit       int
+	window      time.Duration
+	windowStart time.Time
+	counts      map[string]int
+	mux         sync.Mutex
+}
+
+func NewWindowLimiter(limit int, window time.Duration) *WindowLimiter {
+	return &WindowLimiter{
+		limit: limit,
+		window: window,
+		windowStart: time.Now(),
+		counts: make(map[string]int),
+	}
+}
+
+// Count an event for the key, returning whether it's within the limit for the current window
+func (limiter *WindowLimiter) Allow(key string) bool {
+	if limiter.limit <= 0 {
+		return true
+	}
+
+	limiter.mux.Lock()
+	defer limiter.mux.Unlock()
+
+	// Start the counts over for every new window, so keys which stop showing up don't linger
+	if now := time.Now(); now.Sub(limiter.windowStart) >= limiter.window {
+		limiter.windowStart = now
+		clear(limiter.counts)
+	}
+
+	limiter.counts[key]++
+
+	return limiter.counts[key] <= limiter.limit
+}
\ No newline at end of file
@@ -0,0 +1,37 @@
+package server
+
+// Runs background tasks on a fixed number of goroutines, so short-lived async work can't pile up goroutines
+// without bound under load
+type WorkerPool struct {
+	tasks chan func()
+}
+
+// Starts the given number of workers, which share a queue of the given size
+func NewWorkerPool(size int, queueSize int) *WorkerPool {
+	pool := &WorkerPool{
+		tasks: make(chan func(), queueSize),
+	}
+
+	for i := 0; i < size; i++ {
+		go pool.work()
+	}
+
+	return pool
+}
+
+// Queue a task to be run by the next free worker. This never blocks: tasks are submitted from the room's goroutine,
+// e.g. broadcasts as a player is consumed, and the workers can be waiting on that same goroutine to take their
+// broadcasts, so when the queue is full the task gets a goroutine of its own instead
+func (pool *WorkerPool) Submit(task func()) {
+	select {
+		case pool.tasks <- task:
+		default:
+			go task()
+	}
+}
+
+func (pool *WorkerPool) work() {
+	for task := range pool.tasks {
+		task()
+	}
+}
\ No newline at end of file
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Submitting must never block, even with every worker busy and the queue full. The room's goroutine submits
+// broadcasts which only it can take, so here the test plays the room: it submits tasks which wait on it, and only then
+// takes what they send
+func TestWorkerPoolNeverBlocks(t *testing.T) {
+	const tasks int = 50
+
+	pool := NewWorkerPool(1, 1)
+	broadcasts := make(chan int)
+	submitted := make(chan struct{})
+
+	go func() {
+		for i := range tasks {
+			pool.Submit(func() { broadcasts <- i })
+		}
+
+		close(submitted)
+	}()
+
+	select {
+		case <-submitted:
+		case <-time.After(testTimeout):
+			t.Fatal("Submitting to a full pool blocked")
+	}
+
+	for range tasks {
+		select {
+			case <-broadcasts:
+			case <-time.After(testTimeout):
+				t.Fatal("Timed out waiting for the submitted tasks to run")
+		}
+	}
+}
+
+// Under a load the queue can hold, every task must run without the pool going past its own workers in goroutines
+func TestWorkerPoolBounded(t *testing.T) {
+	const workers int = 4
+	const tasks int = 10000
+
+	pool := NewWorkerPool(workers, tasks)
+	baseline := runtime.NumGoroutine()
+	var peak atomic.Int64
+	var done sync.WaitGroup
+	done.Add(tasks)
+
+	for range tasks {
+		pool.Submit(func() {
+			defer done.Done()
+
+			goroutines := int64(runtime.NumGoroutine())
+
+			for current := peak.Load(); goroutines > current && !peak.CompareAndSwap(current, goroutines); {
+				current = peak.Load()
+			}
+		})
+	}
+
+	done.Wait()
+
+	if int(peak.Load()) > baseline {
+		t.Errorf("Running %d tasks on %d workers peaked at %d goroutines, over the %d there were to start with", tasks, workers, peak.Load(), baseline)
+	}
+}
\ No newline at end of file
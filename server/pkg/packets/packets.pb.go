@@ -318,6 +318,8 @@ type PlayerMessage struct {
 	Radius        float64                `protobuf:"fixed64,5,opt,name=radius,proto3" json:"radius,omitempty"`
 	Direction     float64                `protobuf:"fixed64,6,opt,name=direction,proto3" json:"direction,omitempty"`
 	Speed         float64                `protobuf:"fixed64,7,opt,name=speed,proto3" json:"speed,omitempty"`
+	TickTime      int64                  `protobuf:"varint,8,opt,name=tick_time,json=tickTime,proto3" json:"tick_time,omitempty"`
+	Mass          float64                `protobuf:"fixed64,9,opt,name=mass,proto3" json:"mass,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -401,6 +403,20 @@ func (x *PlayerMessage) GetSpeed() float64 {
 	return 0
 }
 
+func (x *PlayerMessage) GetTickTime() int64 {
+	if x != nil {
+		return x.TickTime
+	}
+	return 0
+}
+
+func (x *PlayerMessage) GetMass() float64 {
+	if x != nil {
+		return x.Mass
+	}
+	return 0
+}
+
 type PlayerDirectionMessage struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Direction     float64                `protobuf:"fixed64,1,opt,name=direction,proto3" json:"direction,omitempty"`
@@ -451,6 +467,7 @@ type SporeMessage struct {
 	X             float64                `protobuf:"fixed64,2,opt,name=x,proto3" json:"x,omitempty"`
 	Y             float64                `protobuf:"fixed64,3,opt,name=y,proto3" json:"y,omitempty"`
 	Radius        float64                `protobuf:"fixed64,4,opt,name=radius,proto3" json:"radius,omitempty"`
+	Type          uint32                 `protobuf:"varint,5,opt,name=type,proto3" json:"type,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -513,6 +530,13 @@ func (x *SporeMessage) GetRadius() float64 {
 	return 0
 }
 
+func (x *SporeMessage) GetType() uint32 {
+	if x != nil {
+		return x.Type
+	}
+	return 0
+}
+
 type SporeConsumedMessage struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SporeId       uint64                 `protobuf:"varint,1,opt,name=spore_id,json=sporeId,proto3" json:"spore_id,omitempty"`
@@ -521,21 +545,1052 @@ type SporeConsumedMessage struct {
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SporeConsumedMessage) Reset() {
-	*x = SporeConsumedMessage{}
-	mi := &file_packets_proto_msgTypes[9]
+func (x *SporeConsumedMessage) Reset() {
+	*x = SporeConsumedMessage{}
+	mi := &file_packets_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SporeConsumedMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SporeConsumedMessage) ProtoMessage() {}
+
+func (x *SporeConsumedMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SporeConsumedMessage.ProtoReflect.Descriptor instead.
+func (*SporeConsumedMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SporeConsumedMessage) GetSporeId() uint64 {
+	if x != nil {
+		return x.SporeId
+	}
+	return 0
+}
+
+func (x *SporeConsumedMessage) GetNewRadius() float64 {
+	if x != nil {
+		return x.NewRadius
+	}
+	return 0
+}
+
+type PlayerConsumedMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PlayerId      uint64                 `protobuf:"varint,1,opt,name=player_id,json=playerId,proto3" json:"player_id,omitempty"`
+	NewRadius     float64                `protobuf:"fixed64,2,opt,name=new_radius,json=newRadius,proto3" json:"new_radius,omitempty"`
+	VictimRadius  float64                `protobuf:"fixed64,3,opt,name=victim_radius,json=victimRadius,proto3" json:"victim_radius,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlayerConsumedMessage) Reset() {
+	*x = PlayerConsumedMessage{}
+	mi := &file_packets_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlayerConsumedMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlayerConsumedMessage) ProtoMessage() {}
+
+func (x *PlayerConsumedMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlayerConsumedMessage.ProtoReflect.Descriptor instead.
+func (*PlayerConsumedMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PlayerConsumedMessage) GetPlayerId() uint64 {
+	if x != nil {
+		return x.PlayerId
+	}
+	return 0
+}
+
+func (x *PlayerConsumedMessage) GetNewRadius() float64 {
+	if x != nil {
+		return x.NewRadius
+	}
+	return 0
+}
+
+func (x *PlayerConsumedMessage) GetVictimRadius() float64 {
+	if x != nil {
+		return x.VictimRadius
+	}
+	return 0
+}
+
+type SporeRemovedMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SporeId       uint64                 `protobuf:"varint,1,opt,name=spore_id,json=sporeId,proto3" json:"spore_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SporeRemovedMessage) Reset() {
+	*x = SporeRemovedMessage{}
+	mi := &file_packets_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SporeRemovedMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SporeRemovedMessage) ProtoMessage() {}
+
+func (x *SporeRemovedMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SporeRemovedMessage.ProtoReflect.Descriptor instead.
+func (*SporeRemovedMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SporeRemovedMessage) GetSporeId() uint64 {
+	if x != nil {
+		return x.SporeId
+	}
+	return 0
+}
+
+type SporesBatchMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Spores        []*SporeMessage        `protobuf:"bytes,1,rep,name=spores,proto3" json:"spores,omitempty"`
+	Ids           []uint64               `protobuf:"varint,2,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	SporeValues   []float64              `protobuf:"fixed64,3,rep,packed,name=spore_values,json=sporeValues,proto3" json:"spore_values,omitempty"`
+	SporeTypes    []uint32               `protobuf:"varint,4,rep,packed,name=spore_types,json=sporeTypes,proto3" json:"spore_types,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SporesBatchMessage) Reset() {
+	*x = SporesBatchMessage{}
+	mi := &file_packets_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SporesBatchMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SporesBatchMessage) ProtoMessage() {}
+
+func (x *SporesBatchMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SporesBatchMessage.ProtoReflect.Descriptor instead.
+func (*SporesBatchMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SporesBatchMessage) GetSpores() []*SporeMessage {
+	if x != nil {
+		return x.Spores
+	}
+	return nil
+}
+
+func (x *SporesBatchMessage) GetIds() []uint64 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *SporesBatchMessage) GetSporeValues() []float64 {
+	if x != nil {
+		return x.SporeValues
+	}
+	return nil
+}
+
+func (x *SporesBatchMessage) GetSporeTypes() []uint32 {
+	if x != nil {
+		return x.SporeTypes
+	}
+	return nil
+}
+
+type PlayersBatchMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Players       []*PlayerMessage       `protobuf:"bytes,1,rep,name=players,proto3" json:"players,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlayersBatchMessage) Reset() {
+	*x = PlayersBatchMessage{}
+	mi := &file_packets_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlayersBatchMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlayersBatchMessage) ProtoMessage() {}
+
+func (x *PlayersBatchMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlayersBatchMessage.ProtoReflect.Descriptor instead.
+func (*PlayersBatchMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PlayersBatchMessage) GetPlayers() []*PlayerMessage {
+	if x != nil {
+		return x.Players
+	}
+	return nil
+}
+
+type HazardMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	X             float64                `protobuf:"fixed64,2,opt,name=x,proto3" json:"x,omitempty"`
+	Y             float64                `protobuf:"fixed64,3,opt,name=y,proto3" json:"y,omitempty"`
+	Radius        float64                `protobuf:"fixed64,4,opt,name=radius,proto3" json:"radius,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HazardMessage) Reset() {
+	*x = HazardMessage{}
+	mi := &file_packets_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HazardMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HazardMessage) ProtoMessage() {}
+
+func (x *HazardMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HazardMessage.ProtoReflect.Descriptor instead.
+func (*HazardMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *HazardMessage) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *HazardMessage) GetX() float64 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *HazardMessage) GetY() float64 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *HazardMessage) GetRadius() float64 {
+	if x != nil {
+		return x.Radius
+	}
+	return 0
+}
+
+type HazardRemovedMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	HazardId      uint64                 `protobuf:"varint,1,opt,name=hazard_id,json=hazardId,proto3" json:"hazard_id,omitempty"`
+	PlayerId      uint64                 `protobuf:"varint,2,opt,name=player_id,json=playerId,proto3" json:"player_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HazardRemovedMessage) Reset() {
+	*x = HazardRemovedMessage{}
+	mi := &file_packets_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HazardRemovedMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HazardRemovedMessage) ProtoMessage() {}
+
+func (x *HazardRemovedMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HazardRemovedMessage.ProtoReflect.Descriptor instead.
+func (*HazardRemovedMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *HazardRemovedMessage) GetHazardId() uint64 {
+	if x != nil {
+		return x.HazardId
+	}
+	return 0
+}
+
+func (x *HazardRemovedMessage) GetPlayerId() uint64 {
+	if x != nil {
+		return x.PlayerId
+	}
+	return 0
+}
+
+type ZoneMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	X             float64                `protobuf:"fixed64,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y             float64                `protobuf:"fixed64,2,opt,name=y,proto3" json:"y,omitempty"`
+	Radius        float64                `protobuf:"fixed64,3,opt,name=radius,proto3" json:"radius,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ZoneMessage) Reset() {
+	*x = ZoneMessage{}
+	mi := &file_packets_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ZoneMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ZoneMessage) ProtoMessage() {}
+
+func (x *ZoneMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ZoneMessage.ProtoReflect.Descriptor instead.
+func (*ZoneMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ZoneMessage) GetX() float64 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *ZoneMessage) GetY() float64 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *ZoneMessage) GetRadius() float64 {
+	if x != nil {
+		return x.Radius
+	}
+	return 0
+}
+
+type ReconnectMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReconnectMessage) Reset() {
+	*x = ReconnectMessage{}
+	mi := &file_packets_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReconnectMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReconnectMessage) ProtoMessage() {}
+
+func (x *ReconnectMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReconnectMessage.ProtoReflect.Descriptor instead.
+func (*ReconnectMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ReconnectMessage) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type ServerInfoMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Motd          string                 `protobuf:"bytes,2,opt,name=motd,proto3" json:"motd,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServerInfoMessage) Reset() {
+	*x = ServerInfoMessage{}
+	mi := &file_packets_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServerInfoMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerInfoMessage) ProtoMessage() {}
+
+func (x *ServerInfoMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerInfoMessage.ProtoReflect.Descriptor instead.
+func (*ServerInfoMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ServerInfoMessage) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ServerInfoMessage) GetMotd() string {
+	if x != nil {
+		return x.Motd
+	}
+	return ""
+}
+
+type GameModeMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	ZoneEnabled   bool                   `protobuf:"varint,2,opt,name=zone_enabled,json=zoneEnabled,proto3" json:"zone_enabled,omitempty"`
+	ConsumeRatio  float64                `protobuf:"fixed64,3,opt,name=consume_ratio,json=consumeRatio,proto3" json:"consume_ratio,omitempty"`
+	ConsumeMode   string                 `protobuf:"bytes,4,opt,name=consume_mode,json=consumeMode,proto3" json:"consume_mode,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GameModeMessage) Reset() {
+	*x = GameModeMessage{}
+	mi := &file_packets_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GameModeMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameModeMessage) ProtoMessage() {}
+
+func (x *GameModeMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameModeMessage.ProtoReflect.Descriptor instead.
+func (*GameModeMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *GameModeMessage) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GameModeMessage) GetZoneEnabled() bool {
+	if x != nil {
+		return x.ZoneEnabled
+	}
+	return false
+}
+
+func (x *GameModeMessage) GetConsumeRatio() float64 {
+	if x != nil {
+		return x.ConsumeRatio
+	}
+	return 0
+}
+
+func (x *GameModeMessage) GetConsumeMode() string {
+	if x != nil {
+		return x.ConsumeMode
+	}
+	return ""
+}
+
+type TimeSyncMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientTime    int64                  `protobuf:"varint,1,opt,name=client_time,json=clientTime,proto3" json:"client_time,omitempty"`
+	ServerTime    int64                  `protobuf:"varint,2,opt,name=server_time,json=serverTime,proto3" json:"server_time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TimeSyncMessage) Reset() {
+	*x = TimeSyncMessage{}
+	mi := &file_packets_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TimeSyncMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimeSyncMessage) ProtoMessage() {}
+
+func (x *TimeSyncMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimeSyncMessage.ProtoReflect.Descriptor instead.
+func (*TimeSyncMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *TimeSyncMessage) GetClientTime() int64 {
+	if x != nil {
+		return x.ClientTime
+	}
+	return 0
+}
+
+func (x *TimeSyncMessage) GetServerTime() int64 {
+	if x != nil {
+		return x.ServerTime
+	}
+	return 0
+}
+
+type CapabilitiesMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Capabilities  []string               `protobuf:"bytes,1,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CapabilitiesMessage) Reset() {
+	*x = CapabilitiesMessage{}
+	mi := &file_packets_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapabilitiesMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapabilitiesMessage) ProtoMessage() {}
+
+func (x *CapabilitiesMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapabilitiesMessage.ProtoReflect.Descriptor instead.
+func (*CapabilitiesMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *CapabilitiesMessage) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+type RequestWorldMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RequestWorldMessage) Reset() {
+	*x = RequestWorldMessage{}
+	mi := &file_packets_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RequestWorldMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequestWorldMessage) ProtoMessage() {}
+
+func (x *RequestWorldMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequestWorldMessage.ProtoReflect.Descriptor instead.
+func (*RequestWorldMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{22}
+}
+
+type ServerStatsMessage struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	PlayerCount       uint64                 `protobuf:"varint,1,opt,name=player_count,json=playerCount,proto3" json:"player_count,omitempty"`
+	BiggestPlayerName string                 `protobuf:"bytes,2,opt,name=biggest_player_name,json=biggestPlayerName,proto3" json:"biggest_player_name,omitempty"`
+	BiggestPlayerMass float64                `protobuf:"fixed64,3,opt,name=biggest_player_mass,json=biggestPlayerMass,proto3" json:"biggest_player_mass,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *ServerStatsMessage) Reset() {
+	*x = ServerStatsMessage{}
+	mi := &file_packets_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServerStatsMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerStatsMessage) ProtoMessage() {}
+
+func (x *ServerStatsMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerStatsMessage.ProtoReflect.Descriptor instead.
+func (*ServerStatsMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ServerStatsMessage) GetPlayerCount() uint64 {
+	if x != nil {
+		return x.PlayerCount
+	}
+	return 0
+}
+
+func (x *ServerStatsMessage) GetBiggestPlayerName() string {
+	if x != nil {
+		return x.BiggestPlayerName
+	}
+	return ""
+}
+
+func (x *ServerStatsMessage) GetBiggestPlayerMass() float64 {
+	if x != nil {
+		return x.BiggestPlayerMass
+	}
+	return 0
+}
+
+type LeaderboardEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PlayerId      uint64                 `protobuf:"varint,1,opt,name=player_id,json=playerId,proto3" json:"player_id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Radius        float64                `protobuf:"fixed64,3,opt,name=radius,proto3" json:"radius,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaderboardEntry) Reset() {
+	*x = LeaderboardEntry{}
+	mi := &file_packets_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaderboardEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaderboardEntry) ProtoMessage() {}
+
+func (x *LeaderboardEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaderboardEntry.ProtoReflect.Descriptor instead.
+func (*LeaderboardEntry) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *LeaderboardEntry) GetPlayerId() uint64 {
+	if x != nil {
+		return x.PlayerId
+	}
+	return 0
+}
+
+func (x *LeaderboardEntry) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *LeaderboardEntry) GetRadius() float64 {
+	if x != nil {
+		return x.Radius
+	}
+	return 0
+}
+
+type LeaderboardMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*LeaderboardEntry    `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaderboardMessage) Reset() {
+	*x = LeaderboardMessage{}
+	mi := &file_packets_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaderboardMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaderboardMessage) ProtoMessage() {}
+
+func (x *LeaderboardMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaderboardMessage.ProtoReflect.Descriptor instead.
+func (*LeaderboardMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *LeaderboardMessage) GetEntries() []*LeaderboardEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type SessionTokenMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SessionTokenMessage) Reset() {
+	*x = SessionTokenMessage{}
+	mi := &file_packets_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionTokenMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionTokenMessage) ProtoMessage() {}
+
+func (x *SessionTokenMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionTokenMessage.ProtoReflect.Descriptor instead.
+func (*SessionTokenMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *SessionTokenMessage) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type ResumeRequestMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResumeRequestMessage) Reset() {
+	*x = ResumeRequestMessage{}
+	mi := &file_packets_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumeRequestMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumeRequestMessage) ProtoMessage() {}
+
+func (x *ResumeRequestMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumeRequestMessage.ProtoReflect.Descriptor instead.
+func (*ResumeRequestMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ResumeRequestMessage) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type SpectateRequestMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SpectateRequestMessage) Reset() {
+	*x = SpectateRequestMessage{}
+	mi := &file_packets_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SpectateRequestMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SpectateRequestMessage) ProtoMessage() {}
+
+func (x *SpectateRequestMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SpectateRequestMessage.ProtoReflect.Descriptor instead.
+func (*SpectateRequestMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{28}
+}
+
+type PlayerLeftMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PlayerId      uint64                 `protobuf:"varint,1,opt,name=player_id,json=playerId,proto3" json:"player_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlayerLeftMessage) Reset() {
+	*x = PlayerLeftMessage{}
+	mi := &file_packets_proto_msgTypes[29]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SporeConsumedMessage) String() string {
+func (x *PlayerLeftMessage) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SporeConsumedMessage) ProtoMessage() {}
+func (*PlayerLeftMessage) ProtoMessage() {}
 
-func (x *SporeConsumedMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_packets_proto_msgTypes[9]
+func (x *PlayerLeftMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[29]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -546,48 +1601,40 @@ func (x *SporeConsumedMessage) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SporeConsumedMessage.ProtoReflect.Descriptor instead.
-func (*SporeConsumedMessage) Descriptor() ([]byte, []int) {
-	return file_packets_proto_rawDescGZIP(), []int{9}
-}
-
-func (x *SporeConsumedMessage) GetSporeId() uint64 {
-	if x != nil {
-		return x.SporeId
-	}
-	return 0
+// Deprecated: Use PlayerLeftMessage.ProtoReflect.Descriptor instead.
+func (*PlayerLeftMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{29}
 }
 
-func (x *SporeConsumedMessage) GetNewRadius() float64 {
+func (x *PlayerLeftMessage) GetPlayerId() uint64 {
 	if x != nil {
-		return x.NewRadius
+		return x.PlayerId
 	}
 	return 0
 }
 
-type PlayerConsumedMessage struct {
+type ServerFullMessage struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	PlayerId      uint64                 `protobuf:"varint,1,opt,name=player_id,json=playerId,proto3" json:"player_id,omitempty"`
-	NewRadius     float64                `protobuf:"fixed64,2,opt,name=new_radius,json=newRadius,proto3" json:"new_radius,omitempty"`
+	QueuePosition uint32                 `protobuf:"varint,1,opt,name=queue_position,json=queuePosition,proto3" json:"queue_position,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PlayerConsumedMessage) Reset() {
-	*x = PlayerConsumedMessage{}
-	mi := &file_packets_proto_msgTypes[10]
+func (x *ServerFullMessage) Reset() {
+	*x = ServerFullMessage{}
+	mi := &file_packets_proto_msgTypes[30]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PlayerConsumedMessage) String() string {
+func (x *ServerFullMessage) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PlayerConsumedMessage) ProtoMessage() {}
+func (*ServerFullMessage) ProtoMessage() {}
 
-func (x *PlayerConsumedMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_packets_proto_msgTypes[10]
+func (x *ServerFullMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[30]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -598,47 +1645,43 @@ func (x *PlayerConsumedMessage) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PlayerConsumedMessage.ProtoReflect.Descriptor instead.
-func (*PlayerConsumedMessage) Descriptor() ([]byte, []int) {
-	return file_packets_proto_rawDescGZIP(), []int{10}
-}
-
-func (x *PlayerConsumedMessage) GetPlayerId() uint64 {
-	if x != nil {
-		return x.PlayerId
-	}
-	return 0
+// Deprecated: Use ServerFullMessage.ProtoReflect.Descriptor instead.
+func (*ServerFullMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{30}
 }
 
-func (x *PlayerConsumedMessage) GetNewRadius() float64 {
+func (x *ServerFullMessage) GetQueuePosition() uint32 {
 	if x != nil {
-		return x.NewRadius
+		return x.QueuePosition
 	}
 	return 0
 }
 
-type SporesBatchMessage struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Spores        []*SporeMessage        `protobuf:"bytes,1,rep,name=spores,proto3" json:"spores,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type StatsMessage struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	SporesEaten      uint64                 `protobuf:"varint,1,opt,name=spores_eaten,json=sporesEaten,proto3" json:"spores_eaten,omitempty"`
+	PlayersEaten     uint64                 `protobuf:"varint,2,opt,name=players_eaten,json=playersEaten,proto3" json:"players_eaten,omitempty"`
+	DistanceTraveled float64                `protobuf:"fixed64,3,opt,name=distance_traveled,json=distanceTraveled,proto3" json:"distance_traveled,omitempty"`
+	TimeAlive        float64                `protobuf:"fixed64,4,opt,name=time_alive,json=timeAlive,proto3" json:"time_alive,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
-func (x *SporesBatchMessage) Reset() {
-	*x = SporesBatchMessage{}
-	mi := &file_packets_proto_msgTypes[11]
+func (x *StatsMessage) Reset() {
+	*x = StatsMessage{}
+	mi := &file_packets_proto_msgTypes[31]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SporesBatchMessage) String() string {
+func (x *StatsMessage) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SporesBatchMessage) ProtoMessage() {}
+func (*StatsMessage) ProtoMessage() {}
 
-func (x *SporesBatchMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_packets_proto_msgTypes[11]
+func (x *StatsMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_packets_proto_msgTypes[31]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -649,16 +1692,37 @@ func (x *SporesBatchMessage) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SporesBatchMessage.ProtoReflect.Descriptor instead.
-func (*SporesBatchMessage) Descriptor() ([]byte, []int) {
-	return file_packets_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use StatsMessage.ProtoReflect.Descriptor instead.
+func (*StatsMessage) Descriptor() ([]byte, []int) {
+	return file_packets_proto_rawDescGZIP(), []int{31}
 }
 
-func (x *SporesBatchMessage) GetSpores() []*SporeMessage {
+func (x *StatsMessage) GetSporesEaten() uint64 {
 	if x != nil {
-		return x.Spores
+		return x.SporesEaten
 	}
-	return nil
+	return 0
+}
+
+func (x *StatsMessage) GetPlayersEaten() uint64 {
+	if x != nil {
+		return x.PlayersEaten
+	}
+	return 0
+}
+
+func (x *StatsMessage) GetDistanceTraveled() float64 {
+	if x != nil {
+		return x.DistanceTraveled
+	}
+	return 0
+}
+
+func (x *StatsMessage) GetTimeAlive() float64 {
+	if x != nil {
+		return x.TimeAlive
+	}
+	return 0
 }
 
 type Packet struct {
@@ -678,6 +1742,25 @@ type Packet struct {
 	//	*Packet_SporeConsumed
 	//	*Packet_SporesBatch
 	//	*Packet_PlayerConsumed
+	//	*Packet_Zone
+	//	*Packet_Reconnect
+	//	*Packet_ServerInfo
+	//	*Packet_Stats
+	//	*Packet_TimeSync
+	//	*Packet_SporeRemoved
+	//	*Packet_GameMode
+	//	*Packet_Capabilities
+	//	*Packet_RequestWorld
+	//	*Packet_ServerStats
+	//	*Packet_Leaderboard
+	//	*Packet_SessionToken
+	//	*Packet_ResumeRequest
+	//	*Packet_SpectateRequest
+	//	*Packet_PlayerLeft
+	//	*Packet_ServerFull
+	//	*Packet_PlayersBatch
+	//	*Packet_Hazard
+	//	*Packet_HazardRemoved
 	Msg           isPacket_Msg `protobuf_oneof:"msg"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -685,7 +1768,7 @@ type Packet struct {
 
 func (x *Packet) Reset() {
 	*x = Packet{}
-	mi := &file_packets_proto_msgTypes[12]
+	mi := &file_packets_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -697,7 +1780,7 @@ func (x *Packet) String() string {
 func (*Packet) ProtoMessage() {}
 
 func (x *Packet) ProtoReflect() protoreflect.Message {
-	mi := &file_packets_proto_msgTypes[12]
+	mi := &file_packets_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -710,7 +1793,7 @@ func (x *Packet) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Packet.ProtoReflect.Descriptor instead.
 func (*Packet) Descriptor() ([]byte, []int) {
-	return file_packets_proto_rawDescGZIP(), []int{12}
+	return file_packets_proto_rawDescGZIP(), []int{32}
 }
 
 func (x *Packet) GetSenderId() uint64 {
@@ -835,6 +1918,177 @@ func (x *Packet) GetPlayerConsumed() *PlayerConsumedMessage {
 	return nil
 }
 
+func (x *Packet) GetZone() *ZoneMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_Zone); ok {
+			return x.Zone
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetReconnect() *ReconnectMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_Reconnect); ok {
+			return x.Reconnect
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetServerInfo() *ServerInfoMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_ServerInfo); ok {
+			return x.ServerInfo
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetStats() *StatsMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_Stats); ok {
+			return x.Stats
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetTimeSync() *TimeSyncMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_TimeSync); ok {
+			return x.TimeSync
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetSporeRemoved() *SporeRemovedMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_SporeRemoved); ok {
+			return x.SporeRemoved
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetGameMode() *GameModeMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_GameMode); ok {
+			return x.GameMode
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetCapabilities() *CapabilitiesMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_Capabilities); ok {
+			return x.Capabilities
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetRequestWorld() *RequestWorldMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_RequestWorld); ok {
+			return x.RequestWorld
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetServerStats() *ServerStatsMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_ServerStats); ok {
+			return x.ServerStats
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetLeaderboard() *LeaderboardMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_Leaderboard); ok {
+			return x.Leaderboard
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetSessionToken() *SessionTokenMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_SessionToken); ok {
+			return x.SessionToken
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetResumeRequest() *ResumeRequestMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_ResumeRequest); ok {
+			return x.ResumeRequest
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetSpectateRequest() *SpectateRequestMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_SpectateRequest); ok {
+			return x.SpectateRequest
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetPlayerLeft() *PlayerLeftMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_PlayerLeft); ok {
+			return x.PlayerLeft
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetServerFull() *ServerFullMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_ServerFull); ok {
+			return x.ServerFull
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetPlayersBatch() *PlayersBatchMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_PlayersBatch); ok {
+			return x.PlayersBatch
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetHazard() *HazardMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_Hazard); ok {
+			return x.Hazard
+		}
+	}
+	return nil
+}
+
+func (x *Packet) GetHazardRemoved() *HazardRemovedMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*Packet_HazardRemoved); ok {
+			return x.HazardRemoved
+		}
+	}
+	return nil
+}
+
 type isPacket_Msg interface {
 	isPacket_Msg()
 }
@@ -887,6 +2141,82 @@ type Packet_PlayerConsumed struct {
 	PlayerConsumed *PlayerConsumedMessage `protobuf:"bytes,13,opt,name=player_consumed,json=playerConsumed,proto3,oneof"`
 }
 
+type Packet_Zone struct {
+	Zone *ZoneMessage `protobuf:"bytes,14,opt,name=zone,proto3,oneof"`
+}
+
+type Packet_Reconnect struct {
+	Reconnect *ReconnectMessage `protobuf:"bytes,15,opt,name=reconnect,proto3,oneof"`
+}
+
+type Packet_ServerInfo struct {
+	ServerInfo *ServerInfoMessage `protobuf:"bytes,16,opt,name=server_info,json=serverInfo,proto3,oneof"`
+}
+
+type Packet_Stats struct {
+	Stats *StatsMessage `protobuf:"bytes,17,opt,name=stats,proto3,oneof"`
+}
+
+type Packet_TimeSync struct {
+	TimeSync *TimeSyncMessage `protobuf:"bytes,18,opt,name=time_sync,json=timeSync,proto3,oneof"`
+}
+
+type Packet_SporeRemoved struct {
+	SporeRemoved *SporeRemovedMessage `protobuf:"bytes,19,opt,name=spore_removed,json=sporeRemoved,proto3,oneof"`
+}
+
+type Packet_GameMode struct {
+	GameMode *GameModeMessage `protobuf:"bytes,20,opt,name=game_mode,json=gameMode,proto3,oneof"`
+}
+
+type Packet_Capabilities struct {
+	Capabilities *CapabilitiesMessage `protobuf:"bytes,21,opt,name=capabilities,proto3,oneof"`
+}
+
+type Packet_RequestWorld struct {
+	RequestWorld *RequestWorldMessage `protobuf:"bytes,22,opt,name=request_world,json=requestWorld,proto3,oneof"`
+}
+
+type Packet_ServerStats struct {
+	ServerStats *ServerStatsMessage `protobuf:"bytes,23,opt,name=server_stats,json=serverStats,proto3,oneof"`
+}
+
+type Packet_Leaderboard struct {
+	Leaderboard *LeaderboardMessage `protobuf:"bytes,24,opt,name=leaderboard,proto3,oneof"`
+}
+
+type Packet_SessionToken struct {
+	SessionToken *SessionTokenMessage `protobuf:"bytes,25,opt,name=session_token,json=sessionToken,proto3,oneof"`
+}
+
+type Packet_ResumeRequest struct {
+	ResumeRequest *ResumeRequestMessage `protobuf:"bytes,26,opt,name=resume_request,json=resumeRequest,proto3,oneof"`
+}
+
+type Packet_SpectateRequest struct {
+	SpectateRequest *SpectateRequestMessage `protobuf:"bytes,27,opt,name=spectate_request,json=spectateRequest,proto3,oneof"`
+}
+
+type Packet_PlayerLeft struct {
+	PlayerLeft *PlayerLeftMessage `protobuf:"bytes,28,opt,name=player_left,json=playerLeft,proto3,oneof"`
+}
+
+type Packet_ServerFull struct {
+	ServerFull *ServerFullMessage `protobuf:"bytes,29,opt,name=server_full,json=serverFull,proto3,oneof"`
+}
+
+type Packet_PlayersBatch struct {
+	PlayersBatch *PlayersBatchMessage `protobuf:"bytes,30,opt,name=players_batch,json=playersBatch,proto3,oneof"`
+}
+
+type Packet_Hazard struct {
+	Hazard *HazardMessage `protobuf:"bytes,31,opt,name=hazard,proto3,oneof"`
+}
+
+type Packet_HazardRemoved struct {
+	HazardRemoved *HazardRemovedMessage `protobuf:"bytes,32,opt,name=hazard_removed,json=hazardRemoved,proto3,oneof"`
+}
+
 func (*Packet_Chat) isPacket_Msg() {}
 
 func (*Packet_Id) isPacket_Msg() {}
@@ -911,6 +2241,44 @@ func (*Packet_SporesBatch) isPacket_Msg() {}
 
 func (*Packet_PlayerConsumed) isPacket_Msg() {}
 
+func (*Packet_Zone) isPacket_Msg() {}
+
+func (*Packet_Reconnect) isPacket_Msg() {}
+
+func (*Packet_ServerInfo) isPacket_Msg() {}
+
+func (*Packet_Stats) isPacket_Msg() {}
+
+func (*Packet_TimeSync) isPacket_Msg() {}
+
+func (*Packet_SporeRemoved) isPacket_Msg() {}
+
+func (*Packet_GameMode) isPacket_Msg() {}
+
+func (*Packet_Capabilities) isPacket_Msg() {}
+
+func (*Packet_RequestWorld) isPacket_Msg() {}
+
+func (*Packet_ServerStats) isPacket_Msg() {}
+
+func (*Packet_Leaderboard) isPacket_Msg() {}
+
+func (*Packet_SessionToken) isPacket_Msg() {}
+
+func (*Packet_ResumeRequest) isPacket_Msg() {}
+
+func (*Packet_SpectateRequest) isPacket_Msg() {}
+
+func (*Packet_PlayerLeft) isPacket_Msg() {}
+
+func (*Packet_ServerFull) isPacket_Msg() {}
+
+func (*Packet_PlayersBatch) isPacket_Msg() {}
+
+func (*Packet_Hazard) isPacket_Msg() {}
+
+func (*Packet_HazardRemoved) isPacket_Msg() {}
+
 var File_packets_proto protoreflect.FileDescriptor
 
 const file_packets_proto_rawDesc = "" +
@@ -930,7 +2298,7 @@ const file_packets_proto_rawDesc = "" +
 	"\x15password_confirmation\x18\x03 \x01(\tR\x14passwordConfirmation\"\x13\n" +
 	"\x11OkResponseMessage\"-\n" +
 	"\x13DenyResponseMessage\x12\x16\n" +
-	"\x06reason\x18\x01 \x01(\tR\x06reason\"\x9b\x01\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\"\xcc\x01\n" +
 	"\rPlayerMessage\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12\f\n" +
@@ -938,24 +2306,91 @@ const file_packets_proto_rawDesc = "" +
 	"\x01y\x18\x04 \x01(\x01R\x01y\x12\x16\n" +
 	"\x06radius\x18\x05 \x01(\x01R\x06radius\x12\x1c\n" +
 	"\tdirection\x18\x06 \x01(\x01R\tdirection\x12\x14\n" +
-	"\x05speed\x18\a \x01(\x01R\x05speed\"6\n" +
+	"\x05speed\x18\a \x01(\x01R\x05speed\x12\x1b\n" +
+	"\ttick_time\x18\b \x01(\x03R\btickTime\x12\x12\n" +
+	"\x04mass\x18\t \x01(\x01R\x04mass\"6\n" +
 	"\x16PlayerDirectionMessage\x12\x1c\n" +
-	"\tdirection\x18\x01 \x01(\x01R\tdirection\"R\n" +
+	"\tdirection\x18\x01 \x01(\x01R\tdirection\"f\n" +
 	"\fSporeMessage\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x04R\x02id\x12\f\n" +
 	"\x01x\x18\x02 \x01(\x01R\x01x\x12\f\n" +
 	"\x01y\x18\x03 \x01(\x01R\x01y\x12\x16\n" +
-	"\x06radius\x18\x04 \x01(\x01R\x06radius\"P\n" +
+	"\x06radius\x18\x04 \x01(\x01R\x06radius\x12\x12\n" +
+	"\x04type\x18\x05 \x01(\rR\x04type\"P\n" +
 	"\x14SporeConsumedMessage\x12\x19\n" +
 	"\bspore_id\x18\x01 \x01(\x04R\asporeId\x12\x1d\n" +
 	"\n" +
-	"new_radius\x18\x02 \x01(\x01R\tnewRadius\"S\n" +
+	"new_radius\x18\x02 \x01(\x01R\tnewRadius\"x\n" +
 	"\x15PlayerConsumedMessage\x12\x1b\n" +
 	"\tplayer_id\x18\x01 \x01(\x04R\bplayerId\x12\x1d\n" +
 	"\n" +
-	"new_radius\x18\x02 \x01(\x01R\tnewRadius\"C\n" +
+	"new_radius\x18\x02 \x01(\x01R\tnewRadius\x12#\n" +
+	"\rvictim_radius\x18\x03 \x01(\x01R\fvictimRadius\"0\n" +
+	"\x13SporeRemovedMessage\x12\x19\n" +
+	"\bspore_id\x18\x01 \x01(\x04R\asporeId\"\x99\x01\n" +
 	"\x12SporesBatchMessage\x12-\n" +
-	"\x06spores\x18\x01 \x03(\v2\x15.packets.SporeMessageR\x06spores\"\x99\x06\n" +
+	"\x06spores\x18\x01 \x03(\v2\x15.packets.SporeMessageR\x06spores\x12\x10\n" +
+	"\x03ids\x18\x02 \x03(\x04R\x03ids\x12!\n" +
+	"\fspore_values\x18\x03 \x03(\x01R\vsporeValues\x12\x1f\n" +
+	"\vspore_types\x18\x04 \x03(\rR\n" +
+	"sporeTypes\"G\n" +
+	"\x13PlayersBatchMessage\x120\n" +
+	"\aplayers\x18\x01 \x03(\v2\x16.packets.PlayerMessageR\aplayers\"S\n" +
+	"\rHazardMessage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\f\n" +
+	"\x01x\x18\x02 \x01(\x01R\x01x\x12\f\n" +
+	"\x01y\x18\x03 \x01(\x01R\x01y\x12\x16\n" +
+	"\x06radius\x18\x04 \x01(\x01R\x06radius\"P\n" +
+	"\x14HazardRemovedMessage\x12\x1b\n" +
+	"\thazard_id\x18\x01 \x01(\x04R\bhazardId\x12\x1b\n" +
+	"\tplayer_id\x18\x02 \x01(\x04R\bplayerId\"A\n" +
+	"\vZoneMessage\x12\f\n" +
+	"\x01x\x18\x01 \x01(\x01R\x01x\x12\f\n" +
+	"\x01y\x18\x02 \x01(\x01R\x01y\x12\x16\n" +
+	"\x06radius\x18\x03 \x01(\x01R\x06radius\"*\n" +
+	"\x10ReconnectMessage\x12\x16\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\";\n" +
+	"\x11ServerInfoMessage\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04motd\x18\x02 \x01(\tR\x04motd\"\x90\x01\n" +
+	"\x0fGameModeMessage\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12!\n" +
+	"\fzone_enabled\x18\x02 \x01(\bR\vzoneEnabled\x12#\n" +
+	"\rconsume_ratio\x18\x03 \x01(\x01R\fconsumeRatio\x12!\n" +
+	"\fconsume_mode\x18\x04 \x01(\tR\vconsumeMode\"S\n" +
+	"\x0fTimeSyncMessage\x12\x1f\n" +
+	"\vclient_time\x18\x01 \x01(\x03R\n" +
+	"clientTime\x12\x1f\n" +
+	"\vserver_time\x18\x02 \x01(\x03R\n" +
+	"serverTime\"9\n" +
+	"\x13CapabilitiesMessage\x12\"\n" +
+	"\fcapabilities\x18\x01 \x03(\tR\fcapabilities\"\x15\n" +
+	"\x13RequestWorldMessage\"\x97\x01\n" +
+	"\x12ServerStatsMessage\x12!\n" +
+	"\fplayer_count\x18\x01 \x01(\x04R\vplayerCount\x12.\n" +
+	"\x13biggest_player_name\x18\x02 \x01(\tR\x11biggestPlayerName\x12.\n" +
+	"\x13biggest_player_mass\x18\x03 \x01(\x01R\x11biggestPlayerMass\"[\n" +
+	"\x10LeaderboardEntry\x12\x1b\n" +
+	"\tplayer_id\x18\x01 \x01(\x04R\bplayerId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
+	"\x06radius\x18\x03 \x01(\x01R\x06radius\"I\n" +
+	"\x12LeaderboardMessage\x123\n" +
+	"\aentries\x18\x01 \x03(\v2\x19.packets.LeaderboardEntryR\aentries\"+\n" +
+	"\x13SessionTokenMessage\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\",\n" +
+	"\x14ResumeRequestMessage\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\"\x18\n" +
+	"\x16SpectateRequestMessage\"0\n" +
+	"\x11PlayerLeftMessage\x12\x1b\n" +
+	"\tplayer_id\x18\x01 \x01(\x04R\bplayerId\":\n" +
+	"\x11ServerFullMessage\x12%\n" +
+	"\x0equeue_position\x18\x01 \x01(\rR\rqueuePosition\"\xa2\x01\n" +
+	"\fStatsMessage\x12!\n" +
+	"\fspores_eaten\x18\x01 \x01(\x04R\vsporesEaten\x12#\n" +
+	"\rplayers_eaten\x18\x02 \x01(\x04R\fplayersEaten\x12+\n" +
+	"\x11distance_traveled\x18\x03 \x01(\x01R\x10distanceTraveled\x12\x1d\n" +
+	"\n" +
+	"time_alive\x18\x04 \x01(\x01R\ttimeAlive\"\xc9\x0f\n" +
 	"\x06Packet\x12\x1b\n" +
 	"\tsender_id\x18\x01 \x01(\x04R\bsenderId\x12*\n" +
 	"\x04chat\x18\x02 \x01(\v2\x14.packets.ChatMessageH\x00R\x04chat\x12$\n" +
@@ -971,7 +2406,29 @@ const file_packets_proto_rawDesc = "" +
 	" \x01(\v2\x15.packets.SporeMessageH\x00R\x05spore\x12F\n" +
 	"\x0espore_consumed\x18\v \x01(\v2\x1d.packets.SporeConsumedMessageH\x00R\rsporeConsumed\x12@\n" +
 	"\fspores_batch\x18\f \x01(\v2\x1b.packets.SporesBatchMessageH\x00R\vsporesBatch\x12I\n" +
-	"\x0fplayer_consumed\x18\r \x01(\v2\x1e.packets.PlayerConsumedMessageH\x00R\x0eplayerConsumedB\x05\n" +
+	"\x0fplayer_consumed\x18\r \x01(\v2\x1e.packets.PlayerConsumedMessageH\x00R\x0eplayerConsumed\x12*\n" +
+	"\x04zone\x18\x0e \x01(\v2\x14.packets.ZoneMessageH\x00R\x04zone\x129\n" +
+	"\treconnect\x18\x0f \x01(\v2\x19.packets.ReconnectMessageH\x00R\treconnect\x12=\n" +
+	"\vserver_info\x18\x10 \x01(\v2\x1a.packets.ServerInfoMessageH\x00R\n" +
+	"serverInfo\x12-\n" +
+	"\x05stats\x18\x11 \x01(\v2\x15.packets.StatsMessageH\x00R\x05stats\x127\n" +
+	"\ttime_sync\x18\x12 \x01(\v2\x18.packets.TimeSyncMessageH\x00R\btimeSync\x12C\n" +
+	"\rspore_removed\x18\x13 \x01(\v2\x1c.packets.SporeRemovedMessageH\x00R\fsporeRemoved\x127\n" +
+	"\tgame_mode\x18\x14 \x01(\v2\x18.packets.GameModeMessageH\x00R\bgameMode\x12B\n" +
+	"\fcapabilities\x18\x15 \x01(\v2\x1c.packets.CapabilitiesMessageH\x00R\fcapabilities\x12C\n" +
+	"\rrequest_world\x18\x16 \x01(\v2\x1c.packets.RequestWorldMessageH\x00R\frequestWorld\x12@\n" +
+	"\fserver_stats\x18\x17 \x01(\v2\x1b.packets.ServerStatsMessageH\x00R\vserverStats\x12?\n" +
+	"\vleaderboard\x18\x18 \x01(\v2\x1b.packets.LeaderboardMessageH\x00R\vleaderboard\x12C\n" +
+	"\rsession_token\x18\x19 \x01(\v2\x1c.packets.SessionTokenMessageH\x00R\fsessionToken\x12F\n" +
+	"\x0eresume_request\x18\x1a \x01(\v2\x1d.packets.ResumeRequestMessageH\x00R\rresumeRequest\x12L\n" +
+	"\x10spectate_request\x18\x1b \x01(\v2\x1f.packets.SpectateRequestMessageH\x00R\x0fspectateRequest\x12=\n" +
+	"\vplayer_left\x18\x1c \x01(\v2\x1a.packets.PlayerLeftMessageH\x00R\n" +
+	"playerLeft\x12=\n" +
+	"\vserver_full\x18\x1d \x01(\v2\x1a.packets.ServerFullMessageH\x00R\n" +
+	"serverFull\x12C\n" +
+	"\rplayers_batch\x18\x1e \x01(\v2\x1c.packets.PlayersBatchMessageH\x00R\fplayersBatch\x120\n" +
+	"\x06hazard\x18\x1f \x01(\v2\x16.packets.HazardMessageH\x00R\x06hazard\x12F\n" +
+	"\x0ehazard_removed\x18  \x01(\v2\x1d.packets.HazardRemovedMessageH\x00R\rhazardRemovedB\x05\n" +
 	"\x03msgB\rZ\vpkg/packetsb\x06proto3"
 
 var (
@@ -986,7 +2443,7 @@ func file_packets_proto_rawDescGZIP() []byte {
 	return file_packets_proto_rawDescData
 }
 
-var file_packets_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_packets_proto_msgTypes = make([]protoimpl.MessageInfo, 33)
 var file_packets_proto_goTypes = []any{
 	(*ChatMessage)(nil),            // 0: packets.ChatMessage
 	(*IdMessage)(nil),              // 1: packets.IdMessage
@@ -999,28 +2456,69 @@ var file_packets_proto_goTypes = []any{
 	(*SporeMessage)(nil),           // 8: packets.SporeMessage
 	(*SporeConsumedMessage)(nil),   // 9: packets.SporeConsumedMessage
 	(*PlayerConsumedMessage)(nil),  // 10: packets.PlayerConsumedMessage
-	(*SporesBatchMessage)(nil),     // 11: packets.SporesBatchMessage
-	(*Packet)(nil),                 // 12: packets.Packet
+	(*SporeRemovedMessage)(nil),    // 11: packets.SporeRemovedMessage
+	(*SporesBatchMessage)(nil),     // 12: packets.SporesBatchMessage
+	(*PlayersBatchMessage)(nil),    // 13: packets.PlayersBatchMessage
+	(*HazardMessage)(nil),          // 14: packets.HazardMessage
+	(*HazardRemovedMessage)(nil),   // 15: packets.HazardRemovedMessage
+	(*ZoneMessage)(nil),            // 16: packets.ZoneMessage
+	(*ReconnectMessage)(nil),       // 17: packets.ReconnectMessage
+	(*ServerInfoMessage)(nil),      // 18: packets.ServerInfoMessage
+	(*GameModeMessage)(nil),        // 19: packets.GameModeMessage
+	(*TimeSyncMessage)(nil),        // 20: packets.TimeSyncMessage
+	(*CapabilitiesMessage)(nil),    // 21: packets.CapabilitiesMessage
+	(*RequestWorldMessage)(nil),    // 22: packets.RequestWorldMessage
+	(*ServerStatsMessage)(nil),     // 23: packets.ServerStatsMessage
+	(*LeaderboardEntry)(nil),       // 24: packets.LeaderboardEntry
+	(*LeaderboardMessage)(nil),     // 25: packets.LeaderboardMessage
+	(*SessionTokenMessage)(nil),    // 26: packets.SessionTokenMessage
+	(*ResumeRequestMessage)(nil),   // 27: packets.ResumeRequestMessage
+	(*SpectateRequestMessage)(nil), // 28: packets.SpectateRequestMessage
+	(*PlayerLeftMessage)(nil),      // 29: packets.PlayerLeftMessage
+	(*ServerFullMessage)(nil),      // 30: packets.ServerFullMessage
+	(*StatsMessage)(nil),           // 31: packets.StatsMessage
+	(*Packet)(nil),                 // 32: packets.Packet
 }
 var file_packets_proto_depIdxs = []int32{
 	8,  // 0: packets.SporesBatchMessage.spores:type_name -> packets.SporeMessage
-	0,  // 1: packets.Packet.chat:type_name -> packets.ChatMessage
-	1,  // 2: packets.Packet.id:type_name -> packets.IdMessage
-	2,  // 3: packets.Packet.login_request:type_name -> packets.LoginRequestMessage
-	3,  // 4: packets.Packet.register_request:type_name -> packets.RegisterRequestMessage
-	4,  // 5: packets.Packet.ok_response:type_name -> packets.OkResponseMessage
-	5,  // 6: packets.Packet.deny_response:type_name -> packets.DenyResponseMessage
-	6,  // 7: packets.Packet.player:type_name -> packets.PlayerMessage
-	7,  // 8: packets.Packet.player_direction:type_name -> packets.PlayerDirectionMessage
-	8,  // 9: packets.Packet.spore:type_name -> packets.SporeMessage
-	9,  // 10: packets.Packet.spore_consumed:type_name -> packets.SporeConsumedMessage
-	11, // 11: packets.Packet.spores_batch:type_name -> packets.SporesBatchMessage
-	10, // 12: packets.Packet.player_consumed:type_name -> packets.PlayerConsumedMessage
-	13, // [13:13] is the sub-list for method output_type
-	13, // [13:13] is the sub-list for method input_type
-	13, // [13:13] is the sub-list for extension type_name
-	13, // [13:13] is the sub-list for extension extendee
-	0,  // [0:13] is the sub-list for field type_name
+	6,  // 1: packets.PlayersBatchMessage.players:type_name -> packets.PlayerMessage
+	24, // 2: packets.LeaderboardMessage.entries:type_name -> packets.LeaderboardEntry
+	0,  // 3: packets.Packet.chat:type_name -> packets.ChatMessage
+	1,  // 4: packets.Packet.id:type_name -> packets.IdMessage
+	2,  // 5: packets.Packet.login_request:type_name -> packets.LoginRequestMessage
+	3,  // 6: packets.Packet.register_request:type_name -> packets.RegisterRequestMessage
+	4,  // 7: packets.Packet.ok_response:type_name -> packets.OkResponseMessage
+	5,  // 8: packets.Packet.deny_response:type_name -> packets.DenyResponseMessage
+	6,  // 9: packets.Packet.player:type_name -> packets.PlayerMessage
+	7,  // 10: packets.Packet.player_direction:type_name -> packets.PlayerDirectionMessage
+	8,  // 11: packets.Packet.spore:type_name -> packets.SporeMessage
+	9,  // 12: packets.Packet.spore_consumed:type_name -> packets.SporeConsumedMessage
+	12, // 13: packets.Packet.spores_batch:type_name -> packets.SporesBatchMessage
+	10, // 14: packets.Packet.player_consumed:type_name -> packets.PlayerConsumedMessage
+	16, // 15: packets.Packet.zone:type_name -> packets.ZoneMessage
+	17, // 16: packets.Packet.reconnect:type_name -> packets.ReconnectMessage
+	18, // 17: packets.Packet.server_info:type_name -> packets.ServerInfoMessage
+	31, // 18: packets.Packet.stats:type_name -> packets.StatsMessage
+	20, // 19: packets.Packet.time_sync:type_name -> packets.TimeSyncMessage
+	11, // 20: packets.Packet.spore_removed:type_name -> packets.SporeRemovedMessage
+	19, // 21: packets.Packet.game_mode:type_name -> packets.GameModeMessage
+	21, // 22: packets.Packet.capabilities:type_name -> packets.CapabilitiesMessage
+	22, // 23: packets.Packet.request_world:type_name -> packets.RequestWorldMessage
+	23, // 24: packets.Packet.server_stats:type_name -> packets.ServerStatsMessage
+	25, // 25: packets.Packet.leaderboard:type_name -> packets.LeaderboardMessage
+	26, // 26: packets.Packet.session_token:type_name -> packets.SessionTokenMessage
+	27, // 27: packets.Packet.resume_request:type_name -> packets.ResumeRequestMessage
+	28, // 28: packets.Packet.spectate_request:type_name -> packets.SpectateRequestMessage
+	29, // 29: packets.Packet.player_left:type_name -> packets.PlayerLeftMessage
+	30, // 30: packets.Packet.server_full:type_name -> packets.ServerFullMessage
+	13, // 31: packets.Packet.players_batch:type_name -> packets.PlayersBatchMessage
+	14, // 32: packets.Packet.hazard:type_name -> packets.HazardMessage
+	15, // 33: packets.Packet.hazard_removed:type_name -> packets.HazardRemovedMessage
+	34, // [34:34] is the sub-list for method output_type
+	34, // [34:34] is the sub-list for method input_type
+	34, // [34:34] is the sub-list for extension type_name
+	34, // [34:34] is the sub-list for extension extendee
+	0,  // [0:34] is the sub-list for field type_name
 }
 
 func init() { file_packets_proto_init() }
@@ -1028,7 +2526,7 @@ func file_packets_proto_init() {
 	if File_packets_proto != nil {
 		return
 	}
-	file_packets_proto_msgTypes[12].OneofWrappers = []any{
+	file_packets_proto_msgTypes[32].OneofWrappers = []any{
 		(*Packet_Chat)(nil),
 		(*Packet_Id)(nil),
 		(*Packet_LoginRequest)(nil),
@@ -1041,6 +2539,25 @@ func file_packets_proto_init() {
 		(*Packet_SporeConsumed)(nil),
 		(*Packet_SporesBatch)(nil),
 		(*Packet_PlayerConsumed)(nil),
+		(*Packet_Zone)(nil),
+		(*Packet_Reconnect)(nil),
+		(*Packet_ServerInfo)(nil),
+		(*Packet_Stats)(nil),
+		(*Packet_TimeSync)(nil),
+		(*Packet_SporeRemoved)(nil),
+		(*Packet_GameMode)(nil),
+		(*Packet_Capabilities)(nil),
+		(*Packet_RequestWorld)(nil),
+		(*Packet_ServerStats)(nil),
+		(*Packet_Leaderboard)(nil),
+		(*Packet_SessionToken)(nil),
+		(*Packet_ResumeRequest)(nil),
+		(*Packet_SpectateRequest)(nil),
+		(*Packet_PlayerLeft)(nil),
+		(*Packet_ServerFull)(nil),
+		(*Packet_PlayersBatch)(nil),
+		(*Packet_Hazard)(nil),
+		(*Packet_HazardRemoved)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -1048,7 +2565,7 @@ func file_packets_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_packets_proto_rawDesc), len(file_packets_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   13,
+			NumMessages:   33,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -1,9 +1,32 @@
 package packets
 
-import "server/internal/server/objects"
+import (
+	"server/internal/server/objects"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
 
 type Msg = isPacket_Msg
 
+// Optional protocol features, advertised by the server and opted into by clients which support them
+const (
+	// Spore batches sent as flat arrays rather than a message per spore
+	CapabilityFlatSporesBatch = "flat_spores_batch"
+
+	// Players can chat with each other
+	CapabilityChat = "chat"
+
+	// The client asks for the world with a RequestWorld message once it's ready, rather than being sent it on joining
+	CapabilityRequestWorld = "request_world"
+
+	// The players moved each tick come together in one PlayersBatch message, rather than a Player message each
+	CapabilityPlayersBatch = "players_batch"
+)
+
 func NewChat(msg string) Msg {
 	return &Packet_Chat{
 		Chat: &ChatMessage{
@@ -20,6 +43,35 @@ func NewId(id uint64) Msg {
 	}
 }
 
+func NewServerInfo(name string, motd string) Msg {
+	return &Packet_ServerInfo{
+		ServerInfo: &ServerInfoMessage{
+			Name: name,
+			Motd: motd,
+		},
+	}
+}
+
+func NewGameMode(name string, zoneEnabled bool, consumeRatio float64, consumeMode string) Msg {
+	return &Packet_GameMode{
+		GameMode: &GameModeMessage{
+			Name: name,
+			ZoneEnabled: zoneEnabled,
+			ConsumeRatio: consumeRatio,
+			ConsumeMode: consumeMode,
+		},
+	}
+}
+
+func NewTimeSync(clientTime int64, serverTime int64) Msg {
+	return &Packet_TimeSync{
+		TimeSync: &TimeSyncMessage{
+			ClientTime: clientTime,
+			ServerTime: serverTime,
+		},
+	}
+}
+
 func NewOkResponse() Msg {
 	return &Packet_OkResponse{
 		OkResponse: &OkResponseMessage{},
@@ -34,9 +86,16 @@ func NewDenyResponse(reason string) Msg {
 	}
 }
 
-func NewPlayer(id uint64, player *objects.Player) Msg {
- return &Packet_Player{
-	Player: &PlayerMessage{
+func NewReconnect(reason string) Msg {
+	return &Packet_Reconnect{
+		Reconnect: &ReconnectMessage{
+			Reason: reason,
+		},
+	}
+}
+
+func newPlayerMessage(id uint64, player *objects.Player) *PlayerMessage {
+	return &PlayerMessage{
 		Id: id,
 		Name: player.Name,
 		X: player.X,
@@ -44,16 +103,100 @@ func NewPlayer(id uint64, player *objects.Player) Msg {
 		Radius: player.Radius,
 		Direction: player.Direction,
 		Speed: player.Speed,
-	},
+		TickTime: player.TickTime,
+		Mass: objects.RadiusToMass(player.Radius),
+	}
+}
+
+func NewPlayer(id uint64, player *objects.Player) Msg {
+ return &Packet_Player{
+	Player: newPlayerMessage(id, player),
  }
 }
 
+// A batch of players in ascending order of ID, each as a Player message would have them
+func NewPlayersBatch(players map[uint64]*objects.Player) Msg {
+	ids := make([]uint64, 0, len(players))
+
+	for id := range players {
+		ids = append(ids, id)
+	}
+
+	slices.Sort(ids)
+
+	playerMessages := make([]*PlayerMessage, 0, len(ids))
+
+	for _, id := range ids {
+		playerMessages = append(playerMessages, newPlayerMessage(id, players[id]))
+	}
+
+	return NewPlayersBatchOf(playerMessages)
+}
+
+// A batch of player messages which have already been made, such as some of those in another batch
+func NewPlayersBatchOf(playerMessages []*PlayerMessage) Msg {
+	return &Packet_PlayersBatch{
+		PlayersBatch: &PlayersBatchMessage{
+			Players: playerMessages,
+		},
+	}
+}
+
+func NewStats(player *objects.Player, timeAlive time.Duration) Msg {
+	return &Packet_Stats{
+		Stats: &StatsMessage{
+			SporesEaten: player.SporesEaten,
+			PlayersEaten: player.PlayersEaten,
+			DistanceTraveled: player.DistanceTraveled,
+			TimeAlive: timeAlive.Seconds(),
+		},
+	}
+}
+
+// Totals for the whole server. The biggest player is nil if nobody is playing
+func NewServerStats(playerCount int, biggestPlayer *objects.Player) Msg {
+	message := &ServerStatsMessage{
+		PlayerCount: uint64(playerCount),
+	}
+
+	if biggestPlayer != nil {
+		message.BiggestPlayerName = biggestPlayer.Name
+		message.BiggestPlayerMass = objects.RadiusToMass(biggestPlayer.Radius)
+	}
+
+	return &Packet_ServerStats{
+		ServerStats: message,
+	}
+}
+
 func newSporeMessage(spore_id uint64, spore *objects.Spore) *SporeMessage {
 	return &SporeMessage{
 		Id: spore_id,
 		X: spore.X,
 		Y: spore.Y,
 		Radius: spore.Radius,
+		Type: uint32(spore.Type),
+	}
+}
+
+func NewHazard(id uint64, hazard *objects.Hazard) Msg {
+	return &Packet_Hazard{
+		Hazard: &HazardMessage{
+			Id: id,
+			X: hazard.X,
+			Y: hazard.Y,
+			Radius: hazard.Radius,
+		},
+	}
+}
+
+// A hazard popped by the given player
+func NewHazardRemoved(hazardId uint64, playerId uint64) Msg {
+	return &Packet_HazardRemoved{
+		HazardRemoved: &HazardRemovedMessage{
+			HazardId: hazardId,
+			PlayerId: playerId,
+		},
 	}
 }
 
@@ -63,8 +206,30 @@ func NewSpore(id uint64, spore *objects.Spore) Msg {
 	}
 }
 
+func NewSporeRemoved(sporeId uint64) Msg {
+	return &Packet_SporeRemoved{
+		SporeRemoved: &SporeRemovedMessage{
+			SporeId: sporeId,
+		},
+	}
+}
+
+func NewCapabilities(capabilities []string) Msg {
+	return &Packet_Capabilities{
+		Capabilities: &CapabilitiesMessage{
+			Capabilities: capabilities,
+		},
+	}
+}
+
+func NewRequestWorld() Msg {
+	return &Packet_RequestWorld{
+		RequestWorld: &RequestWorldMessage{},
+	}
+}
+
 func NewSporesBatch(spores map[uint64]*objects.Spore) Msg {
-	sporesMessages := make([]*SporeMessage, len(spores))
+	sporesMessages := make([]*SporeMessage, 0, len(spores))
 	
 	for id, spore := range spores {
 		sporesMessages = append(sporesMessages, newSporeMessage(id, spore))
@@ -75,4 +240,156 @@ func NewSporesBatch(spores map[uint64]*objects.Spore) Msg {
 			Spores: sporesMessages,
 		},
 	}
+}
+
+// A spore batch for clients with the flat spores batch capability. The IDs are in ascending order, and each spore's x,
+// y and radius follow one after the other in the values. The types are in the same order as the IDs, but left out when
+// every spore is plain
+func NewFlatSporesBatch(spores map[uint64]*objects.Spore) Msg {
+	ids := make([]uint64, 0, len(spores))
+
+	for id := range spores {
+		ids = append(ids, id)
+	}
+
+	slices.Sort(ids)
+
+	values := make([]float64, 0, 3 * len(ids))
+	types := make([]uint32, 0, len(ids))
+	allPlain := true
+
+	for _, id := range ids {
+		spore := spores[id]
+		values = append(values, spore.X, spore.Y, spore.Radius)
+		types = append(types, uint32(spore.Type))
+		allPlain = allPlain && spore.Type == objects.SporeTypePlain
+	}
+
+	if allPlain {
+		types = nil
+	}
+
+	return &Packet_SporesBatch{
+		SporesBatch: &SporesBatchMessage{
+			Ids: ids,
+			SporeValues: values,
+			SporeTypes: types,
+		},
+	}
+}
+
+// The oneof holding a packet's message, looked up once the generated code has registered the descriptors
+var msgOneof = sync.OnceValue(func() protoreflect.OneofDescriptor {
+	return (&Packet{}).ProtoReflect().Descriptor().Oneofs().ByName("msg")
+})
+
+// The name of the message in the packet as written in the protocol, e.g. "player", or empty if it has none
+func MsgName(packet *Packet) string {
+	field := packet.ProtoReflect().WhichOneof(msgOneof())
+
+	if field == nil {
+		return ""
+	}
+
+	return string(field.Name())
+}
+
+// The names of every message a packet can hold
+func MsgNames() []string {
+	fields := msgOneof().Fields()
+	names := make([]string, 0, fields.Len())
+
+	for i := range fields.Len() {
+		names = append(names, string(fields.Get(i).Name()))
+	}
+
+	return names
+}
+
+// Returns the field numbers of any messages in the packet which this build of the server doesn't recognise, which
+// happens when the client was built against a newer version of the protocol
+func UnknownMsgNumbers(packet *Packet) []protowire.Number {
+	unknown := packet.ProtoReflect().GetUnknown()
+	numbers := make([]protowire.Number, 0)
+
+	for len(unknown) > 0 {
+		number, wireType, n := protowire.ConsumeTag(unknown)
+
+		if n < 0 {
+			break
+		}
+
+		unknown = unknown[n:]
+		n = protowire.ConsumeFieldValue(number, wireType, unknown)
+
+		if n < 0 {
+			break
+		}
+
+		unknown = unknown[n:]
+
+		if wireType == protowire.BytesType {
+			numbers = append(numbers, number)
+		}
+	}
+
+	return numbers
+}
+
+func NewZone(zone *objects.Zone) Msg {
+	return &Packet_Zone{
+		Zone: &ZoneMessage{
+			X: 0,
+			Y: 0,
+			Radius: zone.Radius(),
+		},
+	}
+}
+
+// The entries should already be sorted, biggest player first
+func NewLeaderboard(entries []*LeaderboardEntry) Msg {
+	return &Packet_Leaderboard{
+		Leaderboard: &LeaderboardMessage{
+			Entries: entries,
+		},
+	}
+}
+
+func NewSessionToken(token string) Msg {
+	return &Packet_SessionToken{
+		SessionToken: &SessionTokenMessage{
+			Token: token,
+		},
+	}
+}
+
+func NewResumeRequest(token string) Msg {
+	return &Packet_ResumeRequest{
+		ResumeRequest: &ResumeRequestMessage{
+			Token: token,
+		},
+	}
+}
+
+func NewSpectateRequest() Msg {
+	return &Packet_SpectateRequest{
+		SpectateRequest: &SpectateRequestMessage{},
+	}
+}
+
+func NewPlayerLeft(playerId uint64) Msg {
+	return &Packet_PlayerLeft{
+		PlayerLeft: &PlayerLeftMessage{
+			PlayerId: playerId,
+		},
+	}
+}
+
+// A queue position of 0 means the client was turned away rather than queued
+func NewServerFull(queuePosition int) Msg {
+	return &Packet_ServerFull{
+		ServerFull: &ServerFullMessage{
+			QueuePosition: uint32(queuePosition),
+		},
+	}
 }
\ No newline at end of file
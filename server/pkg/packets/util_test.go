@@ -0,0 +1,130 @@
+package packets
+
+import (
+	"maps"
+	"server/internal/server/objects"
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// A batch of players must come back out of the wire format with every player where they were, in order of ID
+func TestPlayersBatchRoundTrip(t *testing.T) {
+	players := map[uint64]*objects.Player{
+		7: {Name: "seven", X: -120.5, Y: 33.25, Radius: 20, Direction: 1.5, Speed: 100, TickTime: 1000},
+		2: {Name: "two", X: 0, Y: -4000, Radius: 45.75, Speed: 80, TickTime: 1000},
+		11: {Name: "eleven", X: 2999.999, Y: 1e-9, Radius: 12, Direction: -3, Speed: 120, TickTime: 1000},
+	}
+
+	data, err := proto.Marshal(&Packet{Msg: NewPlayersBatch(players)})
+
+	if err != nil {
+		t.Fatalf("Couldn't marshal a players batch: %v", err)
+	}
+
+	packet := &Packet{}
+
+	if err := proto.Unmarshal(data, packet); err != nil {
+		t.Fatalf("Couldn't unmarshal a players batch: %v", err)
+	}
+
+	received := packet.GetPlayersBatch().GetPlayers()
+
+	if len(received) != len(players) {
+		t.Fatalf("A batch of %d players came back with %d", len(players), len(received))
+	}
+
+	for i, message := range received {
+		player, exists := players[message.Id]
+
+		if !exists || message.X != player.X || message.Y != player.Y || message.Radius != player.Radius || message.Name != player.Name {
+			t.Errorf("Player %d came back from a batch as %v", message.Id, message)
+		}
+
+		if i > 0 && message.Id <= received[i - 1].Id {
+			t.Errorf("Player %d came after player %d in a batch", message.Id, received[i - 1].Id)
+		}
+	}
+}
+
+// Player packets must carry the player's mass as the server works it out, so clients don't have to recompute it from
+// the radius
+func TestPlayerMass(t *testing.T) {
+	for _, radius := range []float64{0, 1, 20, 137.25} {
+		mass := NewPlayer(1, &objects.Player{Radius: radius}).(*Packet_Player).Player.Mass
+
+		if mass != objects.RadiusToMass(radius) {
+			t.Errorf("A player of radius %f was sent with mass %f instead of %f", radius, mass, objects.RadiusToMass(radius))
+		}
+	}
+}
+
+// The spores in a batch read back out of the wire format, whichever format it's in
+func decodeSporesBatch(t *testing.T, msg Msg) map[uint64]objects.Spore {
+	t.Helper()
+
+	data, err := proto.Marshal(&Packet{Msg: msg})
+
+	if err != nil {
+		t.Fatalf("Couldn't marshal a spores batch: %v", err)
+	}
+
+	packet := &Packet{}
+
+	if err := proto.Unmarshal(data, packet); err != nil {
+		t.Fatalf("Couldn't unmarshal a spores batch: %v", err)
+	}
+
+	batch := packet.GetSporesBatch()
+	spores := make(map[uint64]objects.Spore)
+
+	for _, spore := range batch.Spores {
+		spores[spore.Id] = objects.Spore{X: spore.X, Y: spore.Y, Radius: spore.Radius, Type: objects.SporeType(spore.Type)}
+	}
+
+	if len(batch.SporeValues) != 3 * len(batch.Ids) || (len(batch.SporeTypes) > 0 && len(batch.SporeTypes) != len(batch.Ids)) {
+		t.Fatalf("A flat spores batch of %d IDs came with %d values and %d types", len(batch.Ids), len(batch.SporeValues), len(batch.SporeTypes))
+	}
+
+	for i, id := range batch.Ids {
+		spore := objects.Spore{X: batch.SporeValues[3 * i], Y: batch.SporeValues[3 * i + 1], Radius: batch.SporeValues[3 * i + 2]}
+
+		if len(batch.SporeTypes) > 0 {
+			spore.Type = objects.SporeType(batch.SporeTypes[i])
+		}
+
+		spores[id] = spore
+	}
+
+	return spores
+}
+
+// Both spore batch formats must come back out of the wire format as the same spores they were made from, with or
+// without any special spores among them, and the flat format must list them in order of ID
+func TestSporesBatchFormats(t *testing.T) {
+	for _, golden := range []objects.SporeType{objects.SporeTypePlain, objects.SporeTypeGolden} {
+		spores := map[uint64]*objects.Spore{
+			9: {X: -120.5, Y: 33.25, Radius: 5},
+			3: {X: 0, Y: -4000, Radius: 7.125, Type: golden},
+			42: {X: 2999.999, Y: 1e-9, Radius: 3},
+		}
+		expected := make(map[uint64]objects.Spore)
+
+		for id, spore := range spores {
+			expected[id] = objects.Spore{X: spore.X, Y: spore.Y, Radius: spore.Radius, Type: spore.Type}
+		}
+
+		if decoded := decodeSporesBatch(t, NewSporesBatch(spores)); !maps.Equal(decoded, expected) {
+			t.Errorf("A spores batch came back as %v instead of %v", decoded, expected)
+		}
+
+		if decoded := decodeSporesBatch(t, NewFlatSporesBatch(spores)); !maps.Equal(decoded, expected) {
+			t.Errorf("A flat spores batch came back as %v instead of %v", decoded, expected)
+		}
+
+		if ids := NewFlatSporesBatch(spores).(*Packet_SporesBatch).SporesBatch.Ids; !slices.IsSorted(ids) {
+			t.Errorf("A flat spores batch listed IDs %v out of order", ids)
+		}
+	}
+}
\ No newline at end of file